@@ -3,7 +3,10 @@ package goversioninfo
 import (
 	"fmt"
 	"io"
+	"log"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 // CLIConfig holds all settings for generating a version info resource.
@@ -11,15 +14,46 @@ import (
 type CLIConfig struct {
 	ConfigFile          string
 	OutputFile          string
+	Format              string
 	GoFile              string
 	GoFilePackage       string
 	PlatformSpecific    bool
 	IconPath            string
 	ApplicationIconPath string
 	ManifestPath        string
+	ResPath             string
+	ResConflict         string
 	SkipVersionInfo     bool
 	PropagateVerStrings bool
 
+	// MessageTablePath is an mc.exe message text file embedded as
+	// RT_MESSAGETABLE. MessageGoFile is a Go file with the constants of its
+	// message IDs, in the package GoFilePackage.
+	MessageTablePath string
+	MessageGoFile    string
+
+	// PatchFile is a Windows executable or DLL whose resources are
+	// replaced, instead of writing OutputFile. Without a ConfigFile the
+	// version info it has is the one the flags change.
+	PatchFile string
+
+	// VersionFromGit sets the versions from the git repository in the
+	// working directory before the version flags are applied.
+	VersionFromGit bool
+
+	// SemVer sets the versions from a semantic version like
+	// v2.3.1-rc.2+sha.abc, after VersionFromGit. SemVerPrerelease is the
+	// PrereleaseScheme and SemVerMetadata the string the build metadata
+	// goes to, see VersionInfo.SetSemVer.
+	SemVer           string
+	SemVerPrerelease string
+	SemVerMetadata   string
+
+	// Strict fails on validation errors instead of logging them,
+	// WarningsAsErrors on warnings too.
+	Strict           bool
+	WarningsAsErrors bool
+
 	Comment        string
 	CompanyName    string
 	Description    string
@@ -33,6 +67,24 @@ type CLIConfig struct {
 	ProductVersion string
 	SpecialBuild   string
 
+	// Strings are applied after the fields above with StringFileInfo.Set,
+	// so they can set standard keys as well as custom ones.
+	Strings CustomStrings
+
+	// Resources are added after the ones of the config file.
+	Resources []FileResource
+
+	// The FixedFileInfo flags and types take hex numbers or winver.h names
+	// joined with |, like VS_FF_DEBUG|VS_FF_PRERELEASE.
+	FileFlagsMask string
+	FileFlags     string
+	FileOS        string
+	FileType      string
+	FileSubType   string
+
+	// FileDate is an RFC 3339 time, SOURCE_DATE_EPOCH or git.
+	FileDate string
+
 	TranslationID int
 	CharsetID     int
 
@@ -57,6 +109,7 @@ func NewCLIConfig() CLIConfig {
 	return CLIConfig{
 		ConfigFile:      "versioninfo.json",
 		OutputFile:      "resource.syso",
+		Format:          "syso",
 		GoFilePackage:   "main",
 		VerMajor:        -1,
 		VerMinor:        -1,
@@ -71,9 +124,23 @@ func NewCLIConfig() CLIConfig {
 
 // RunCLI generates version info resource files based on the provided CLIConfig.
 func RunCLI(cfg CLIConfig) error {
+	switch cfg.Format {
+	case "", "syso", "res", "rc":
+	default:
+		return fmt.Errorf("unknown output format %q, expected syso, res or rc", cfg.Format)
+	}
+
 	vi := &VersionInfo{}
 
-	if !cfg.SkipVersionInfo {
+	if cfg.PatchFile != "" && cfg.ConfigFile == "" {
+		er, err := ReadExecutable(cfg.PatchFile)
+		if err != nil {
+			return err
+		}
+		if er.VersionInfo != nil {
+			vi = er.VersionInfo
+		}
+	} else if !cfg.SkipVersionInfo {
 		var input = io.ReadCloser(os.Stdin)
 		if cfg.ConfigFile != "-" {
 			f, err := os.Open(cfg.ConfigFile)
@@ -89,7 +156,12 @@ func RunCLI(cfg CLIConfig) error {
 			return fmt.Errorf("error reading %q: %w", cfg.ConfigFile, err)
 		}
 
-		if err := vi.ParseJSON(jsonBytes); err != nil {
+		// Resource scripts can be used in place of versioninfo.json
+		if strings.EqualFold(filepath.Ext(cfg.ConfigFile), ".rc") {
+			if err := vi.ParseRC(jsonBytes); err != nil {
+				return fmt.Errorf("could not parse the .rc file: %w", err)
+			}
+		} else if err := vi.ParseJSON(jsonBytes); err != nil {
 			return fmt.Errorf("could not parse the .json file: %w", err)
 		}
 	}
@@ -103,6 +175,15 @@ func RunCLI(cfg CLIConfig) error {
 	if cfg.ManifestPath != "" {
 		vi.ManifestPath = cfg.ManifestPath
 	}
+	if cfg.ResPath != "" {
+		vi.ResPath = cfg.ResPath
+	}
+	if cfg.ResConflict != "" {
+		vi.ResConflict = ConflictPolicy(cfg.ResConflict)
+	}
+	if cfg.MessageTablePath != "" {
+		vi.MessageTablePath = cfg.MessageTablePath
+	}
 	if cfg.Comment != "" {
 		vi.StringFileInfo.Comments = cfg.Comment
 	}
@@ -139,12 +220,54 @@ func RunCLI(cfg CLIConfig) error {
 	if cfg.SpecialBuild != "" {
 		vi.StringFileInfo.SpecialBuild = cfg.SpecialBuild
 	}
+	for _, pair := range cfg.Strings {
+		vi.StringFileInfo.Set(pair.Key, pair.Value)
+	}
+	vi.FileResources = append(vi.FileResources, cfg.Resources...)
+
+	if cfg.FileFlagsMask != "" {
+		vi.FixedFileInfo.FileFlagsMask = cfg.FileFlagsMask
+	}
+	if cfg.FileFlags != "" {
+		vi.FixedFileInfo.FileFlags = cfg.FileFlags
+	}
+	if cfg.FileOS != "" {
+		vi.FixedFileInfo.FileOS = cfg.FileOS
+	}
+	if cfg.FileType != "" {
+		vi.FixedFileInfo.FileType = cfg.FileType
+	}
+	if cfg.FileSubType != "" {
+		vi.FixedFileInfo.FileSubType = cfg.FileSubType
+	}
+	if cfg.FileDate != "" {
+		vi.FixedFileInfo.FileDate = cfg.FileDate
+	}
+	if err := vi.ResolveFileDate(""); err != nil {
+		return fmt.Errorf("could not resolve the file date: %w", err)
+	}
 
+	if (cfg.TranslationID > 0 || cfg.CharsetID > 0) && len(vi.VarFileInfo.Translation) == 0 {
+		vi.VarFileInfo.Translation = Translations{Translation{}}
+	}
 	if cfg.TranslationID > 0 {
-		vi.VarFileInfo.Translation.LangID = LangID(cfg.TranslationID)
+		vi.VarFileInfo.Translation[0].LangID = LangID(cfg.TranslationID)
 	}
 	if cfg.CharsetID > 0 {
-		vi.VarFileInfo.Translation.CharsetID = CharsetID(cfg.CharsetID)
+		vi.VarFileInfo.Translation[0].CharsetID = CharsetID(cfg.CharsetID)
+	}
+
+	if cfg.VersionFromGit {
+		gv, err := ReadGitVersion("")
+		if err != nil {
+			return fmt.Errorf("could not read the version from git: %w", err)
+		}
+		vi.SetGitVersion(gv)
+	}
+	if cfg.SemVer != "" {
+		if err := vi.SetSemVer(cfg.SemVer, PrereleaseScheme(cfg.SemVerPrerelease), cfg.SemVerMetadata); err != nil {
+			return fmt.Errorf("invalid -semver: %w", err)
+		}
 	}
 
 	if cfg.VerMajor >= 0 {
@@ -174,21 +297,32 @@ func RunCLI(cfg CLIConfig) error {
 	}
 
 	if cfg.PropagateVerStrings && vi.StringFileInfo.FileVersion != "" {
-		v, err := NewFileVersion(vi.StringFileInfo.FileVersion)
+		v, _, err := parseVersionString(vi.StringFileInfo.FileVersion)
 		if err != nil {
 			return fmt.Errorf("unexpected StringFileInfo.FileVersion format: %w", err)
 		}
 		vi.FixedFileInfo.FileVersion = v
 	}
 	if cfg.PropagateVerStrings && vi.StringFileInfo.ProductVersion != "" {
-		v, err := NewFileVersion(vi.StringFileInfo.ProductVersion)
+		v, _, err := parseVersionString(vi.StringFileInfo.ProductVersion)
 		if err != nil {
 			return fmt.Errorf("unexpected StringFileInfo.ProductVersion format: %w", err)
 		}
 		vi.FixedFileInfo.ProductVersion = v
 	}
 
-	vi.Build()
+	// Validate once here and log what does not fail the build, Build
+	// itself stays quiet.
+	failed, others := splitIssues(vi.Validate(), cfg.Strict, cfg.WarningsAsErrors)
+	for _, issue := range others {
+		log.Print(issue)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("invalid version info: %w", &ValidationError{failed})
+	}
+	if err := vi.Build(); err != nil {
+		return fmt.Errorf("invalid version info: %w", err)
+	}
 	vi.Walk()
 
 	if cfg.GoFile != "" {
@@ -196,6 +330,42 @@ func RunCLI(cfg CLIConfig) error {
 			return fmt.Errorf("error writing Go file: %w", err)
 		}
 	}
+	if cfg.MessageGoFile != "" {
+		if vi.MessageTablePath == "" {
+			return fmt.Errorf("a Go file of message IDs needs a message text file")
+		}
+		mt, err := ReadMC(vi.MessageTablePath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", vi.MessageTablePath, err)
+		}
+		if err := mt.WriteGo(cfg.MessageGoFile, cfg.GoFilePackage); err != nil {
+			return fmt.Errorf("error writing Go file: %w", err)
+		}
+	}
+
+	if cfg.PatchFile != "" {
+		if err := vi.PatchExecutable(cfg.PatchFile); err != nil {
+			return fmt.Errorf("error patching %s: %w", cfg.PatchFile, err)
+		}
+		return nil
+	}
+
+	// .res files and resource scripts have no architecture, so one file
+	// serves every platform
+	if cfg.Format == "res" || cfg.Format == "rc" {
+		fileout := cfg.OutputFile
+		if fileout == "" || fileout == NewCLIConfig().OutputFile {
+			fileout = "resource." + cfg.Format
+		}
+		write := vi.WriteRes
+		if cfg.Format == "rc" {
+			write = vi.WriteRC
+		}
+		if err := write(fileout); err != nil {
+			return fmt.Errorf("error writing %s: %w", cfg.Format, err)
+		}
+		return nil
+	}
 
 	var archs []string
 	if cfg.PlatformSpecific {
@@ -9,25 +9,60 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/josephspurrier/goversioninfo"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "extract":
+			extract(os.Args[2:])
+			return
+		case "inspect":
+			inspect(os.Args[2:])
+			return
+		case "lint-icon":
+			lintIcon(os.Args[2:])
+			return
+		}
+	}
+
+	// patch takes the same flags, with the executable before the config
+	patch := len(os.Args) > 1 && os.Args[1] == "patch"
+	if patch {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	flagExample := flag.Bool("example", false, "dump out an example versioninfo.json to stdout")
 
 	cfg := goversioninfo.NewCLIConfig()
 
 	flagOut := flag.String("o", cfg.OutputFile, "output file name")
+	flagFormat := flag.String("format", cfg.Format, "output format: syso, res for a Win32 .res file or rc for a resource script (resource.res or resource.rc unless -o is set)")
 	flagGo := flag.String("gofile", "", "Go output file name (optional)")
 	flagPackage := flag.String("gofilepackage", cfg.GoFilePackage, "Go output package name (optional, requires parameter: 'gofile')")
 	flagPlatformSpecific := flag.Bool("platform-specific", false, "output i386, amd64, arm and arm64 named resource.syso, ignores -o")
-	flagIcon := flag.String("icon", "", "icon file name(s), separated by commas")
+	flagIcon := flag.String("icon", "", "icon file name(s), separated by commas; .ico, .png or a directory of .png files")
 	flagApplicationIcon := flag.String("application-icon", "", "icon file for IDI_APPLICATION (window title bar); defaults to -icon if unset")
 	flagManifest := flag.String("manifest", "", "manifest file name")
+	flagRes := flag.String("res", "", ".res file whose resources are added to the output")
+	var flagResources resourcesFlag
+	flag.Var(&flagResources, "resource", "file embedded as type:id:path or type:id:lang=XXXX:path, like RCDATA:LICENSE:LICENSE.txt, may be repeated")
+	flagMC := flag.String("mc", "", "mc.exe message text file embedded as RT_MESSAGETABLE")
+	flagMCGo := flag.String("mc-gofile", "", "Go output file name for the message ID constants of -mc (optional, package from -gofilepackage)")
+	flagResConflict := flag.String("res-conflict", "", "resources also in the -res file: replace (default) keeps the generated one, error fails")
 	flagSkipVersion := flag.Bool("skip-versioninfo", false, "skip version info")
 	flagPropagateVerStrings := flag.Bool("propagate-ver-strings", false,
 		"fill FixedFileInfo version fields using FileVersion and ProductVersion from the StringFileInfo")
+	flagVersionFromGit := flag.Bool("version-from-git", false,
+		"set the versions from the nearest v1.2.3 tag with the commits since it as Build, and GitCommit to the commit hash")
+	flagSemVer := flag.String("semver", "", "set the versions from a semantic version like v2.3.1-rc.2+sha.abc, kept as StringFileInfo.ProductVersion")
+	flagSemVerPrerelease := flag.String("semver-prerelease", "", "Build number of a -semver prerelease: number for 2 of rc.2, stage for 1000+N for alpha, 2000+N for beta and 3000+N for rc")
+	flagSemVerMetadata := flag.String("semver-metadata", "SpecialBuild", "string for the build metadata of -semver: SpecialBuild or PrivateBuild")
+	flagStrict := flag.Bool("strict", false, "fail on validation errors, like version parts above 65535 or missing icons, instead of logging them")
+	flagWarningsAsErrors := flag.Bool("warnings-as-errors", false, "fail on validation warnings too, like versions that do not match")
 
 	flagComment := flag.String("comment", "", "StringFileInfo.Comments")
 	flagCompany := flag.String("company", "", "StringFileInfo.CompanyName")
@@ -41,6 +76,15 @@ func main() {
 	flagProductName := flag.String("product-name", "", "StringFileInfo.ProductName")
 	flagProductVersion := flag.String("product-version", "", "StringFileInfo.ProductVersion")
 	flagSpecialBuild := flag.String("special-build", "", "StringFileInfo.SpecialBuild")
+	var flagStrings stringsFlag
+	flag.Var(&flagStrings, "string", "StringFileInfo key in Key=Value form, may be repeated")
+
+	flagFileFlagsMask := flag.String("file-flags-mask", "", "FixedFileInfo.FileFlagsMask, hex or VS_FFI_FILEFLAGSMASK")
+	flagFileFlags := flag.String("file-flags", "", "FixedFileInfo.FileFlags, hex or names like VS_FF_DEBUG|VS_FF_PRERELEASE")
+	flagFileOS := flag.String("file-os", "", "FixedFileInfo.FileOS, hex or a name like VOS_NT_WINDOWS32")
+	flagFileType := flag.String("file-type", "", "FixedFileInfo.FileType, hex or a name like VFT_DLL")
+	flagFileSubType := flag.String("file-subtype", "", "FixedFileInfo.FileSubType, hex or a name like VFT2_DRV_PRINTER")
+	flagFileDate := flag.String("file-date", "", "FixedFileInfo.FileDate: an RFC 3339 time, SOURCE_DATE_EPOCH or git for the last commit")
 
 	flagTranslation := flag.Int("translation", 0, "translation ID")
 	flagCharset := flag.Int("charset", 0, "charset ID")
@@ -63,7 +107,11 @@ func main() {
 	flagProductVerBuild := flag.Int("product-ver-build", -1, "ProductVersion.Build")
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <versioninfo.json>\n\nPossible flags:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <versioninfo.json|versioninfo.rc>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s patch [flags] <file.exe> [versioninfo.json|versioninfo.rc]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s extract [-o dir] <file.exe>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s inspect <file.syso|file.exe|file.res>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s lint-icon <file.ico>...\n\nPossible flags:\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 	flag.Parse()
@@ -74,19 +122,37 @@ func main() {
 	}
 
 	cfg.ConfigFile = flag.Arg(0)
-	if cfg.ConfigFile == "" {
+	if patch {
+		if flag.NArg() == 0 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		cfg.PatchFile, cfg.ConfigFile = flag.Arg(0), flag.Arg(1)
+	} else if cfg.ConfigFile == "" {
 		cfg.ConfigFile = "versioninfo.json"
 	}
 
 	cfg.OutputFile = *flagOut
+	cfg.Format = *flagFormat
 	cfg.GoFile = *flagGo
 	cfg.GoFilePackage = *flagPackage
 	cfg.PlatformSpecific = *flagPlatformSpecific
 	cfg.IconPath = *flagIcon
 	cfg.ApplicationIconPath = *flagApplicationIcon
 	cfg.ManifestPath = *flagManifest
+	cfg.ResPath = *flagRes
+	cfg.ResConflict = *flagResConflict
+	cfg.MessageTablePath = *flagMC
+	cfg.MessageGoFile = *flagMCGo
+	cfg.Resources = flagResources
 	cfg.SkipVersionInfo = *flagSkipVersion
 	cfg.PropagateVerStrings = *flagPropagateVerStrings
+	cfg.VersionFromGit = *flagVersionFromGit
+	cfg.SemVer = *flagSemVer
+	cfg.SemVerPrerelease = *flagSemVerPrerelease
+	cfg.SemVerMetadata = *flagSemVerMetadata
+	cfg.Strict = *flagStrict
+	cfg.WarningsAsErrors = *flagWarningsAsErrors
 
 	cfg.Comment = *flagComment
 	cfg.CompanyName = *flagCompany
@@ -100,6 +166,14 @@ func main() {
 	cfg.ProductName = *flagProductName
 	cfg.ProductVersion = *flagProductVersion
 	cfg.SpecialBuild = *flagSpecialBuild
+	cfg.Strings = goversioninfo.CustomStrings(flagStrings)
+
+	cfg.FileFlagsMask = *flagFileFlagsMask
+	cfg.FileFlags = *flagFileFlags
+	cfg.FileOS = *flagFileOS
+	cfg.FileType = *flagFileType
+	cfg.FileSubType = *flagFileSubType
+	cfg.FileDate = *flagFileDate
 
 	cfg.TranslationID = *flagTranslation
 	cfg.CharsetID = *flagCharset
@@ -122,6 +196,133 @@ func main() {
 	}
 }
 
+// extract saves the version info, icons and manifest of an executable.
+func extract(args []string) {
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	flagDir := fs.String("o", ".", "output directory")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s extract [-o dir] <file.exe>\n\nPossible flags:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	er, err := goversioninfo.ReadExecutable(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := er.WriteFiles(*flagDir); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// inspect lists the resources of object files, executables and .res files.
+func inspect(args []string) {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s inspect <file.syso|file.exe|file.res>...\n", os.Args[0])
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	for i, name := range fs.Args() {
+		res, err := goversioninfo.ReadResources(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if fs.NArg() > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", name)
+		}
+		if err := res.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// lintIcon reports the images of .ico files and what is wrong with them. It
+// exits with status 1 when an icon has errors.
+func lintIcon(args []string) {
+	fs := flag.NewFlagSet("lint-icon", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s lint-icon <file.ico>...\n", os.Args[0])
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	failed := false
+	for i, name := range fs.Args() {
+		report, err := goversioninfo.LintIcon(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if fs.NArg() > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", name)
+		}
+		if err := report.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		failed = failed || report.HasErrors()
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// stringsFlag collects every -string Key=Value flag in order.
+type stringsFlag goversioninfo.CustomStrings
+
+func (s *stringsFlag) String() string {
+	pairs := make([]string, len(*s))
+	for i, pair := range *s {
+		pairs[i] = pair.Key + "=" + pair.Value
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected Key=Value, got %q", value)
+	}
+	*s = append(*s, goversioninfo.StringPair{Key: key, Value: val})
+	return nil
+}
+
+// resourcesFlag collects every -resource flag in order.
+type resourcesFlag []goversioninfo.FileResource
+
+func (r *resourcesFlag) String() string {
+	specs := make([]string, len(*r))
+	for i, fr := range *r {
+		specs[i] = fr.String()
+	}
+	return strings.Join(specs, ",")
+}
+
+func (r *resourcesFlag) Set(value string) error {
+	fr, err := goversioninfo.ParseFileResource(value)
+	if err != nil {
+		return err
+	}
+	*r = append(*r, fr)
+	return nil
+}
+
 const example = `{
 	"FixedFileInfo": {
 		"FileVersion": {
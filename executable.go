@@ -0,0 +1,277 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/akavel/rsrc/ico"
+)
+
+// *****************************************************************************
+// Executable Reading
+// *****************************************************************************
+
+// ExecutableResources holds the resources embedded in a PE file.
+type ExecutableResources struct {
+	// Resources lists every resource in directory order.
+	Resources Resources
+	// VersionInfo is decoded from the RT_VERSION resource, nil without one.
+	VersionInfo *VersionInfo
+	// Manifest is the data of the RT_MANIFEST resource.
+	Manifest []byte
+	// Icons holds one .ico file for each RT_GROUP_ICON resource.
+	Icons []Icon
+	// StringResources holds the strings of the RT_STRING resources, one
+	// entry for each language.
+	StringResources []StringResource
+}
+
+// Icon is an icon group and its images rebuilt as an .ico file.
+type Icon struct {
+	ID     ResourceID
+	LangID LangID
+	Data   []byte
+}
+
+// ReadExecutable reads the resources of a Windows executable or DLL. The file
+// is only parsed, so this works on any platform.
+func ReadExecutable(filename string) (*ExecutableResources, error) {
+	f, err := pe.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	res, err := executableResources(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	er, err := newExecutableResources(res)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return er, nil
+}
+
+// maxZeroFill is how many bytes past the raw data of a section readRVA fills
+// with zeros, which covers the padding of the section alignment.
+const maxZeroFill = 0x10000
+
+// executableResources reads the resource directory of an image file.
+func executableResources(f *pe.File) (Resources, error) {
+	var dd pe.DataDirectory
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		if oh.NumberOfRvaAndSizes > pe.IMAGE_DIRECTORY_ENTRY_RESOURCE {
+			dd = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE]
+		}
+	case *pe.OptionalHeader64:
+		if oh.NumberOfRvaAndSizes > pe.IMAGE_DIRECTORY_ENTRY_RESOURCE {
+			dd = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE]
+		}
+	default:
+		return nil, errors.New("not an executable image, the optional header is missing")
+	}
+	if dd.VirtualAddress == 0 || dd.Size == 0 {
+		return nil, nil
+	}
+
+	sections := map[*pe.Section][]byte{}
+	readRVA := func(_, rva, size uint32) ([]byte, error) {
+		for _, s := range f.Sections {
+			virtualSize := s.VirtualSize
+			if virtualSize == 0 {
+				virtualSize = s.Size
+			}
+			if rva < s.VirtualAddress || uint64(rva)+uint64(size) > uint64(s.VirtualAddress)+uint64(virtualSize) {
+				continue
+			}
+			data, ok := sections[s]
+			if !ok {
+				var err error
+				if data, err = s.Data(); err != nil {
+					return nil, fmt.Errorf("section %s: %w", s.Name, err)
+				}
+				sections[s] = data
+			}
+			// Anything past the raw data is zero filled when loaded, but
+			// only up to a bound, or a crafted size could take all memory.
+			off := rva - s.VirtualAddress
+			if uint64(off)+uint64(size) > uint64(len(data))+maxZeroFill {
+				return nil, fmt.Errorf("RVA %#x with size %d runs past the %d bytes of section %s", rva, size, len(data), s.Name)
+			}
+			if uint64(off)+uint64(size) <= uint64(len(data)) {
+				return data[off : off+size], nil
+			}
+			b := make([]byte, size)
+			if off < uint32(len(data)) {
+				copy(b, data[off:])
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("RVA %#x with size %d is not inside any section", rva, size)
+	}
+
+	dir, err := readRVA(0, dd.VirtualAddress, dd.Size)
+	if err != nil {
+		return nil, fmt.Errorf("resource directory: %w", err)
+	}
+	return parseResourceDirectory(dir, readRVA)
+}
+
+func newExecutableResources(res Resources) (*ExecutableResources, error) {
+	er := &ExecutableResources{Resources: res}
+
+	images := map[uint16][]byte{}
+	for _, r := range res {
+		if r.Type == (ResourceID{ID: rtIcon}) && r.Name.Name == "" {
+			images[r.Name.ID] = r.Data
+		}
+	}
+
+	for _, r := range res {
+		if r.Type.Name != "" {
+			continue
+		}
+		switch uint32(r.Type.ID) {
+		case rtVersion:
+			if er.VersionInfo != nil {
+				continue
+			}
+			vi, err := DecodeVersionInfo(r.Data)
+			if err != nil {
+				return nil, fmt.Errorf("RT_VERSION %s: %w", r.Name, err)
+			}
+			er.VersionInfo = vi
+		case rtManifest:
+			if er.Manifest == nil {
+				er.Manifest = r.Data
+			}
+		case rtGroupIcon:
+			data, err := iconFile(r.Data, images)
+			if err != nil {
+				return nil, fmt.Errorf("RT_GROUP_ICON %s: %w", r.Name, err)
+			}
+			er.Icons = append(er.Icons, Icon{ID: r.Name, LangID: r.LangID, Data: data})
+		case rtString:
+			if r.Name.Name != "" {
+				continue
+			}
+			strs := er.languageStrings(r.LangID)
+			if err := decodeStringBlock(strs, r.Name.ID, r.Data); err != nil {
+				return nil, fmt.Errorf("RT_STRING %s: %w", r.Name, err)
+			}
+		}
+	}
+
+	return er, nil
+}
+
+// languageStrings returns the strings of the language, adding an entry to
+// StringResources for a new one.
+func (er *ExecutableResources) languageStrings(lang LangID) map[uint16]string {
+	for _, st := range er.StringResources {
+		if st.lang() == lang {
+			return st.Strings
+		}
+	}
+	st := StringResource{Strings: map[uint16]string{}}
+	if lang != LngUSEnglish {
+		st.LangID = &lang
+	}
+	er.StringResources = append(er.StringResources, st)
+	return st.Strings
+}
+
+// iconFile joins an RT_GROUP_ICON and the RT_ICON images it refers to into
+// the layout of an .ico file.
+func iconFile(group []byte, images map[uint16][]byte) ([]byte, error) {
+	r := bytes.NewReader(group)
+	var dir ico.ICONDIR
+	if err := binary.Read(r, binary.LittleEndian, &dir); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	entries := make([]gRPICONDIRENTRY, dir.Count)
+	if err := binary.Read(r, binary.LittleEndian, entries); err != nil {
+		return nil, fmt.Errorf("reading %d entries: %w", dir.Count, err)
+	}
+
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, dir)
+
+	offset := binary.Size(dir) + len(entries)*binary.Size(ico.ICONDIRENTRY{})
+	for _, e := range entries {
+		img, ok := images[e.ID]
+		if !ok {
+			return nil, fmt.Errorf("RT_ICON %d is missing", e.ID)
+		}
+		entry := ico.ICONDIRENTRY{IconDirEntryCommon: e.IconDirEntryCommon, ImageOffset: uint32(offset)}
+		entry.BytesInRes = uint32(len(img))
+		binary.Write(&b, binary.LittleEndian, entry)
+		offset += len(img)
+	}
+	for _, e := range entries {
+		b.Write(images[e.ID])
+	}
+
+	return b.Bytes(), nil
+}
+
+// WriteFiles saves the icons, the manifest and a versioninfo.json that refers
+// to them into dir, so the resources can be rebuilt with this package.
+func (er *ExecutableResources) WriteFiles(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// Icons keep their IDs, names and languages
+	var appIconPath string
+	var icons []IconResource
+	for _, icon := range er.Icons {
+		name := "icon_" + icon.ID.String()
+		if icon.LangID != LngUSEnglish {
+			name += fmt.Sprintf("_%04x", uint16(icon.LangID))
+		}
+		path := filepath.Join(dir, name+".ico")
+		if err := os.WriteFile(path, icon.Data, 0644); err != nil {
+			return err
+		}
+		if icon.ID == (ResourceID{ID: 32512}) && icon.LangID == LngUSEnglish {
+			appIconPath = path
+			continue
+		}
+		ir := IconResource{Path: path, ID: icon.ID}
+		if icon.LangID != LngUSEnglish {
+			lang := icon.LangID
+			ir.LangID = &lang
+		}
+		icons = append(icons, ir)
+	}
+
+	var manifestPath string
+	if er.Manifest != nil {
+		manifestPath = filepath.Join(dir, "app.manifest")
+		if err := os.WriteFile(manifestPath, er.Manifest, 0644); err != nil {
+			return err
+		}
+	}
+
+	if er.VersionInfo == nil {
+		return nil
+	}
+
+	vi := *er.VersionInfo
+	vi.IconPath = ""
+	vi.ApplicationIconPath = appIconPath
+	vi.Icons = icons
+	vi.ManifestPath = manifestPath
+	vi.StringResources = er.StringResources
+	return vi.WriteJSON(filepath.Join(dir, "versioninfo.json"))
+}
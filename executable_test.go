@@ -0,0 +1,216 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/akavel/rsrc/ico"
+	"github.com/stretchr/testify/assert"
+)
+
+// buildExecutable links a small Windows program with the given .syso, if
+// any, and returns the path of the executable.
+func buildExecutable(t *testing.T, dir, syso, arch string) string {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("skipping go build in short mode")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	src := filepath.Join(dir, "src")
+	assert.NoError(t, os.MkdirAll(src, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "go.mod"), []byte("module example\n\ngo 1.19\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	if syso != "" {
+		sysoData, err := os.ReadFile(syso)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(src, "resource.syso"), sysoData, 0644))
+	}
+
+	exe := filepath.Join(dir, "example.exe")
+	cmd := exec.Command(goBin, "build", "-o", exe)
+	cmd.Dir = src
+	cmd.Env = append(os.Environ(), "GOOS=windows", "GOARCH="+arch, "CGO_ENABLED=0", "GOFLAGS=")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, out)
+	}
+	return exe
+}
+
+func TestReadExecutable(t *testing.T) {
+	jsonBytes, err := os.ReadFile("testdata/json/cmd.json")
+	assert.NoError(t, err)
+
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON(jsonBytes))
+	vi.IconPath = "testdata/resource/icon.ico"
+	vi.ManifestPath = "testdata/resource/goversioninfo.exe.manifest"
+	vi.Build()
+	vi.Walk()
+
+	tmpdir, err := os.MkdirTemp("", "executable")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	syso := filepath.Join(tmpdir, "resource.syso")
+	assert.NoError(t, vi.WriteSyso(syso, "amd64"))
+	exe := buildExecutable(t, tmpdir, syso, "amd64")
+
+	er, err := ReadExecutable(exe)
+	if err != nil {
+		t.Fatal("Could not read the executable", err)
+	}
+
+	if assert.NotNil(t, er.VersionInfo) {
+		got := *er.VersionInfo
+		got.Build()
+		got.Walk()
+		assert.Equal(t, vi.Buffer.Bytes(), got.Buffer.Bytes())
+	}
+
+	manifest, err := os.ReadFile(vi.ManifestPath)
+	assert.NoError(t, err)
+	assert.Equal(t, manifest, er.Manifest)
+
+	icon, err := os.ReadFile(vi.IconPath)
+	assert.NoError(t, err)
+	if assert.Len(t, er.Icons, 2) {
+		assert.Equal(t, ResourceID{ID: 2}, er.Icons[0].ID)
+		assert.Equal(t, ResourceID{ID: 32512}, er.Icons[1].ID)
+		for _, i := range er.Icons {
+			assert.Equal(t, iconImages(t, icon), iconImages(t, i.Data))
+		}
+	}
+
+	out := filepath.Join(tmpdir, "out")
+	assert.NoError(t, er.WriteFiles(out))
+
+	back := &VersionInfo{}
+	jsonBytes, err = os.ReadFile(filepath.Join(out, "versioninfo.json"))
+	assert.NoError(t, err)
+	assert.NoError(t, back.ParseJSON(jsonBytes))
+	assert.Equal(t, er.VersionInfo.StringFileInfo, back.StringFileInfo)
+	assert.Equal(t, "", back.IconPath)
+	assert.Equal(t, []IconResource{{Path: filepath.Join(out, "icon_2.ico"), ID: ResourceID{ID: 2}}}, back.Icons)
+	assert.Equal(t, filepath.Join(out, "icon_32512.ico"), back.ApplicationIconPath)
+	assert.Equal(t, filepath.Join(out, "app.manifest"), back.ManifestPath)
+	for _, name := range []string{back.Icons[0].Path, back.ApplicationIconPath, back.ManifestPath} {
+		_, err := os.Stat(name)
+		assert.NoError(t, err)
+	}
+}
+
+func TestWriteFilesIcons(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "extract")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	er := &ExecutableResources{
+		VersionInfo: &VersionInfo{},
+		Icons: []Icon{
+			{ID: ResourceID{ID: 101}, LangID: LngUSEnglish, Data: []byte("a")},
+			{ID: ResourceID{ID: 101}, LangID: LngGerman, Data: []byte("b")},
+			{ID: ResourceID{Name: "TRAYICON"}, LangID: LngUSEnglish, Data: []byte("c")},
+			{ID: ResourceID{ID: 32512}, LangID: LngUSEnglish, Data: []byte("d")},
+		},
+	}
+	assert.NoError(t, er.WriteFiles(tmpdir))
+
+	back := &VersionInfo{}
+	jsonBytes, err := os.ReadFile(filepath.Join(tmpdir, "versioninfo.json"))
+	assert.NoError(t, err)
+	assert.NoError(t, back.ParseJSON(jsonBytes))
+	german := LngGerman
+	assert.Equal(t, "", back.IconPath)
+	assert.Equal(t, []IconResource{
+		{Path: filepath.Join(tmpdir, "icon_101.ico"), ID: ResourceID{ID: 101}},
+		{Path: filepath.Join(tmpdir, "icon_101_0407.ico"), ID: ResourceID{ID: 101}, LangID: &german},
+		{Path: filepath.Join(tmpdir, "icon_TRAYICON.ico"), ID: ResourceID{Name: "TRAYICON"}},
+	}, back.Icons)
+	assert.Equal(t, filepath.Join(tmpdir, "icon_32512.ico"), back.ApplicationIconPath)
+
+	b, err := os.ReadFile(back.Icons[1].Path)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", string(b))
+}
+
+// iconImages returns the header and data of every image in an .ico file.
+func iconImages(t *testing.T, data []byte) []interface{} {
+	t.Helper()
+
+	icons, err := ico.DecodeHeaders(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	var images []interface{}
+	for _, icon := range icons {
+		end := int(icon.ImageOffset) + int(icon.BytesInRes)
+		if end > len(data) {
+			t.Fatalf("icon image ends at %d, past the %d byte file", end, len(data))
+		}
+		images = append(images, icon.IconDirEntryCommon, data[icon.ImageOffset:end])
+	}
+	return images
+}
+
+func TestReadExecutableCrafted(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "executable")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	vi := &VersionInfo{}
+	vi.Build()
+	vi.Walk()
+	syso := filepath.Join(tmpdir, "resource.syso")
+	assert.NoError(t, vi.WriteSyso(syso, "amd64"))
+	exe := buildExecutable(t, tmpdir, syso, "amd64")
+
+	// Claim a resource directory of almost 4 GB in a .rsrc section that is
+	// as large in memory, but not in the file.
+	b, err := os.ReadFile(exe)
+	assert.NoError(t, err)
+	le := binary.LittleEndian
+	peOff := le.Uint32(b[0x3c:])
+	optOff := peOff + 24
+	le.PutUint32(b[optOff+112+8*pe.IMAGE_DIRECTORY_ENTRY_RESOURCE+4:], 0xfffff000)
+	sections := optOff + uint32(le.Uint16(b[peOff+20:]))
+	for i := uint32(0); i < uint32(le.Uint16(b[peOff+6:])); i++ {
+		if sh := b[sections+40*i:]; bytes.HasPrefix(sh, []byte(".rsrc\x00")) {
+			le.PutUint32(sh[8:], 0xfffff000)
+		}
+	}
+	crafted := filepath.Join(tmpdir, "crafted.exe")
+	assert.NoError(t, os.WriteFile(crafted, b, 0644))
+
+	_, err = ReadExecutable(crafted)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "resource directory: RVA ")
+		assert.Contains(t, err.Error(), "with size 4294963200 runs past the ")
+	}
+}
+
+func TestParseResourceDirectoryLoop(t *testing.T) {
+	// Both types of the root point at the same subdirectory.
+	dir := make([]byte, 48)
+	le := binary.LittleEndian
+	le.PutUint16(dir[14:], 2)
+	le.PutUint32(dir[16:], 1)
+	le.PutUint32(dir[20:], 0x80000020)
+	le.PutUint32(dir[24:], 2)
+	le.PutUint32(dir[28:], 0x80000020)
+	_, err := parseResourceDirectory(dir, nil)
+	assert.EqualError(t, err, "resource directory: directory at 0x20 is referenced twice")
+}
+
+func TestReadExecutableNotPE(t *testing.T) {
+	_, err := ReadExecutable("testdata/hex/cmd.hex")
+	assert.Error(t, err)
+}
@@ -0,0 +1,130 @@
+package goversioninfo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// *****************************************************************************
+// File Date
+// *****************************************************************************
+
+// Sources of FixedFileInfo.FileDate besides an RFC 3339 time, which
+// ResolveFileDate replaces with the time they stand for.
+const (
+	// FileDateSourceDateEpoch takes the date from the SOURCE_DATE_EPOCH
+	// environment variable, see https://reproducible-builds.org/specs/source-date-epoch/
+	FileDateSourceDateEpoch = "SOURCE_DATE_EPOCH"
+
+	// FileDateGit takes the date of the last commit of the git repository.
+	FileDateGit = "git"
+)
+
+// fileTimeEpoch is January 1, 1601 UTC, where a FILETIME starts counting.
+var fileTimeEpoch = time.Date(1601, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// fileTime converts t to a Windows FILETIME, the number of 100 nanosecond
+// intervals since January 1, 1601 UTC.
+func fileTime(t time.Time) (uint64, error) {
+	if t.Before(fileTimeEpoch) {
+		return 0, fmt.Errorf("%s is before 1601, where a FILETIME starts", t.Format(time.RFC3339))
+	}
+	secs := uint64(t.Unix() - fileTimeEpoch.Unix())
+	return secs*1e7 + uint64(t.Nanosecond()/100), nil
+}
+
+// fromFileTime is the inverse of fileTime.
+func fromFileTime(ft uint64) time.Time {
+	secs := int64(ft / 1e7)
+	nsec := int64(ft%1e7) * 100
+	return time.Unix(fileTimeEpoch.Unix()+secs, nsec).UTC()
+}
+
+// checkFileDate tells if s is a FileDate ResolveFileDate and Build can
+// handle, without looking at the environment or git.
+func checkFileDate(s string) error {
+	switch s {
+	case "", FileDateSourceDateEpoch, FileDateGit:
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return fmt.Errorf("%q is neither an RFC 3339 time, %s nor %s", s, FileDateSourceDateEpoch, FileDateGit)
+	}
+	_, err = fileTime(t)
+	return err
+}
+
+// fileDate converts FixedFileInfo.FileDate to a FILETIME. Zero means no date,
+// so builds stay reproducible unless a date is asked for.
+func (vi *VersionInfo) fileDate() (uint64, error) {
+	s := vi.FixedFileInfo.FileDate
+	switch s {
+	case "":
+		return 0, nil
+	case FileDateSourceDateEpoch, FileDateGit:
+		return 0, fmt.Errorf("FixedFileInfo.FileDate: %s has to be resolved with ResolveFileDate first", s)
+	}
+	if err := checkFileDate(s); err != nil {
+		return 0, fmt.Errorf("FixedFileInfo.FileDate: %w", err)
+	}
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return fileTime(t)
+}
+
+// SetFileDate sets FixedFileInfo.FileDate to t.
+func (vi *VersionInfo) SetFileDate(t time.Time) {
+	vi.FixedFileInfo.FileDate = t.UTC().Format(time.RFC3339Nano)
+}
+
+// ResolveFileDate replaces a FixedFileInfo.FileDate of SOURCE_DATE_EPOCH or
+// git with the time it stands for, reading the git repository in dir. Other
+// dates are left as they are.
+func (vi *VersionInfo) ResolveFileDate(dir string) error {
+	var t time.Time
+	var err error
+	switch vi.FixedFileInfo.FileDate {
+	case FileDateSourceDateEpoch:
+		t, err = ReadSourceDateEpoch()
+	case FileDateGit:
+		t, err = ReadGitCommitTime(dir)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("FixedFileInfo.FileDate: %w", err)
+	}
+	vi.SetFileDate(t)
+	return nil
+}
+
+// ReadSourceDateEpoch returns the time SOURCE_DATE_EPOCH holds in seconds
+// since the Unix epoch.
+func ReadSourceDateEpoch() (time.Time, error) {
+	s, ok := os.LookupEnv(FileDateSourceDateEpoch)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%s is not set", FileDateSourceDateEpoch)
+	}
+	secs, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s %q is not a number of seconds", FileDateSourceDateEpoch, s)
+	}
+	return time.Unix(secs, 0).UTC(), nil
+}
+
+// ReadGitCommitTime returns the committer date of HEAD in the git repository
+// in dir, or in the working directory if dir is empty.
+func ReadGitCommitTime(dir string) (time.Time, error) {
+	out, err := runGit(dir, "log", "-1", "--format=%ct")
+	if err != nil {
+		return time.Time{}, err
+	}
+	secs, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("git log: unexpected commit time %q", out)
+	}
+	return time.Unix(secs, 0).UTC(), nil
+}
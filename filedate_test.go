@@ -0,0 +1,94 @@
+package goversioninfo
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileTime(t *testing.T) {
+	tests := []struct {
+		in  time.Time
+		out uint64
+	}{
+		{time.Date(1601, time.January, 1, 0, 0, 0, 0, time.UTC), 0},
+		{time.Unix(0, 0), 116444736000000000},
+		{time.Date(2024, time.February, 29, 12, 30, 0, 123456789, time.UTC), 133536834001234567},
+		{time.Date(2024, time.February, 29, 13, 30, 0, 0, time.FixedZone("CET", 3600)), 133536834000000000},
+	}
+	for _, tt := range tests {
+		got, err := fileTime(tt.in)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.out, got, tt.in.String())
+		assert.True(t, tt.in.Truncate(100).Equal(fromFileTime(got)), tt.in.String())
+	}
+
+	_, err := fileTime(time.Date(1600, time.December, 31, 0, 0, 0, 0, time.UTC))
+	assert.EqualError(t, err, "1600-12-31T00:00:00Z is before 1601, where a FILETIME starts")
+}
+
+func TestBuildFileDate(t *testing.T) {
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.Build())
+	assert.Zero(t, vi.Structure.Value.DwFileDateMS)
+	assert.Zero(t, vi.Structure.Value.DwFileDateLS)
+
+	vi.FixedFileInfo.FileDate = "2024-02-29T12:30:00Z"
+	assert.NoError(t, vi.Build())
+	assert.Equal(t, uint32(0x01DA6B0B), vi.Structure.Value.DwFileDateMS)
+	assert.Equal(t, uint32(0x03001400), vi.Structure.Value.DwFileDateLS)
+
+	// The date survives a round trip.
+	vi.Walk()
+	decoded, err := DecodeVersionInfo(vi.Buffer.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-02-29T12:30:00Z", decoded.FixedFileInfo.FileDate)
+
+	// Build leaves SOURCE_DATE_EPOCH to ResolveFileDate.
+	t.Setenv(FileDateSourceDateEpoch, "1709209800")
+	vi.FixedFileInfo.FileDate = FileDateSourceDateEpoch
+	assert.EqualError(t, vi.Build(), "FixedFileInfo.FileDate: SOURCE_DATE_EPOCH has to be resolved with ResolveFileDate first")
+	assert.NoError(t, vi.ResolveFileDate(""))
+	assert.Equal(t, "2024-02-29T12:30:00Z", vi.FixedFileInfo.FileDate)
+	assert.NoError(t, vi.Build())
+	assert.Equal(t, uint32(0x01DA6B0B), vi.Structure.Value.DwFileDateMS)
+	assert.Equal(t, uint32(0x03001400), vi.Structure.Value.DwFileDateLS)
+
+	// Timestamp does not set a date.
+	vi.FixedFileInfo.FileDate = ""
+	vi.Timestamp = true
+	assert.NoError(t, vi.Build())
+	assert.Equal(t, uint32(0), vi.Structure.Value.DwFileDateMS)
+	assert.Equal(t, uint32(0), vi.Structure.Value.DwFileDateLS)
+
+	vi.FixedFileInfo.FileDate = FileDateSourceDateEpoch
+	os.Setenv(FileDateSourceDateEpoch, "yesterday")
+	assert.EqualError(t, vi.ResolveFileDate(""), `FixedFileInfo.FileDate: SOURCE_DATE_EPOCH "yesterday" is not a number of seconds`)
+	os.Unsetenv(FileDateSourceDateEpoch)
+	assert.EqualError(t, vi.ResolveFileDate(""), "FixedFileInfo.FileDate: SOURCE_DATE_EPOCH is not set")
+	assert.Equal(t, FileDateSourceDateEpoch, vi.FixedFileInfo.FileDate)
+
+	vi.FixedFileInfo.FileDate = "2024-02-29"
+	assert.Equal(t, []Issue{{SeverityError, "FixedFileInfo.FileDate",
+		`"2024-02-29" is neither an RFC 3339 time, SOURCE_DATE_EPOCH nor git`}}, vi.Validate())
+}
+
+func TestResolveFileDateGit(t *testing.T) {
+	dir, git := newGitRepo(t)
+
+	vi := &VersionInfo{}
+	vi.FixedFileInfo.FileDate = FileDateGit
+	err := vi.ResolveFileDate(dir)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "FixedFileInfo.FileDate: git log: ")
+	}
+
+	git("commit", "-q", "--allow-empty", "-m", "initial")
+	assert.NoError(t, vi.ResolveFileDate(dir))
+	assert.Equal(t, "2024-02-29T12:30:00Z", vi.FixedFileInfo.FileDate)
+	assert.NoError(t, vi.Build())
+	assert.Equal(t, uint32(0x01DA6B0B), vi.Structure.Value.DwFileDateMS)
+	assert.Equal(t, uint32(0x03001400), vi.Structure.Value.DwFileDateLS)
+}
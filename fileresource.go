@@ -0,0 +1,140 @@
+package goversioninfo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// *****************************************************************************
+// File Resources
+// *****************************************************************************
+
+const (
+	rtRCData = 10
+	rtHTML   = 23
+)
+
+// FileResource is a file embedded as it is, like a license text, a default
+// config or a JSON schema, for programs that load it with FindResource and
+// LoadResource.
+type FileResource struct {
+	// Type is RT_RCDATA when it is 0. It is a number, a predefined type like
+	// RT_HTML or HTML, or a name of its own like SCHEMA. In JSON it is a
+	// number or a string, where "#256" stands for the number 256.
+	Type ResourceID
+
+	// ID is the number or the name of the resource, like the ID of an
+	// IconResource.
+	ID ResourceID
+
+	// LangID is the language of the resource, U.S. English when it is not
+	// set.
+	LangID *LangID `json:",omitempty"`
+
+	Path string
+}
+
+// resourceType returns the type the resource is stored under, with the names
+// of the predefined types turned into their numbers.
+func (fr FileResource) resourceType() ResourceID {
+	if fr.Type == (ResourceID{}) {
+		return ResourceID{ID: rtRCData}
+	}
+	if fr.Type.Name != "" {
+		return parseResourceType(fr.Type.Name)
+	}
+	return fr.Type
+}
+
+// lang returns the language the resource is stored in.
+func (fr FileResource) lang() LangID {
+	if fr.LangID != nil {
+		return *fr.LangID
+	}
+	return LngUSEnglish
+}
+
+// parseResourceType reads a type number or name. The predefined types may
+// be written with or without RT_, like RT_RCDATA or RCDATA, as rc.exe does.
+func parseResourceType(s string) ResourceID {
+	upper := strings.TrimPrefix(strings.ToUpper(s), "RT_")
+	for id, name := range resourceTypeNames {
+		if name == "RT_"+upper {
+			return ResourceID{ID: id}
+		}
+	}
+	if id, err := parseResourceID(s); err == nil {
+		return id
+	}
+	return ResourceID{Name: s}
+}
+
+// parseResourceID reads a number, a number of the form #101, or a name.
+func parseResourceID(s string) (ResourceID, error) {
+	digits := strings.TrimPrefix(s, "#")
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return ResourceID{Name: s}, nil
+	}
+	u, err := strconv.ParseUint(digits, 10, 16)
+	if err != nil {
+		return ResourceID{}, fmt.Errorf("resource ID %q is not a 16-bit number", s)
+	}
+	return ResourceID{ID: uint16(u)}, nil
+}
+
+// ParseFileResource reads a resource in the type:id:path form of the
+// -resource flag, like RCDATA:LICENSE:LICENSE.txt. The form
+// type:id:lang=XXXX:path adds a language of four hex digits, like
+// lang=0407. The path may contain colons.
+func ParseFileResource(s string) (FileResource, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" {
+		return FileResource{}, fmt.Errorf("expected type:id:path, got %q", s)
+	}
+
+	fr := FileResource{Type: parseResourceType(parts[0])}
+	id, err := parseResourceID(parts[1])
+	if err != nil {
+		return FileResource{}, err
+	}
+	fr.ID = id
+
+	fr.Path = parts[2]
+	if strings.HasPrefix(fr.Path, "lang=") {
+		var hex string
+		hex, fr.Path, _ = strings.Cut(strings.TrimPrefix(fr.Path, "lang="), ":")
+		u, err := strconv.ParseUint(hex, 16, 16)
+		if err != nil || len(hex) != 4 {
+			return FileResource{}, fmt.Errorf("language %q is not four hex digits like lang=0407", hex)
+		}
+		lang := LangID(u)
+		fr.LangID = &lang
+	}
+	if fr.Path == "" {
+		return FileResource{}, fmt.Errorf("expected type:id:path, got %q", s)
+	}
+	return fr, nil
+}
+
+// String returns the resource in the form ParseFileResource reads.
+func (fr FileResource) String() string {
+	s := fr.Type.String() + ":" + fr.ID.String() + ":"
+	if fr.LangID != nil {
+		s += fmt.Sprintf("lang=%04X:", uint16(*fr.LangID))
+	}
+	return s + fr.Path
+}
+
+// addFileResources reads the files of the resources.
+func addFileResources(res *Resources, files []FileResource) error {
+	for _, fr := range files {
+		data, err := os.ReadFile(fr.Path)
+		if err != nil {
+			return err
+		}
+		*res = append(*res, Resource{Type: fr.resourceType(), Name: fr.ID, LangID: fr.lang(), Data: data})
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package goversioninfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFileResource(t *testing.T) {
+	german := LngGerman
+	tests := []struct {
+		spec string
+		want FileResource
+	}{
+		{"RCDATA:LICENSE:LICENSE.txt", FileResource{Type: ResourceID{ID: rtRCData}, ID: ResourceID{Name: "LICENSE"}, Path: "LICENSE.txt"}},
+		{"rt_html:101:index.html", FileResource{Type: ResourceID{ID: rtHTML}, ID: ResourceID{ID: 101}, Path: "index.html"}},
+		{"SCHEMA:#7:lang=0407:schema.json", FileResource{Type: ResourceID{Name: "SCHEMA"}, ID: ResourceID{ID: 7}, LangID: &german, Path: "schema.json"}},
+		{"256:CONFIG:C:\\app\\config.ini", FileResource{Type: ResourceID{ID: 256}, ID: ResourceID{Name: "CONFIG"}, Path: "C:\\app\\config.ini"}},
+		// Without the marker, four hex digits are a directory
+		{"RCDATA:1:cafe:data.bin", FileResource{Type: ResourceID{ID: rtRCData}, ID: ResourceID{ID: 1}, Path: "cafe:data.bin"}},
+	}
+	for _, tt := range tests {
+		fr, err := ParseFileResource(tt.spec)
+		assert.NoError(t, err, tt.spec)
+		assert.Equal(t, tt.want, fr, tt.spec)
+
+		again, err := ParseFileResource(fr.String())
+		assert.NoError(t, err, fr.String())
+		assert.Equal(t, fr, again, fr.String())
+	}
+
+	for _, spec := range []string{"LICENSE.txt", "RCDATA:LICENSE", ":1:a.txt", "RCDATA::a.txt", "RCDATA:1:", "RCDATA:1:lang=0407"} {
+		_, err := ParseFileResource(spec)
+		assert.EqualError(t, err, `expected type:id:path, got "`+spec+`"`)
+	}
+	_, err := ParseFileResource("RCDATA:70000:a.txt")
+	assert.EqualError(t, err, `resource ID "70000" is not a 16-bit number`)
+	_, err = ParseFileResource("RCDATA:1:lang=German:a.txt")
+	assert.EqualError(t, err, `language "German" is not four hex digits like lang=0407`)
+	assert.Equal(t, "SCHEMA:7:lang=0407:schema.json", FileResource{Type: ResourceID{Name: "SCHEMA"}, ID: ResourceID{ID: 7}, LangID: &german, Path: "schema.json"}.String())
+}
+
+func TestFileResources(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "resource")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	license := filepath.Join(tmpdir, "LICENSE.txt")
+	assert.NoError(t, os.WriteFile(license, []byte("MIT"), 0644))
+	schema := filepath.Join(tmpdir, "schema.json")
+	assert.NoError(t, os.WriteFile(schema, []byte("{}"), 0644))
+
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON([]byte(`{"Resources": [
+		{"ID": "LICENSE", "Path": `+jsonString(license)+`},
+		{"Type": "SCHEMA", "ID": 1, "LangID": "0407", "Path": `+jsonString(schema)+`}
+	]}`)))
+	assert.Empty(t, vi.Validate())
+
+	res, err := vi.Resources()
+	assert.NoError(t, err)
+	data := map[string]string{}
+	for _, r := range res {
+		data[fmt.Sprintf("%s %s %04x", r.Type.typeString(), r.Name, uint16(r.LangID))] = string(r.Data)
+	}
+	assert.Equal(t, "MIT", data["RT_RCDATA LICENSE 0409"])
+	assert.Equal(t, "{}", data["SCHEMA 1 0407"])
+
+	b, err := json.Marshal(vi.FileResources[1])
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Type":"SCHEMA","ID":1,"LangID":1031,"Path":`+jsonString(schema)+`}`, string(b))
+
+	// Ties are reported by Validate and fail the build
+	vi.Icons = []IconResource{{Path: "testdata/resource/icon.ico", ID: ResourceID{ID: 2}}}
+	vi.FileResources = append(vi.FileResources,
+		FileResource{Type: ResourceID{Name: "RT_VERSION"}, ID: ResourceID{ID: 1}, Path: license},
+		FileResource{Type: ResourceID{Name: "RT_GROUP_ICON"}, ID: ResourceID{ID: 2}, Path: license},
+		FileResource{Type: ResourceID{ID: rtRCData}, ID: ResourceID{Name: "license"}, Path: license},
+		FileResource{Path: filepath.Join(tmpdir, "missing.txt")},
+	)
+	assert.Equal(t, []Issue{
+		{SeverityError, "Resources[2].ID", "RT_VERSION 1 is already used by the version info"},
+		{SeverityError, "Resources[3].ID", "RT_GROUP_ICON 2 is already used by Icons[0]"},
+		{SeverityError, "Resources[4].ID", "RT_RCDATA license is already used by Resources[0]"},
+		{SeverityError, "Resources[5].Path", "resource file " + filepath.Join(tmpdir, "missing.txt") + " does not exist"},
+		{SeverityError, "Resources[5].ID", "needs a number above 0 or a name"},
+	}, vi.Validate())
+
+	vi.Icons = nil
+	vi.FileResources = append(vi.FileResources[:2], vi.FileResources[4])
+	_, err = vi.Resources()
+	assert.EqualError(t, err, "RT_RCDATA license language 0409 is defined twice")
+}
+
+func TestFileResourcesRC(t *testing.T) {
+	german := LngGerman
+	vi := &VersionInfo{FileResources: []FileResource{
+		{ID: ResourceID{Name: "LICENSE"}, Path: "LICENSE.txt"},
+		{Type: ResourceID{Name: "HTML"}, ID: ResourceID{ID: 101}, Path: "index.html"},
+		{Type: ResourceID{Name: "SCHEMA"}, ID: ResourceID{ID: 7}, LangID: &german, Path: "schema.json"},
+		{Type: ResourceID{ID: 256}, ID: ResourceID{ID: 8}, Path: "data.bin"},
+	}}
+	b, err := vi.rc()
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "\n\"LICENSE\" RCDATA \"LICENSE.txt\"\n\n101 HTML \"index.html\"\n\n"+
+		"LANGUAGE 0x07, 0x01\n7 \"SCHEMA\" \"schema.json\"\nLANGUAGE 0x09, 0x01\n\n8 256 \"data.bin\"\n")
+
+	parsed := &VersionInfo{}
+	assert.NoError(t, parsed.ParseRC(b))
+	assert.Equal(t, []FileResource{
+		{Type: ResourceID{ID: rtRCData}, ID: ResourceID{Name: "LICENSE"}, Path: "LICENSE.txt"},
+		{Type: ResourceID{ID: rtHTML}, ID: ResourceID{ID: 101}, Path: "index.html"},
+		{Type: ResourceID{Name: "SCHEMA"}, ID: ResourceID{ID: 7}, LangID: &german, Path: "schema.json"},
+		{Type: ResourceID{ID: 256}, ID: ResourceID{ID: 8}, Path: "data.bin"},
+	}, parsed.FileResources)
+}
+
+// jsonString returns s as a JSON string.
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,139 @@
+package goversioninfo
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// *****************************************************************************
+// Git
+// *****************************************************************************
+
+// GitVersion is the version of a git working tree, taken from the nearest
+// version tag like v1.2.3 or 1.2.3.
+type GitVersion struct {
+	// Tag is the nearest version tag, empty when there is none.
+	Tag string
+
+	// Version holds the version of the tag, with the commits since the
+	// tag as Build.
+	Version FileVersion
+
+	// Prerelease is the prerelease of a semantic version tag, like rc.2 for
+	// v2.3.1-rc.2.
+	Prerelease string
+
+	// Hash is the abbreviated hash of HEAD.
+	Hash string
+
+	// Dirty is set when the working tree has uncommitted changes.
+	Dirty bool
+}
+
+// describePattern splits the output of git describe --long.
+var describePattern = regexp.MustCompile(`^(.*)-(\d+)-g([0-9a-f]+)$`)
+
+// ReadGitVersion describes the repository dir is in, with the working
+// directory for an empty dir. Tags are parsed as semantic versions, so
+// v2.3.1-rc.2 keeps its prerelease. A tag with four parts like v1.2.3.4 is
+// also taken, but its last part is replaced by the commit count. Without a
+// version tag the version is 0.0.0 with every commit counted as Build.
+func ReadGitVersion(dir string) (GitVersion, error) {
+	var gv GitVersion
+	out, err := runGit(dir, "describe", "--tags", "--long", "--always", "--dirty",
+		"--match", "v[0-9]*", "--match", "[0-9]*")
+	if err != nil {
+		return gv, err
+	}
+	if strings.HasSuffix(out, "-dirty") {
+		out, gv.Dirty = strings.TrimSuffix(out, "-dirty"), true
+	}
+
+	m := describePattern.FindStringSubmatch(out)
+	if m == nil {
+		// --always gives just the hash when no tag matches
+		gv.Hash = out
+		count, err := runGit(dir, "rev-list", "--count", "HEAD")
+		if err != nil {
+			return gv, err
+		}
+		if gv.Version.Build, err = strconv.Atoi(count); err != nil {
+			return gv, fmt.Errorf("git rev-list: unexpected commit count %q", count)
+		}
+		return gv, nil
+	}
+
+	gv.Tag, gv.Hash = m[1], m[3]
+	if sv, err := ParseSemVer(gv.Tag); err == nil {
+		gv.Version = FileVersion{Major: sv.Major, Minor: sv.Minor, Patch: sv.Patch}
+		gv.Prerelease = sv.Prerelease
+	} else if gv.Version, err = NewFileVersion(gv.Tag); err != nil {
+		return gv, fmt.Errorf("tag %s: %w", gv.Tag, err)
+	}
+	if gv.Version.Build, err = strconv.Atoi(m[2]); err != nil {
+		return gv, fmt.Errorf("tag %s: %w", gv.Tag, err)
+	}
+	return gv, nil
+}
+
+// SetGitVersion sets the file and product versions to the one of gv and
+// stores the hash of the commit as the GitCommit string. A prerelease tag
+// sets VS_FF_PRERELEASE, and a dirty working tree is marked as a private
+// build.
+func (vi *VersionInfo) SetGitVersion(gv GitVersion) {
+	vi.FixedFileInfo.FileVersion = gv.Version
+	vi.FixedFileInfo.ProductVersion = gv.Version
+	vi.StringFileInfo.FileVersion = gv.Version.GetVersionString()
+	vi.StringFileInfo.ProductVersion = gv.Version.GetVersionString()
+	vi.StringFileInfo.Custom.Set("GitCommit", gv.Hash)
+
+	if gv.Prerelease != "" {
+		vi.FixedFileInfo.setFileFlag("VS_FF_PRERELEASE")
+	}
+	if gv.Dirty {
+		vi.FixedFileInfo.setFileFlag("VS_FF_PRIVATEBUILD")
+		if vi.StringFileInfo.PrivateBuild == "" {
+			vi.StringFileInfo.PrivateBuild = "Built from " + gv.Hash + " with uncommitted changes"
+		}
+	}
+}
+
+// setFileFlag adds the named flag to FileFlags, and to FileFlagsMask when
+// the mask does not cover it. Values that can not be parsed are kept, so
+// Validate still reports them.
+func (ff *FixedFileInfo) setFileFlag(name string) {
+	bit, _ := symbolValue(fileFlagSymbols, name)
+	values, _ := ff.values()
+	if values.FileFlags&bit == 0 {
+		ff.FileFlags = joinSymbols(ff.FileFlags, name)
+	}
+	if values.FileFlagsMask&bit == 0 {
+		ff.FileFlagsMask = joinSymbols(ff.FileFlagsMask, name)
+	}
+}
+
+// joinSymbols adds name to the symbols of s.
+func joinSymbols(s, name string) string {
+	if strings.TrimSpace(s) == "" {
+		return name
+	}
+	return s + "|" + name
+}
+
+// runGit runs git in dir and returns its trimmed output. Errors carry what
+// git printed to stderr.
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,171 @@
+package goversioninfo
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newGitRepo creates a repository in a temporary directory and returns it
+// with a function that runs git in it. Commits are dated 2024-02-29.
+func newGitRepo(t *testing.T) (string, func(args ...string) string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := os.MkdirTemp("", "git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+			"GIT_AUTHOR_DATE=2024-02-29T12:30:00Z", "GIT_COMMITTER_DATE=2024-02-29T12:30:00Z")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+	git("init", "-q")
+	return dir, git
+}
+
+func TestReadGitVersion(t *testing.T) {
+	dir, git := newGitRepo(t)
+
+	_, err := ReadGitVersion(dir)
+	assert.Error(t, err, "a repository without commits has no version")
+
+	// Without a tag every commit counts.
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644))
+	git("add", "main.go")
+	git("commit", "-q", "-m", "first")
+	git("commit", "-q", "--allow-empty", "-m", "second")
+	gv, err := ReadGitVersion(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "", gv.Tag)
+	assert.Equal(t, FileVersion{0, 0, 0, 2}, gv.Version)
+	assert.Len(t, gv.Hash, 7)
+	assert.False(t, gv.Dirty)
+
+	// Tags that are not versions are skipped.
+	git("tag", "v1.2.3")
+	git("commit", "-q", "--allow-empty", "-m", "third")
+	git("tag", "release")
+	git("commit", "-q", "--allow-empty", "-m", "fourth")
+	gv, err = ReadGitVersion(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.2.3", gv.Tag)
+	assert.Equal(t, FileVersion{1, 2, 3, 2}, gv.Version)
+	assert.Equal(t, git("rev-parse", "--short", "HEAD"), gv.Hash+"\n")
+
+	git("tag", "2.0.0")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // changed\n"), 0644))
+	gv, err = ReadGitVersion(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0.0", gv.Tag)
+	assert.Equal(t, FileVersion{2, 0, 0, 0}, gv.Version)
+	assert.Equal(t, "", gv.Prerelease)
+	assert.True(t, gv.Dirty)
+
+	// Semantic versions keep their prerelease, four parts lose the last one.
+	git("commit", "-q", "-a", "-m", "fifth")
+	git("tag", "v2.3.1-rc.2")
+	gv, err = ReadGitVersion(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "v2.3.1-rc.2", gv.Tag)
+	assert.Equal(t, FileVersion{2, 3, 1, 0}, gv.Version)
+	assert.Equal(t, "rc.2", gv.Prerelease)
+
+	git("commit", "-q", "--allow-empty", "-m", "sixth")
+	git("tag", "v2.3.1.9")
+	git("commit", "-q", "--allow-empty", "-m", "seventh")
+	gv, err = ReadGitVersion(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, FileVersion{2, 3, 1, 1}, gv.Version)
+	assert.Equal(t, "", gv.Prerelease)
+}
+
+func TestSetGitVersion(t *testing.T) {
+	jsonBytes, err := os.ReadFile("testdata/json/cmd.json")
+	assert.NoError(t, err)
+
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON(jsonBytes))
+	vi.SetGitVersion(GitVersion{Tag: "v1.2.3", Version: FileVersion{1, 2, 3, 4}, Hash: "abc1234"})
+	assert.Empty(t, vi.Validate())
+	assert.Equal(t, FileVersion{1, 2, 3, 4}, vi.FixedFileInfo.FileVersion)
+	assert.Equal(t, FileVersion{1, 2, 3, 4}, vi.FixedFileInfo.ProductVersion)
+	assert.Equal(t, "1.2.3.4", vi.StringFileInfo.FileVersion)
+	assert.Equal(t, "1.2.3.4", vi.StringFileInfo.ProductVersion)
+	assert.Equal(t, CustomStrings{{"GitCommit", "abc1234"}}, vi.StringFileInfo.Custom)
+	assert.Equal(t, "00", vi.FixedFileInfo.FileFlags)
+	assert.Equal(t, "", vi.StringFileInfo.PrivateBuild)
+
+	vi.SetGitVersion(GitVersion{Tag: "v1.2.3", Version: FileVersion{1, 2, 3, 5}, Hash: "def5678", Dirty: true})
+	assert.Empty(t, vi.Validate())
+	assert.Equal(t, CustomStrings{{"GitCommit", "def5678"}}, vi.StringFileInfo.Custom)
+	assert.Equal(t, "00|VS_FF_PRIVATEBUILD", vi.FixedFileInfo.FileFlags)
+	assert.Equal(t, "3f", vi.FixedFileInfo.FileFlagsMask)
+	assert.Equal(t, "Built from def5678 with uncommitted changes", vi.StringFileInfo.PrivateBuild)
+
+	// Setting it again does not repeat the flag.
+	vi.SetGitVersion(GitVersion{Version: FileVersion{1, 2, 3, 5}, Hash: "def5678", Dirty: true})
+	assert.Equal(t, "00|VS_FF_PRIVATEBUILD", vi.FixedFileInfo.FileFlags)
+
+	// A mask that does not cover the flag gets it.
+	vi = &VersionInfo{}
+	vi.SetGitVersion(GitVersion{Hash: "def5678", Dirty: true})
+	assert.Equal(t, "VS_FF_PRIVATEBUILD", vi.FixedFileInfo.FileFlags)
+	assert.Equal(t, "VS_FF_PRIVATEBUILD", vi.FixedFileInfo.FileFlagsMask)
+
+	vi = &VersionInfo{}
+	vi.SetGitVersion(GitVersion{Tag: "v2.3.1-rc.2", Version: FileVersion{2, 3, 1, 0}, Prerelease: "rc.2", Hash: "abc1234"})
+	assert.Equal(t, "VS_FF_PRERELEASE", vi.FixedFileInfo.FileFlags)
+	assert.Equal(t, "VS_FF_PRERELEASE", vi.FixedFileInfo.FileFlagsMask)
+}
+
+func TestRunCLIVersionFromGit(t *testing.T) {
+	dir, git := newGitRepo(t)
+	git("commit", "-q", "--allow-empty", "-m", "first")
+	git("tag", "v3.1.0")
+	git("commit", "-q", "--allow-empty", "-m", "second")
+
+	config, err := filepath.Abs("testdata/json/cmd.json")
+	assert.NoError(t, err)
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	// The version flags still win over git.
+	cfg := NewCLIConfig()
+	cfg.ConfigFile = config
+	cfg.VersionFromGit = true
+	cfg.VerPatch = 7
+	cfg.FileDate = FileDateGit
+	assert.NoError(t, RunCLI(cfg))
+
+	res, err := ReadResources("resource.syso")
+	assert.NoError(t, err)
+	decoded, err := DecodeVersionInfo(res[0].Data)
+	assert.NoError(t, err)
+	assert.Equal(t, FileVersion{3, 1, 7, 1}, decoded.FixedFileInfo.FileVersion)
+	assert.Equal(t, FileVersion{3, 1, 0, 1}, decoded.FixedFileInfo.ProductVersion)
+	assert.Equal(t, "3.1.0.1", decoded.StringFileInfo.ProductVersion)
+	assert.Equal(t, "2024-02-29T12:30:00Z", decoded.FixedFileInfo.FileDate)
+	assert.Equal(t, CustomStrings{{"GitCommit", git("rev-parse", "--short", "HEAD")[:7]}}, decoded.StringFileInfo.Custom)
+}
@@ -7,10 +7,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
 	"unicode/utf16"
@@ -33,12 +31,25 @@ type VersionInfo struct {
 	FixedFileInfo       `json:"FixedFileInfo"`
 	StringFileInfo      `json:"StringFileInfo"`
 	VarFileInfo         `json:"VarFileInfo"`
-	Timestamp           bool
-	Buffer              bytes.Buffer
-	Structure           VSVersionInfo
-	IconPath            string `json:"IconPath"`
-	ManifestPath        string `json:"ManifestPath"`
-	ApplicationIconPath string `json:"ApplicationIconPath"`
+	StringTables        []StringTable    `json:"StringTables,omitempty"`
+	Timestamp           bool             // Deprecated: never set a date and is ignored, use FixedFileInfo.FileDate.
+	Buffer              bytes.Buffer     `json:"-"`
+	Structure           VSVersionInfo    `json:"-"`
+	IconPath            string           `json:"IconPath"`
+	ManifestPath        string           `json:"ManifestPath"`
+	Manifest            *Manifest        `json:"Manifest,omitempty"`
+	ApplicationIconPath string           `json:"ApplicationIconPath"`
+	Icons               []IconResource   `json:"Icons,omitempty"`
+	FileResources       []FileResource   `json:"Resources,omitempty"`
+	StringResources     []StringResource `json:"StringResources,omitempty"`
+	MessageTablePath    string           `json:"MessageTablePath,omitempty"`
+	ResPath             string           `json:"ResPath,omitempty"`
+	ResConflict         ConflictPolicy   `json:"ResConflict,omitempty"`
+
+	// Strict makes Build fail on the errors Validate finds, which it does
+	// not check otherwise, WarningsAsErrors on every issue.
+	Strict           bool `json:"-"`
+	WarningsAsErrors bool `json:"-"`
 }
 
 // Translation with langid and charsetid.
@@ -64,11 +75,20 @@ type FixedFileInfo struct {
 	FileOS         string
 	FileType       string
 	FileSubType    string
+
+	// FileDate is an RFC 3339 time, SOURCE_DATE_EPOCH or git. It is left
+	// zero when empty, as Windows itself does.
+	FileDate string `json:",omitempty"`
 }
 
+// Translations lists every language and code page pair the file supports.
+// The first one keys the default string table. VarFileInfo.Translation used to
+// be a single Translation, set its first entry instead.
+type Translations []Translation
+
 // VarFileInfo is the translation container.
 type VarFileInfo struct {
-	Translation `json:"Translation"`
+	Translation Translations `json:"Translation"`
 }
 
 // StringFileInfo is what you want to change.
@@ -85,12 +105,107 @@ type StringFileInfo struct {
 	ProductName      string
 	ProductVersion   string
 	SpecialBuild     string
+	Custom           CustomStrings `json:",omitempty"`
+}
+
+// StringPair is a single key and value of a string table.
+type StringPair struct {
+	Key   string
+	Value string
+}
+
+// CustomStrings holds extra string table keys, like BuildCommit, that are not
+// part of the standard set. They are written after the standard keys in the
+// order they are listed. In JSON they are an object whose key order is kept.
+type CustomStrings []StringPair
+
+// StringTable is an additional set of strings keyed by its own translation.
+// It is written after the table built from VersionInfo.StringFileInfo, which
+// allows a single file to carry localized strings for several languages.
+type StringTable struct {
+	Translation    `json:"Translation"`
+	StringFileInfo `json:"StringFileInfo"`
 }
 
 // *****************************************************************************
 // Helpers
 // *****************************************************************************
 
+// Set assigns value to the string named key. Standard keys like CompanyName
+// set the matching field, any other key is added to or updated in Custom.
+func (sfi *StringFileInfo) Set(key, value string) {
+	v := reflect.ValueOf(sfi).Elem()
+	if f, ok := v.Type().FieldByName(key); ok && f.Type.Kind() == reflect.String {
+		v.FieldByIndex(f.Index).SetString(value)
+		return
+	}
+	sfi.Custom.Set(key, value)
+}
+
+// Set updates the value of key, appending it when it is not present yet.
+func (cs *CustomStrings) Set(key, value string) {
+	for i := range *cs {
+		if (*cs)[i].Key == key {
+			(*cs)[i].Value = value
+			return
+		}
+	}
+	*cs = append(*cs, StringPair{Key: key, Value: value})
+}
+
+// UnmarshalJSON reads a JSON object into the list, keeping the key order.
+func (cs *CustomStrings) UnmarshalJSON(p []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return fmt.Errorf("custom strings must be a JSON object, got %v", tok)
+	}
+	list := CustomStrings{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var value string
+		if err := dec.Decode(&value); err != nil {
+			return fmt.Errorf("custom string %q: %w", tok, err)
+		}
+		list.Set(tok.(string), value)
+	}
+	*cs = list
+	return nil
+}
+
+// MarshalJSON writes the list as a JSON object in its current order.
+func (cs CustomStrings) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, pair := range cs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(pair.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(pair.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
 // SizedReader is a *bytes.Buffer.
 type SizedReader struct {
 	*bytes.Buffer
@@ -105,9 +220,9 @@ func str2Uint32(s string) uint32 {
 	if s == "" {
 		return 0
 	}
+	// Validate reports the values that do not parse
 	u, err := strconv.ParseUint(s, 16, 32)
 	if err != nil {
-		log.Printf("Error parsing %q as uint32: %v", s, err)
 		return 0
 	}
 
@@ -132,39 +247,12 @@ func padBytes(i int) []byte {
 	return make([]byte, i)
 }
 
-// NewFileVersion parses semver version string into a FileVersion object
+// NewFileVersion parses a version string into a FileVersion object. Semantic
+// versions like v1.2.3 or 1.2.3-rc.1 give Major, Minor and Patch, other
+// strings need to start with x.y.z or x.y.z.w, like 6.3.9600.16384 (winblue_rtm).
 func NewFileVersion(version string) (FileVersion, error) {
-	re := regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)(?:\.(\d+))?`)
-
-	comps := re.FindStringSubmatch(version)
-	if len(comps) == 0 {
-		return FileVersion{}, fmt.Errorf("version expected to start from x.y.z")
-	}
-
-	// First match group is a whole matched string.
-	comps = comps[1:]
-	if comps[3] == "" {
-		comps = comps[:3]
-	}
-
-	nums := make([]int, len(comps))
-	for i := range nums {
-		n, err := strconv.Atoi(comps[i])
-		if err != nil {
-			return FileVersion{}, fmt.Errorf("%s: %s", comps[i], err)
-		}
-		nums[i] = n
-	}
-
-	res := FileVersion{
-		Major: nums[0],
-		Minor: nums[1],
-		Patch: nums[2],
-	}
-	if len(nums) == 4 {
-		res.Build = nums[3]
-	}
-	return res, nil
+	v, _, err := parseVersionString(version)
+	return v, err
 }
 
 func (f FileVersion) getVersionHighString() string {
@@ -187,13 +275,24 @@ func (f FileVersion) GetVersionString() string {
 
 // fillVersions syncs version info between FixedFileInfo and StringFileInfo.
 // If one section has version data and the other doesn't, the missing section
-// is populated automatically. Warnings are logged when StringFileInfo version
-// strings cannot be parsed or when the two sections have conflicting values.
+// is populated automatically. Validate reports StringFileInfo version strings
+// that cannot be parsed and sections with conflicting values.
 func (vi *VersionInfo) fillVersions() {
 	vi.fillVersion("FileVersion",
 		&vi.FixedFileInfo.FileVersion, &vi.StringFileInfo.FileVersion)
 	vi.fillVersion("ProductVersion",
 		&vi.FixedFileInfo.ProductVersion, &vi.StringFileInfo.ProductVersion)
+
+	// Localized tables only inherit the version strings they leave empty.
+	for i := range vi.StringTables {
+		sfi := &vi.StringTables[i].StringFileInfo
+		if sfi.FileVersion == "" && !vi.FixedFileInfo.FileVersion.IsZero() {
+			sfi.FileVersion = vi.FixedFileInfo.FileVersion.GetVersionString()
+		}
+		if sfi.ProductVersion == "" && !vi.FixedFileInfo.ProductVersion.IsZero() {
+			sfi.ProductVersion = vi.FixedFileInfo.ProductVersion.GetVersionString()
+		}
+	}
 }
 
 func (vi *VersionInfo) fillVersion(name string, fixed *FileVersion, str *string) {
@@ -204,23 +303,49 @@ func (vi *VersionInfo) fillVersion(name string, fixed *FileVersion, str *string)
 	case !fixedZero && strEmpty:
 		*str = fixed.GetVersionString()
 	case fixedZero && !strEmpty:
-		v, err := NewFileVersion(*str)
-		if err != nil {
-			log.Printf("Warning: StringFileInfo.%s %q could not be parsed: %v", name, *str, err)
-			return
+		if v, _, err := parseVersionString(*str); err == nil {
+			*fixed = v
 		}
-		*fixed = v
-	case !fixedZero && !strEmpty:
-		v, err := NewFileVersion(*str)
-		if err != nil {
-			log.Printf("Warning: StringFileInfo.%s %q could not be parsed: %v", name, *str, err)
-			return
-		}
-		if *fixed != v {
-			log.Printf("Warning: FixedFileInfo.%s (%s) and StringFileInfo.%s (%s) do not match",
-				name, fixed.GetVersionString(), name, *str)
+	}
+}
+
+// UnmarshalJSON accepts either a single translation object or a list of them.
+func (ts *Translations) UnmarshalJSON(p []byte) error {
+	p = bytes.TrimSpace(p)
+	if len(p) == 0 || string(p) == "null" {
+		return nil
+	}
+	if p[0] != '[' {
+		var t Translation
+		if err := json.Unmarshal(p, &t); err != nil {
+			return err
 		}
+		*ts = Translations{t}
+		return nil
 	}
+	var list []Translation
+	if err := json.Unmarshal(p, &list); err != nil {
+		return err
+	}
+	*ts = list
+	return nil
+}
+
+// MarshalJSON writes a single translation as an object so the output stays
+// readable by older versions of this package.
+func (ts Translations) MarshalJSON() ([]byte, error) {
+	if len(ts) == 1 {
+		return json.Marshal(ts[0])
+	}
+	return json.Marshal([]Translation(ts))
+}
+
+// first returns the translation that keys the default string table.
+func (ts Translations) first() Translation {
+	if len(ts) == 0 {
+		return Translation{}
+	}
+	return ts[0]
 }
 
 func (t Translation) getTranslationString() string {
@@ -255,61 +380,163 @@ func (vi *VersionInfo) Walk() {
 // WriteSyso creates a resource file from the version info and optionally an icon.
 // arch must be an architecture string accepted by coff.Arch, like "386" or "amd64"
 func (vi *VersionInfo) WriteSyso(filename string, arch string) error {
+	res, err := vi.Resources()
+	if err != nil {
+		return err
+	}
+
+	// Write to file
+	return res.WriteSyso(filename, arch)
+}
+
+// WriteRes creates a Win32 .res file from the version info and optionally an
+// icon, for toolchains that link resources themselves.
+func (vi *VersionInfo) WriteRes(filename string) error {
+	res, err := vi.Resources()
+	if err != nil {
+		return err
+	}
+
+	// Write to file
+	return res.WriteRes(filename)
+}
 
+// Resources collects the version info, manifest, icon, file, string and
+// message table resources that WriteSyso embeds, followed by the ones of the
+// ResPath .res file. Two of these resources with the same type, ID and
+// language are an error. Resources of the .res file with the same type and ID
+// as these are handled according to ResConflict.
+func (vi *VersionInfo) Resources() (Resources, error) {
 	var i uint16
 	newID := func() uint16 {
 		i++
 		return i
 	}
 
-	// Create a new RSRC section
-	rsrc := coff.NewRSRC()
+	var res Resources
 
-	// Set the architecture
-	err := rsrc.Arch(arch)
-	if err != nil {
-		return err
+	// IDI_APPLICATION (32512) is the icon shown in the window title bar.
+	// Default to IconPath if not explicitly set.
+	appIcon := vi.ApplicationIconPath
+	if appIcon == "" {
+		appIcon = vi.IconPath
+	}
+
+	// Icon images must not take the IDs of the ones in the .res file, or its
+	// icon groups would show the wrong images. Only the images of the .res
+	// groups that Icons or the application icon replace are free, as Merge
+	// drops them. Numbered groups get their IDs from the same sequence, but
+	// skip the groups of Icons and of the .res file.
+	var resFile Resources
+	reservedImages, reservedGroups := map[uint16]bool{}, map[uint16]bool{}
+	groups := map[ResourceID]bool{}
+	for _, icon := range vi.Icons {
+		if icon.ID.Name == "" {
+			reservedGroups[icon.ID.ID] = true
+		}
+		groups[icon.ID.key()] = true
+	}
+	if appIcon != "" {
+		groups[ResourceID{ID: 32512}] = true
+	}
+	if vi.ResPath != "" {
+		var err error
+		if resFile, err = ReadRes(vi.ResPath); err != nil {
+			return nil, err
+		}
+		orphans := resFile.orphanIcons(func(group ResourceID) bool {
+			return vi.ResConflict != ConflictError && groups[group.key()]
+		})
+		for _, r := range resFile {
+			switch {
+			case r.Name.Name != "":
+			case r.Type == (ResourceID{ID: rtIcon}) && !orphans[r.Name.ID]:
+				reservedImages[r.Name.ID] = true
+			case r.Type == (ResourceID{ID: rtGroupIcon}):
+				reservedGroups[r.Name.ID] = true
+			}
+		}
+	}
+	skip := func(reserved map[uint16]bool) func() uint16 {
+		return func() uint16 {
+			id := newID()
+			for reserved[id] {
+				id = newID()
+			}
+			return id
+		}
 	}
+	newIconID, newGroupID := skip(reservedImages), skip(reservedGroups)
 
 	// ID 16 is for Version Information
-	rsrc.AddResource(16, 1, SizedReader{bytes.NewBuffer(vi.Buffer.Bytes())})
+	res.add(rtVersion, 1, vi.Buffer.Bytes())
 
 	// If manifest is enabled
-	if vi.ManifestPath != "" {
-
-		manifest, err := binutil.SizedOpen(vi.ManifestPath)
+	if vi.ManifestPath != "" || vi.Manifest != nil {
+		manifest, err := vi.manifest()
 		if err != nil {
-			return err
+			return nil, err
 		}
-		defer manifest.Close()
 
 		id := newID()
-		rsrc.AddResource(rtManifest, id, manifest)
+		res.add(rtManifest, id, manifest)
 	}
 
 	// If icon is enabled
 	if vi.IconPath != "" {
-		if err := addIcon(rsrc, vi.IconPath, newID); err != nil {
-			return err
+		if err := addIcon(&res, vi.IconPath, newIconID, newGroupID); err != nil {
+			return nil, err
 		}
 	}
 
-	// IDI_APPLICATION (32512) is the icon shown in the window title bar.
-	// Default to IconPath if not explicitly set.
-	appIcon := vi.ApplicationIconPath
-	if appIcon == "" {
-		appIcon = vi.IconPath
+	for _, icon := range vi.Icons {
+		if err := addOneIconWithGroupID(&res, icon.Path, newIconID, newGroupID, icon.ID, icon.lang()); err != nil {
+			return nil, fmt.Errorf("%s: %w", icon.Path, err)
+		}
 	}
+
 	if appIcon != "" {
-		if err := addIconWithGroupID(rsrc, appIcon, newID, 32512); err != nil {
-			return err
+		if err := addIconWithGroupID(&res, appIcon, newIconID, newGroupID, 32512); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := addFileResources(&res, vi.FileResources); err != nil {
+		return nil, err
+	}
+	if err := addStringResources(&res, vi.StringResources); err != nil {
+		return nil, err
+	}
+	if vi.MessageTablePath != "" {
+		mt, err := ReadMC(vi.MessageTablePath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", vi.MessageTablePath, err)
 		}
+		addMessageTable(&res, mt)
 	}
 
-	rsrc.Freeze()
+	if err := res.checkIDs(); err != nil {
+		return nil, err
+	}
 
-	// Write to file
-	return writeCoff(rsrc, filename)
+	if vi.ResPath != "" {
+		merged, err := res.Merge(resFile, vi.ResConflict)
+		if err != nil {
+			return nil, fmt.Errorf("merging %s: %w", vi.ResPath, err)
+		}
+		res = merged
+	}
+
+	return res, nil
+}
+
+// WriteJSON creates a JSON file from the version info that ParseJSON can read back.
+func (vi *VersionInfo) WriteJSON(filename string) error {
+	b, err := json.MarshalIndent(vi, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(b, '\n'), 0644)
 }
 
 // WriteHex creates a hex file for debugging version info
@@ -354,6 +581,15 @@ func (vi *VersionInfo) WriteGo(filename, packageName string) error {
 	str += ",\n\t"
 	str += `"VarFileInfo":`
 	str += strings.Replace(string(vfib), "`", replace, -1)
+	if len(vi.StringTables) > 0 {
+		stb, err := json.MarshalIndent(vi.StringTables, "\t", "\t")
+		if err != nil {
+			return err
+		}
+		str += ",\n\t"
+		str += `"StringTables":`
+		str += strings.Replace(string(stb), "`", replace, -1)
+	}
 	str += "\n"
 	str += "}`"
 	fmt.Fprintf(out, `// Auto-generated file by goversioninfo. Do not edit.
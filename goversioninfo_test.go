@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"debug/pe"
 	"encoding/binary"
+	"encoding/json"
 	"io"
 	"log"
 	"os"
@@ -601,11 +602,15 @@ func TestNewFileVersion(t *testing.T) {
 		{"1.2.3.4-RC.1", FileVersion{1, 2, 3, 4}, ""},
 		{"1.2.3.4 (final)", FileVersion{1, 2, 3, 4}, ""},
 		{"6.3.9600.17284 (aaa.140822-1915)", FileVersion{6, 3, 9600, 17284}, ""},
+		{"v1.2.3", FileVersion{1, 2, 3, 0}, ""},
+		{"1.2.3-rc.1", FileVersion{1, 2, 3, 0}, ""},
+		{"v1.2.3-rc.1+sha.abc", FileVersion{1, 2, 3, 0}, ""},
+		{"v1.2.3.4", FileVersion{1, 2, 3, 4}, ""},
 
 		// Unexpected format.
 		{"1.2", FileVersion{}, "version expected to start from x.y.z"},
 		{"1.3.a", FileVersion{}, "version expected to start from x.y.z"},
-		{"v1.2.3", FileVersion{}, "version expected to start from x.y.z"},
+		{"version 1.2.3", FileVersion{}, "version expected to start from x.y.z"},
 
 		// Any way to check Atoi errors except of overflow?
 		{"1.1.1.9223372036854775808", FileVersion{}, "9223372036854775808"},
@@ -662,34 +667,29 @@ func TestFillVersions_InvalidStringVersion(t *testing.T) {
 
 	vi := &VersionInfo{}
 	vi.StringFileInfo.FileVersion = "not-a-version"
+	if issues := vi.Validate(); assert.Len(t, issues, 1) {
+		assert.Contains(t, issues[0].Message, "could not be parsed")
+	}
 	vi.Build()
 	assert.Equal(t, FileVersion{0, 0, 0, 0}, vi.FixedFileInfo.FileVersion)
 	assert.Equal(t, "not-a-version", vi.StringFileInfo.FileVersion)
-	assert.Contains(t, buf.String(), "could not be parsed")
+	assert.Empty(t, buf.String())
 }
 
 func TestFillVersions_Conflict(t *testing.T) {
-	var buf bytes.Buffer
-	log.SetOutput(&buf)
-	defer log.SetOutput(os.Stderr)
-
 	vi := &VersionInfo{}
 	vi.FixedFileInfo.FileVersion = FileVersion{2, 0, 0, 0}
 	vi.StringFileInfo.FileVersion = "3.0.0.0"
-	vi.Build()
-	assert.Contains(t, buf.String(), "do not match")
+	if issues := vi.Validate(); assert.Len(t, issues, 1) {
+		assert.Contains(t, issues[0].Message, "do not match")
+	}
 }
 
 func TestFillVersions_MatchingVersionsNoWarning(t *testing.T) {
-	var buf bytes.Buffer
-	log.SetOutput(&buf)
-	defer log.SetOutput(os.Stderr)
-
 	vi := &VersionInfo{}
 	vi.FixedFileInfo.FileVersion = FileVersion{6, 3, 9600, 16384}
 	vi.StringFileInfo.FileVersion = "6.3.9600.16384 (winblue_rtm.130821-1623)"
-	vi.Build()
-	assert.Empty(t, buf.String())
+	assert.Empty(t, vi.Validate())
 }
 
 func TestPadStringEmoji(t *testing.T) {
@@ -723,8 +723,7 @@ func TestCopyrightSymbolCharset(t *testing.T) {
 	build := func(charset CharsetID) []byte {
 		vi := &VersionInfo{}
 		vi.StringFileInfo.LegalCopyright = copyright
-		vi.VarFileInfo.Translation.LangID = LangID(0x0409)
-		vi.VarFileInfo.Translation.CharsetID = charset
+		vi.VarFileInfo.Translation = Translations{{LangID: LangID(0x0409), CharsetID: charset}}
 		vi.Build()
 		vi.Walk()
 		return vi.Buffer.Bytes()
@@ -754,6 +753,162 @@ func TestCopyrightSymbolCharset(t *testing.T) {
 		"wrong translation declares 7-bit ASCII (0000) — Windows will show '?' for non-ASCII characters")
 }
 
+func TestStringTables(t *testing.T) {
+	jsonBytes := []byte(`{
+		"StringFileInfo": {
+			"CompanyName": "Company, Inc.",
+			"ProductName": "Product"
+		},
+		"VarFileInfo": {
+			"Translation": {"LangID": "0409", "CharsetID": "04B0"}
+		},
+		"StringTables": [{
+			"Translation": {"LangID": "0407", "CharsetID": "04B0"},
+			"StringFileInfo": {
+				"CompanyName": "Firma GmbH",
+				"ProductName": "Produkt"
+			}
+		}]
+	}`)
+
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON(jsonBytes))
+	assert.Len(t, vi.StringTables, 1)
+	assert.Equal(t, LngGerman, vi.StringTables[0].LangID)
+	assert.Equal(t, "Firma GmbH", vi.StringTables[0].StringFileInfo.CompanyName)
+
+	vi.FixedFileInfo.FileVersion = FileVersion{1, 2, 3, 4}
+	vi.Build()
+	vi.Walk()
+
+	// The localized table inherits the version string it left empty.
+	assert.Equal(t, "1.2.3.4", vi.StringTables[0].StringFileInfo.FileVersion)
+
+	sfi := vi.Structure.Children
+	if assert.Len(t, sfi.Children, 2) {
+		assert.Equal(t, padString("040904B0", 0), sfi.Children[0].SzKey)
+		assert.Equal(t, padString("040704B0", 0), sfi.Children[1].SzKey)
+	}
+
+	// Every length must add up to what was actually written.
+	got := uint16(6 + len(sfi.SzKey) + len(sfi.Padding))
+	for _, st := range sfi.Children {
+		assert.Zero(t, st.WLength%4, "string table must keep 32-bit alignment")
+		got += st.WLength
+	}
+	assert.Equal(t, sfi.WLength, got)
+	assert.Equal(t, int(vi.Structure.WLength), vi.Buffer.Len())
+	assert.Contains(t, string(vi.Buffer.Bytes()), string(padString("Firma GmbH", 0)))
+}
+
+func TestTranslations(t *testing.T) {
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON([]byte(`{
+		"VarFileInfo": {
+			"Translation": [
+				{"LangID": "0409", "CharsetID": "04B0"},
+				{"LangID": "0407", "CharsetID": "04E4"}
+			]
+		}
+	}`)))
+	assert.Equal(t, Translations{
+		{LangID: LngUSEnglish, CharsetID: CsUnicode},
+		{LangID: LngGerman, CharsetID: CsMultilingual},
+	}, vi.VarFileInfo.Translation)
+
+	vi.Build()
+	vi.Walk()
+
+	v := vi.Structure.Children2.Value
+	assert.Equal(t, []uint32{0x04B00409, 0x04E40407}, v.Value)
+	assert.Equal(t, uint16(8), v.WValueLength)
+	assert.Equal(t, int(vi.Structure.WLength), vi.Buffer.Len())
+
+	// The default string table is keyed by the first translation.
+	assert.Equal(t, padString("040904B0", 0), vi.Structure.Children.Children[0].SzKey)
+
+	// A single translation keeps the object form in both directions.
+	single := Translations{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"LangID": "0409", "CharsetID": "04B0"}`), &single))
+	assert.Len(t, single, 1)
+	b, err := json.Marshal(single)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"LangID":1033,"CharsetID":1200}`, string(b))
+
+	b, err = json.Marshal(vi.VarFileInfo.Translation)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"LangID":1033,"CharsetID":1200},{"LangID":1031,"CharsetID":1252}]`, string(b))
+}
+
+func TestCustomStrings(t *testing.T) {
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON([]byte(`{
+		"StringFileInfo": {
+			"CompanyName": "Company, Inc.",
+			"Custom": {
+				"BuildHost": "ci-01",
+				"Assembly Version": "1.0.0.0",
+				"BuildCommit": "abc1234"
+			}
+		}
+	}`)))
+	assert.Equal(t, CustomStrings{
+		{"BuildHost", "ci-01"},
+		{"Assembly Version", "1.0.0.0"},
+		{"BuildCommit", "abc1234"},
+	}, vi.StringFileInfo.Custom)
+
+	// Standard keys go to their field, anything else is custom.
+	vi.StringFileInfo.Set("ProductName", "Product")
+	vi.StringFileInfo.Set("BuildHost", "ci-02")
+	vi.StringFileInfo.Set("Branch", "main")
+	assert.Equal(t, "Product", vi.StringFileInfo.ProductName)
+	assert.Equal(t, CustomStrings{
+		{"BuildHost", "ci-02"},
+		{"Assembly Version", "1.0.0.0"},
+		{"BuildCommit", "abc1234"},
+		{"Branch", "main"},
+	}, vi.StringFileInfo.Custom)
+
+	vi.Build()
+	vi.Walk()
+
+	var keys []string
+	for _, s := range vi.Structure.Children.Children[0].Children {
+		keys = append(keys, string(s.SzKey))
+	}
+	var want []string
+	for _, k := range []string{"CompanyName", "ProductName", "BuildHost", "Assembly Version", "BuildCommit", "Branch"} {
+		want = append(want, string(padString(k, 0)))
+	}
+	assert.Equal(t, want, keys)
+	assert.Equal(t, int(vi.Structure.WLength), vi.Buffer.Len())
+
+	tmpdir, err := os.MkdirTemp("", "generate_go")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+	path := filepath.Join(tmpdir, "custom.go")
+	assert.NoError(t, vi.WriteGo(path, ""))
+	gen, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(gen), `"Custom": {
+			"BuildHost": "ci-02",
+			"Assembly Version": "1.0.0.0",
+			"BuildCommit": "abc1234",
+			"Branch": "main"
+		}`)
+
+	// The generated JSON must read back into the same order.
+	b, err := json.Marshal(vi.StringFileInfo)
+	assert.NoError(t, err)
+	back := StringFileInfo{}
+	assert.NoError(t, json.Unmarshal(b, &back))
+	assert.Equal(t, vi.StringFileInfo.Custom, back.Custom)
+
+	assert.Error(t, json.Unmarshal([]byte(`["BuildHost"]`), &back.Custom))
+	assert.Error(t, json.Unmarshal([]byte(`{"BuildHost": 1}`), &back.Custom))
+}
+
 type badWriter struct {
 	writeErr, closeErr error
 }
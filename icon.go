@@ -42,6 +42,7 @@ THE SOFTWARE.
 const (
 	rtIcon      = coff.RT_ICON
 	rtGroupIcon = coff.RT_GROUP_ICON
+	rtVersion   = 16
 	rtManifest  = coff.RT_MANIFEST
 )
 
@@ -51,22 +52,18 @@ type gRPICONDIR struct {
 	Entries []gRPICONDIRENTRY
 }
 
-func (group gRPICONDIR) Size() int64 {
-	return int64(binary.Size(group.ICONDIR) + len(group.Entries)*binary.Size(group.Entries[0]))
-}
-
 type gRPICONDIRENTRY struct {
 	ico.IconDirEntryCommon
 	ID uint16
 }
 
-func addIcon(coff *coff.Coff, fnames string, newID func() uint16) error {
+func addIcon(res *Resources, fnames string, newID, newGroupID func() uint16) error {
 	for {
 		var fname1 string
 		var ok bool
 		fname1, fnames, ok = strings.Cut(fnames, ",")
 		if fname1 != "" {
-			if err := addOneIcon(coff, fname1, newID); err != nil {
+			if err := addOneIcon(res, fname1, newID, newGroupID); err != nil {
 				return fmt.Errorf("%s: %w", fname1, err)
 			}
 		}
@@ -76,57 +73,103 @@ func addIcon(coff *coff.Coff, fnames string, newID func() uint16) error {
 	}
 }
 
-func addOneIcon(coff *coff.Coff, fname string, newID func() uint16) error {
-	return addOneIconWithGroupID(coff, fname, newID, 0)
+// IconResource is an icon group with a fixed ID or name, for programs that
+// load it with LoadIcon(hInst, MAKEINTRESOURCE(101)) or LoadIcon(hInst,
+// L"TRAYICON").
+type IconResource struct {
+	// Path is an .ico file, a .png file or a directory of .png files, like
+	// the entries of IconPath.
+	Path string
+
+	// ID is the number or the name of the RT_GROUP_ICON resource. In JSON it
+	// is a number or a string, where "#101" stands for the number 101.
+	ID ResourceID
+
+	// LangID is the language of the group and its images, U.S. English when
+	// it is not set.
+	LangID *LangID `json:",omitempty"`
 }
 
-func addIconWithGroupID(coff *coff.Coff, fname string, newID func() uint16, groupID uint16) error {
-	return addOneIconWithGroupID(coff, fname, newID, groupID)
+// lang returns the language the icon is stored in.
+func (ir IconResource) lang() LangID {
+	if ir.LangID != nil {
+		return *ir.LangID
+	}
+	return LngUSEnglish
 }
 
-func addOneIconWithGroupID(coff *coff.Coff, fname string, newID func() uint16, groupID uint16) error {
-	f, err := os.Open(fname)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
+func addOneIcon(res *Resources, fname string, newID, newGroupID func() uint16) error {
+	return addOneIconWithGroupID(res, fname, newID, newGroupID, ResourceID{}, LngUSEnglish)
+}
 
-	icons, err := ico.DecodeHeaders(f)
+func addIconWithGroupID(res *Resources, fname string, newID, newGroupID func() uint16, groupID uint16) error {
+	return addOneIconWithGroupID(res, fname, newID, newGroupID, ResourceID{ID: groupID}, LngUSEnglish)
+}
+
+// addOneIconWithGroupID adds the images of an icon, numbered by newID, and
+// the group that lists them. The group takes the ID of newGroupID when
+// groupID is zero.
+func addOneIconWithGroupID(res *Resources, fname string, newID, newGroupID func() uint16, groupID ResourceID, lang LangID) error {
+	images, err := readIcon(fname)
 	if err != nil {
 		return err
 	}
 
-	if len(icons) > 0 {
+	if len(images) > 0 {
 		// RT_ICONs
 		group := gRPICONDIR{ICONDIR: ico.ICONDIR{
 			Reserved: 0, // magic num.
 			Type:     1, // magic num.
-			Count:    uint16(len(icons)),
+			Count:    uint16(len(images)),
 		}}
 		gid := groupID
-		if gid == 0 {
-			gid = newID()
+		if gid == (ResourceID{}) {
+			gid = ResourceID{ID: newGroupID()}
 		}
-		for _, icon := range icons {
+		for _, img := range images {
 			id := newID()
-			buff, err := bufferIcon(f, int64(icon.ImageOffset), int(icon.BytesInRes))
-			if err != nil {
-				return err
-			}
-			coff.AddResource(rtIcon, id, buff)
-			group.Entries = append(group.Entries, gRPICONDIRENTRY{IconDirEntryCommon: icon.IconDirEntryCommon, ID: id})
+			*res = append(*res, Resource{Type: ResourceID{ID: rtIcon}, Name: ResourceID{ID: id}, LangID: lang, Data: img.Data})
+			group.Entries = append(group.Entries, gRPICONDIRENTRY{IconDirEntryCommon: img.IconDirEntryCommon, ID: id})
 		}
-		coff.AddResource(rtGroupIcon, gid, group)
+		var b bytes.Buffer
+		if err := binary.Write(&b, binary.LittleEndian, group.ICONDIR); err != nil {
+			return err
+		}
+		if err := binary.Write(&b, binary.LittleEndian, group.Entries); err != nil {
+			return err
+		}
+		*res = append(*res, Resource{Type: ResourceID{ID: rtGroupIcon}, Name: gid, LangID: lang, Data: b.Bytes()})
 	}
 
 	return nil
 }
 
-func bufferIcon(f *os.File, offset int64, size int) (*bytes.Reader, error) {
-	data := make([]byte, size)
-	_, err := f.ReadAt(data, offset)
+// readIcon returns the images of an .ico file, or the ones made from PNG
+// sources. Only images that lie outside the file are rejected, the rest of
+// what LintIcon finds is left to Validate.
+func readIcon(fname string) ([]iconImage, error) {
+	if isPNGIcon(fname) {
+		return readPNGIcon(fname)
+	}
+
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	icons, err := ico.DecodeHeaders(bytes.NewReader(b))
 	if err != nil {
 		return nil, err
 	}
-	return bytes.NewReader(data), nil
+
+	images := make([]iconImage, len(icons))
+	for i, icon := range icons {
+		end := uint64(icon.ImageOffset) + uint64(icon.BytesInRes)
+		if end > uint64(len(b)) {
+			return nil, fmt.Errorf("image %d at %#x with %d bytes runs past the end of the %d byte file",
+				i, icon.ImageOffset, icon.BytesInRes, len(b))
+		}
+		data := b[icon.ImageOffset:end]
+		images[i] = iconImage{IconDirEntryCommon: icon.IconDirEntryCommon, Data: data}
+	}
+	return images, nil
 }
@@ -1,10 +1,13 @@
 package goversioninfo
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestIconReleaseFileHandle(t *testing.T) {
@@ -36,3 +39,103 @@ func TestIconReleaseFileHandle(t *testing.T) {
 		t.Errorf("Error restoring icon: %v", err)
 	}
 }
+
+func TestIconResources(t *testing.T) {
+	german := LngGerman
+	vi := &VersionInfo{
+		IconPath: "testdata/resource/icon.ico",
+		Icons: []IconResource{
+			{Path: "testdata/resource/icon.ico", ID: ResourceID{ID: 1}},
+			{Path: "testdata/resource/icon.ico", ID: ResourceID{Name: "TrayIcon"}, LangID: &german},
+		},
+	}
+	assert.Empty(t, vi.Validate())
+	res, err := vi.Resources()
+	assert.NoError(t, err)
+
+	groups := map[ResourceID]LangID{}
+	var germanImages int
+	for _, r := range res {
+		switch r.Type {
+		case ResourceID{ID: rtGroupIcon}:
+			groups[r.Name] = r.LangID
+		case ResourceID{ID: rtIcon}:
+			if r.LangID == LngGerman {
+				germanImages++
+			}
+		}
+	}
+	assert.Len(t, groups, 4)
+	assert.Equal(t, LngUSEnglish, groups[ResourceID{ID: 1}])
+	assert.Equal(t, LngGerman, groups[ResourceID{Name: "TrayIcon"}])
+	assert.Equal(t, LngUSEnglish, groups[ResourceID{ID: 32512}])
+	assert.Equal(t, 6, germanImages)
+
+	// The group of IconPath skips the ID of Icons[0]
+	_, ok := groups[ResourceID{ID: 2}]
+	assert.True(t, ok)
+
+	// Ties are reported by Validate and fail the build
+	vi.Icons = append(vi.Icons,
+		IconResource{Path: "testdata/resource/icon.ico", ID: ResourceID{ID: 32512}},
+		IconResource{Path: "testdata/resource/icon.ico", ID: ResourceID{Name: "trayicon"}, LangID: &german},
+		IconResource{Path: ""},
+	)
+	assert.Equal(t, []Issue{
+		{SeverityError, "Icons[2].ID", "32512 is already used by the application icon"},
+		{SeverityError, "Icons[3].ID", "trayicon is already used by Icons[1]"},
+		{SeverityError, "Icons[4].Path", "is empty"},
+		{SeverityError, "Icons[4].ID", "needs a number above 0 or a name"},
+	}, vi.Validate())
+
+	vi.Icons = vi.Icons[:3]
+	_, err = vi.Resources()
+	assert.EqualError(t, err, "RT_GROUP_ICON 32512 language 0409 is defined twice")
+}
+
+func TestIconResourcesJSON(t *testing.T) {
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON([]byte(`{"Icons": [
+		{"Path": "app.ico", "ID": 101},
+		{"Path": "tray.ico", "ID": "TRAYICON", "LangID": "0407"},
+		{"Path": "other.ico", "ID": "#7"}
+	]}`)))
+	german := LngGerman
+	assert.Equal(t, []IconResource{
+		{Path: "app.ico", ID: ResourceID{ID: 101}},
+		{Path: "tray.ico", ID: ResourceID{Name: "TRAYICON"}, LangID: &german},
+		{Path: "other.ico", ID: ResourceID{ID: 7}},
+	}, vi.Icons)
+
+	b, err := json.Marshal(vi.Icons)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"Path":"app.ico","ID":101},{"Path":"tray.ico","ID":"TRAYICON","LangID":1031},{"Path":"other.ico","ID":7}]`, string(b))
+
+	assert.EqualError(t, vi.ParseJSON([]byte(`{"Icons": [{"ID": "#70000"}]}`)), `resource ID "#70000" is not a 16-bit number`)
+}
+
+func TestIconResourcesRC(t *testing.T) {
+	german := LngGerman
+	vi := &VersionInfo{Icons: []IconResource{
+		{Path: "app.ico", ID: ResourceID{ID: 101}},
+		{Path: "tray.ico", ID: ResourceID{Name: "TRAYICON"}, LangID: &german},
+	}}
+	b, err := vi.rc()
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "\n101 ICON \"app.ico\"\n\nLANGUAGE 0x07, 0x01\n\"TRAYICON\" ICON \"tray.ico\"\nLANGUAGE 0x09, 0x01\n")
+
+	// Reading the script back keeps the IDs, names and languages.
+	parsed := &VersionInfo{}
+	assert.NoError(t, parsed.ParseRC(b))
+	assert.Equal(t, "", parsed.IconPath)
+	assert.Equal(t, vi.Icons, parsed.Icons)
+
+	parsed = &VersionInfo{}
+	assert.NoError(t, parsed.ParseRC([]byte("1 VERSIONINFO\nBEGIN\nEND\n"+
+		"101 ICON \"a.ico\"\nTRAYICON ICON \"b.ico\"\nLANGUAGE 0x07,0x01\n\"X\" ICON \"c.ico\"\n")))
+	assert.Equal(t, []IconResource{
+		{Path: "a.ico", ID: ResourceID{ID: 101}},
+		{Path: "b.ico", ID: ResourceID{Name: "TRAYICON"}},
+		{Path: "c.ico", ID: ResourceID{Name: "X"}, LangID: &german},
+	}, parsed.Icons)
+}
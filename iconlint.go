@@ -0,0 +1,245 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/akavel/rsrc/ico"
+)
+
+// *****************************************************************************
+// Icon Linting
+// *****************************************************************************
+
+// standardIconSizes are the sizes Windows picks from for the shell and the
+// title bar at 100% scaling.
+var standardIconSizes = []int{16, 32, 48, 256}
+
+// pngSignature starts every PNG file.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// IconReport is what LintIcon found in an .ico file.
+type IconReport struct {
+	Images []IconImageInfo
+
+	// Issues have the file name as Path. Errors mean Windows can not show an
+	// image, warnings that it may show a scaled or wrong one.
+	Issues []Issue
+}
+
+// IconImageInfo describes one image of an .ico file.
+type IconImageInfo struct {
+	// Width and Height are in pixels, 256 for the 0 of the directory entry.
+	Width  int
+	Height int
+
+	// BitCount is the color depth of the image data in bits per pixel.
+	BitCount int
+
+	// PNG is set for PNG compressed images, which are bitmaps otherwise.
+	PNG bool
+
+	Offset uint32
+	Size   uint32
+}
+
+// LintIcon checks that the images of an .ico file lie within the file and
+// can be decoded, and reports missing standard sizes and duplicate images.
+// The error is only set when the file can not be read.
+func LintIcon(filename string) (*IconReport, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return lintIcon(filename, b), nil
+}
+
+// lintIcon checks the icon b read from filename.
+func lintIcon(filename string, b []byte) *IconReport {
+	r := &IconReport{}
+	add := func(severity Severity, format string, a ...interface{}) {
+		r.Issues = append(r.Issues, Issue{severity, filename, fmt.Sprintf(format, a...)})
+	}
+
+	var dir ico.ICONDIR
+	if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, &dir); err != nil {
+		add(SeverityError, "the %d byte file is too short for an icon header", len(b))
+		return r
+	}
+	if dir.Reserved != 0 || dir.Type != 1 {
+		add(SeverityError, "is not an icon, the header has type %d", dir.Type)
+		return r
+	}
+	if dir.Count == 0 {
+		add(SeverityError, "has no images")
+		return r
+	}
+
+	entrySize := binary.Size(ico.ICONDIRENTRY{})
+	dirEnd := binary.Size(dir) + int(dir.Count)*entrySize
+	if dirEnd > len(b) {
+		add(SeverityError, "the directory of %d images runs past the end of the %d byte file", dir.Count, len(b))
+		return r
+	}
+	entries := make([]ico.ICONDIRENTRY, dir.Count)
+	binary.Read(bytes.NewReader(b[binary.Size(dir):]), binary.LittleEndian, entries)
+
+	for i, e := range entries {
+		info := IconImageInfo{
+			Width:    iconDimension(e.Width),
+			Height:   iconDimension(e.Height),
+			BitCount: int(e.BitCount),
+			Offset:   e.ImageOffset,
+			Size:     e.BytesInRes,
+		}
+
+		end := uint64(e.ImageOffset) + uint64(e.BytesInRes)
+		switch {
+		case e.BytesInRes == 0:
+			add(SeverityError, "image %d is empty", i)
+		case int(e.ImageOffset) < dirEnd:
+			add(SeverityError, "image %d at %#x overlaps the directory, which ends at %#x", i, e.ImageOffset, dirEnd)
+		case end > uint64(len(b)):
+			add(SeverityError, "image %d at %#x with %d bytes runs past the end of the %d byte file",
+				i, e.ImageOffset, e.BytesInRes, len(b))
+		default:
+			data := b[e.ImageOffset:end]
+			width, height, bitCount, isPNG, hasMask, err := iconImageFormat(data)
+			info.PNG = isPNG
+			if err != nil {
+				add(SeverityError, "image %d: %v", i, err)
+				break
+			}
+			info.BitCount = bitCount
+			if !hasMask {
+				add(SeverityWarning, "image %d has no AND mask after its bitmap", i)
+			}
+			if width != info.Width || height != info.Height {
+				add(SeverityWarning, "image %d is %dx%d, but its directory entry says %dx%d",
+					i, width, height, info.Width, info.Height)
+			}
+		}
+
+		for j, other := range r.Images {
+			if other.Width == info.Width && other.Height == info.Height && other.BitCount == info.BitCount {
+				add(SeverityWarning, "image %d has the same size and color depth as image %d", i, j)
+				break
+			}
+		}
+		r.Images = append(r.Images, info)
+	}
+
+	var missing []string
+	for _, size := range standardIconSizes {
+		found := false
+		for _, img := range r.Images {
+			found = found || (img.Width == size && img.Height == size)
+		}
+		if !found {
+			missing = append(missing, fmt.Sprintf("%dx%d", size, size))
+		}
+	}
+	if len(missing) > 0 {
+		add(SeverityWarning, "has no %s image, Windows scales another one", strings.Join(missing, ", "))
+	}
+
+	return r
+}
+
+// iconDimension returns the size a directory entry stands for.
+func iconDimension(b byte) int {
+	if b == 0 {
+		return 256
+	}
+	return int(b)
+}
+
+// iconImageFormat decodes the header of an icon image, which is a PNG file
+// or a DIB, and returns its size and color depth. hasMask tells if a DIB is
+// followed by the AND mask, which PNG images do without.
+func iconImageFormat(data []byte) (width, height, bitCount int, isPNG, hasMask bool, err error) {
+	if bytes.HasPrefix(data, pngSignature) {
+		cfg, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return 0, 0, 0, true, true, fmt.Errorf("PNG image: %v", err)
+		}
+		// IHDR holds the bit depth and color type after the size
+		channels := map[byte]int{0: 1, 2: 3, 3: 1, 4: 2, 6: 4}[data[25]]
+		return cfg.Width, cfg.Height, int(data[24]) * channels, true, true, nil
+	}
+
+	// A BITMAPCOREHEADER has 16-bit sizes and RGBTRIPLE colors, a
+	// BITMAPINFOHEADER or a later version 32-bit sizes and RGBQUAD colors.
+	var headerSize, w, hgt, colorSize, colors int
+	var h ico.BITMAPINFOHEADER
+	switch {
+	case len(data) >= 12 && binary.LittleEndian.Uint32(data) == 12:
+		headerSize, colorSize = 12, 3
+		w = int(binary.LittleEndian.Uint16(data[4:]))
+		hgt = int(binary.LittleEndian.Uint16(data[6:]))
+		h.BitCount = binary.LittleEndian.Uint16(data[10:])
+	case binary.Read(bytes.NewReader(data), binary.LittleEndian, &h) == nil && h.Size >= 40:
+		headerSize, colorSize = int(h.Size), 4
+		w, hgt = int(h.Width), int(h.Height)
+		colors = int(h.ClrUsed)
+	default:
+		return 0, 0, 0, false, false, fmt.Errorf("neither a PNG image nor a bitmap")
+	}
+	switch h.BitCount {
+	case 1, 4, 8, 16, 24, 32:
+	default:
+		return 0, 0, 0, false, false, fmt.Errorf("bitmap has an invalid color depth of %d bits", h.BitCount)
+	}
+
+	// The height covers the color bitmap and the AND mask
+	hgt /= 2
+	if h.BitCount > 8 {
+		colors = 0
+	} else if colors == 0 {
+		colors = 1 << h.BitCount
+	}
+	stride := func(bits int) int { return (w*bits + 31) / 32 * 4 }
+	need := headerSize + colorSize*colors + stride(int(h.BitCount))*hgt
+	if w <= 0 || hgt <= 0 || need > len(data) {
+		return 0, 0, 0, false, false, fmt.Errorf("%dx%d bitmap needs %d bytes, but the image has %d", w, hgt, need, len(data))
+	}
+	return w, hgt, int(h.BitCount), false, need+stride(1)*hgt <= len(data), nil
+}
+
+// HasErrors reports whether any issue is an error.
+func (r *IconReport) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes a table of the images followed by the issues.
+func (r *IconReport) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "IMAGE\tSIZE\tDEPTH\tFORMAT\tBYTES\t")
+	for i, img := range r.Images {
+		format := "BMP"
+		if img.PNG {
+			format = "PNG"
+		}
+		fmt.Fprintf(tw, "%d\t%dx%d\t%d-bit\t%s\t%d\t\n", i, img.Width, img.Height, img.BitCount, format, img.Size)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, issue := range r.Issues {
+		if _, err := fmt.Fprintln(w, issue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
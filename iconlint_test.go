@@ -0,0 +1,159 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/akavel/rsrc/ico"
+	"github.com/stretchr/testify/assert"
+)
+
+// testIconImage returns a transparent image of the given size.
+func testIconImage(t *testing.T, size int) iconImage {
+	t.Helper()
+	img, err := encodeIconImage(image.NewNRGBA(image.Rect(0, 0, size, size)))
+	assert.NoError(t, err)
+	return img
+}
+
+func TestLintIcon(t *testing.T) {
+	var images []iconImage
+	for _, size := range []int{16, 32, 48, 256} {
+		images = append(images, testIconImage(t, size))
+	}
+	r := lintIcon("app.ico", icoFile(images))
+	assert.Empty(t, r.Issues)
+	assert.False(t, r.HasErrors())
+	if assert.Len(t, r.Images, 4) {
+		assert.Equal(t, IconImageInfo{Width: 16, Height: 16, BitCount: 32, Offset: 6 + 16*4, Size: images[0].BytesInRes}, r.Images[0])
+		assert.Equal(t, 256, r.Images[3].Width)
+		assert.True(t, r.Images[3].PNG)
+		assert.Equal(t, 32, r.Images[3].BitCount)
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, r.Print(&out))
+	assert.Contains(t, out.String(), "3      256x256  32-bit  PNG")
+
+	// Warnings
+	mislabeled := testIconImage(t, 24)
+	mislabeled.Width, mislabeled.Height = 20, 20
+	r = lintIcon("app.ico", icoFile([]iconImage{testIconImage(t, 16), testIconImage(t, 16), mislabeled}))
+	assert.Equal(t, []Issue{
+		{SeverityWarning, "app.ico", "image 1 has the same size and color depth as image 0"},
+		{SeverityWarning, "app.ico", "image 2 is 24x24, but its directory entry says 20x20"},
+		{SeverityWarning, "app.ico", "has no 32x32, 48x48, 256x256 image, Windows scales another one"},
+	}, r.Issues)
+	assert.False(t, r.HasErrors())
+
+	// Errors in the images
+	notPNG := testIconImage(t, 16)
+	notPNG.Data = append(append([]byte(nil), pngSignature...), "not really"...)
+	notPNG.BytesInRes = uint32(len(notPNG.Data))
+	garbage := testIconImage(t, 32)
+	garbage.Data = []byte("garbage")
+	garbage.BytesInRes = uint32(len(garbage.Data))
+	short := testIconImage(t, 48)
+	short.Data = short.Data[:100]
+	short.BytesInRes = 100
+	b := icoFile([]iconImage{notPNG, garbage, short, testIconImage(t, 256)})
+	r = lintIcon("app.ico", b)
+	if assert.Len(t, r.Issues, 3) {
+		assert.Contains(t, r.Issues[0].Message, "image 0: PNG image: ")
+		assert.Equal(t, "image 1: neither a PNG image nor a bitmap", r.Issues[1].Message)
+		assert.Equal(t, "image 2: 48x48 bitmap needs 9256 bytes, but the image has 100", r.Issues[2].Message)
+	}
+	assert.True(t, r.HasErrors())
+	assert.True(t, r.Images[0].PNG)
+
+	// Errors in the directory
+	entry := func(b []byte, i int) []byte { return b[6+16*i:] }
+	b = icoFile([]iconImage{testIconImage(t, 16), testIconImage(t, 32), testIconImage(t, 48)})
+	binary.LittleEndian.PutUint32(entry(b, 0)[8:], 0)
+	binary.LittleEndian.PutUint32(entry(b, 1)[12:], 10)
+	binary.LittleEndian.PutUint32(entry(b, 2)[8:], uint32(len(b)))
+	r = lintIcon("app.ico", b)
+	assert.Equal(t, []Issue{
+		{SeverityError, "app.ico", "image 0 is empty"},
+		{SeverityError, "app.ico", "image 1 at 0xa overlaps the directory, which ends at 0x36"},
+		{SeverityError, "app.ico", "image 2 at 0x1546 with 15086 bytes runs past the end of the 15086 byte file"},
+		{SeverityWarning, "app.ico", "has no 256x256 image, Windows scales another one"},
+	}, r.Issues)
+
+	// Bitmaps with a BITMAPCOREHEADER or without the AND mask
+	core := make([]byte, 12+2*3+2*4*2)
+	binary.LittleEndian.PutUint32(core, 12)
+	binary.LittleEndian.PutUint16(core[4:], 16)
+	binary.LittleEndian.PutUint16(core[6:], 4)
+	binary.LittleEndian.PutUint16(core[8:], 1)
+	binary.LittleEndian.PutUint16(core[10:], 1)
+	noMask := make([]byte, 40+16*16*4)
+	binary.LittleEndian.PutUint32(noMask, 40)
+	binary.LittleEndian.PutUint32(noMask[4:], 16)
+	binary.LittleEndian.PutUint32(noMask[8:], 32)
+	binary.LittleEndian.PutUint16(noMask[14:], 32)
+	b = icoFile([]iconImage{
+		{IconDirEntryCommon: ico.IconDirEntryCommon{Width: 16, Height: 2, BitCount: 1, BytesInRes: uint32(len(core))}, Data: core},
+		{IconDirEntryCommon: ico.IconDirEntryCommon{Width: 16, Height: 16, BitCount: 32, BytesInRes: uint32(len(noMask))}, Data: noMask},
+	})
+	r = lintIcon("app.ico", b)
+	assert.Equal(t, []Issue{
+		{SeverityWarning, "app.ico", "image 1 has no AND mask after its bitmap"},
+		{SeverityWarning, "app.ico", "has no 32x32, 48x48, 256x256 image, Windows scales another one"},
+	}, r.Issues)
+	assert.Equal(t, 1, r.Images[0].BitCount)
+
+	tests := []struct {
+		data []byte
+		msg  string
+	}{
+		{[]byte{0, 0, 1}, "the 3 byte file is too short for an icon header"},
+		{[]byte{0, 0, 2, 0, 1, 0}, "is not an icon, the header has type 2"},
+		{[]byte{0, 0, 1, 0, 0, 0}, "has no images"},
+		{[]byte{0, 0, 1, 0, 2, 0, 16}, "the directory of 2 images runs past the end of the 7 byte file"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, []Issue{{SeverityError, "app.ico", tt.msg}}, lintIcon("app.ico", tt.data).Issues)
+	}
+}
+
+func TestValidateIcon(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "iconlint")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	name := filepath.Join(tmpdir, "broken.ico")
+	garbage := testIconImage(t, 16)
+	garbage.Data = []byte("garbage")
+	garbage.BytesInRes = uint32(len(garbage.Data))
+	assert.NoError(t, os.WriteFile(name, icoFile([]iconImage{garbage}), 0644))
+
+	vi := &VersionInfo{IconPath: "testdata/resource/icon.ico," + name}
+	assert.Equal(t, []Issue{
+		{SeverityError, "IconPath", name + ": image 0: neither a PNG image nor a bitmap"},
+		{SeverityWarning, "IconPath", name + ": has no 32x32, 48x48, 256x256 image, Windows scales another one"},
+	}, vi.Validate())
+
+	// The linter is advisory, only images outside the file fail the build.
+	vi.ApplicationIconPath = "testdata/resource/icon.ico"
+	_, err = vi.Resources()
+	assert.NoError(t, err)
+
+	b := icoFile([]iconImage{testIconImage(t, 16)})
+	assert.NoError(t, os.WriteFile(name, b[:len(b)-1], 0644))
+	_, err = vi.Resources()
+	assert.EqualError(t, err, name+fmt.Sprintf(": image 0 at 0x16 with %d bytes runs past the end of the %d byte file", len(b)-22, len(b)-1))
+
+	report, err := LintIcon("testdata/resource/icon.ico")
+	assert.NoError(t, err)
+	assert.Empty(t, report.Issues)
+	assert.Len(t, report.Images, 6)
+
+	_, err = LintIcon(filepath.Join(tmpdir, "missing.ico"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,267 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/akavel/rsrc/ico"
+)
+
+// *****************************************************************************
+// Icons from PNG Images
+// *****************************************************************************
+
+/*
+Icon image formats
+https://learn.microsoft.com/en-us/previous-versions/ms997538(v=msdn.10)
+
+Each image of an icon is either a PNG file or a DIB: a BITMAPINFOHEADER with
+twice the height, the XOR bitmap bottom-up and a 1-bit AND mask. Windows Vista
+and later read PNG images, which keeps the 256 pixel one small.
+*/
+
+// iconSizes are the sizes of the images made from PNG sources. A size above
+// the largest source is left out rather than scaled up.
+var iconSizes = []int{16, 24, 32, 48, 64, 256}
+
+// pngIconSize is the smallest size stored as a PNG image instead of a DIB.
+const pngIconSize = 256
+
+// iconImage is one image of an icon with its directory entry.
+type iconImage struct {
+	ico.IconDirEntryCommon
+	Data []byte
+}
+
+// isPNGIcon reports whether an IconPath entry is a .png file or a directory,
+// which holds the PNG sources of one icon.
+func isPNGIcon(fname string) bool {
+	if strings.EqualFold(filepath.Ext(fname), ".png") {
+		return true
+	}
+	fi, err := os.Stat(fname)
+	return err == nil && fi.IsDir()
+}
+
+// icoFile lays images out as an .ico file.
+func icoFile(images []iconImage) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, ico.ICONDIR{Type: 1, Count: uint16(len(images))})
+	offset := 6 + 16*len(images)
+	for _, img := range images {
+		binary.Write(&b, binary.LittleEndian, ico.ICONDIRENTRY{IconDirEntryCommon: img.IconDirEntryCommon, ImageOffset: uint32(offset)})
+		offset += len(img.Data)
+	}
+	for _, img := range images {
+		b.Write(img.Data)
+	}
+	return b.Bytes()
+}
+
+// readPNGIcon builds the images of an icon from a .png file or from every
+// .png file in a directory. Each size of iconSizes is taken from the source
+// of that size, or scaled down from the smallest larger one.
+func readPNGIcon(fname string) ([]iconImage, error) {
+	files := []string{fname}
+	if fi, err := os.Stat(fname); err != nil {
+		return nil, err
+	} else if fi.IsDir() {
+		entries, err := os.ReadDir(fname)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, e := range entries {
+			if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".png") {
+				files = append(files, filepath.Join(fname, e.Name()))
+			}
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("directory has no .png files")
+		}
+	}
+
+	var sources []image.Image
+	for _, file := range files {
+		img, err := decodePNG(file)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, img)
+	}
+	sort.SliceStable(sources, func(i, j int) bool { return sources[i].Bounds().Dx() < sources[j].Bounds().Dx() })
+
+	var images []iconImage
+	for _, size := range iconSizes {
+		var src image.Image
+		for _, s := range sources {
+			if s.Bounds().Dx() >= size {
+				src = s
+				break
+			}
+		}
+		if src == nil {
+			break
+		}
+		img, err := encodeIconImage(scaleImage(src, size))
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	if len(images) == 0 {
+		largest := sources[len(sources)-1].Bounds().Dx()
+		return nil, fmt.Errorf("the largest image is %dx%d, smaller than the %dx%d of the smallest icon",
+			largest, largest, iconSizes[0], iconSizes[0])
+	}
+	return images, nil
+}
+
+// decodePNG reads a square PNG image.
+func decodePNG(fname string) (image.Image, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fname, err)
+	}
+	if b := img.Bounds(); b.Dx() != b.Dy() {
+		return nil, fmt.Errorf("%s is %dx%d, icons must be square", fname, b.Dx(), b.Dy())
+	}
+	return img, nil
+}
+
+// scaleImage resizes src to size by size pixels. Each pixel of the result
+// averages the source pixels it covers, weighted by how much of each it
+// covers and by their alpha, so transparent pixels do not darken the edges.
+func scaleImage(src image.Image, size int) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	if b.Dx() == size {
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+		return dst
+	}
+
+	scale := float64(b.Dx()) / float64(size)
+	for y := 0; y < size; y++ {
+		y0, y1 := float64(y)*scale, float64(y+1)*scale
+		for x := 0; x < size; x++ {
+			x0, x1 := float64(x)*scale, float64(x+1)*scale
+
+			var r, g, bl, a, area float64
+			for sy := int(y0); float64(sy) < y1 && sy < b.Dy(); sy++ {
+				wy := overlap(y0, y1, sy)
+				for sx := int(x0); float64(sx) < x1 && sx < b.Dx(); sx++ {
+					w := wy * overlap(x0, x1, sx)
+					c := color.NRGBA64Model.Convert(src.At(b.Min.X+sx, b.Min.Y+sy)).(color.NRGBA64)
+					wa := w * float64(c.A)
+					r += wa * float64(c.R)
+					g += wa * float64(c.G)
+					bl += wa * float64(c.B)
+					a += wa
+					area += w
+				}
+			}
+
+			var c color.NRGBA
+			if a > 0 {
+				c = color.NRGBA{
+					R: uint8(r/a/257 + 0.5),
+					G: uint8(g/a/257 + 0.5),
+					B: uint8(bl/a/257 + 0.5),
+					A: uint8(a/area/257 + 0.5),
+				}
+			}
+			dst.SetNRGBA(x, y, c)
+		}
+	}
+	return dst
+}
+
+// overlap returns how much of the pixel at i lies between lo and hi.
+func overlap(lo, hi float64, i int) float64 {
+	start, end := float64(i), float64(i+1)
+	if lo > start {
+		start = lo
+	}
+	if hi < end {
+		end = hi
+	}
+	return end - start
+}
+
+// encodeIconImage stores an image as PNG from pngIconSize up and as a 32-bit
+// DIB below it.
+func encodeIconImage(img *image.NRGBA) (iconImage, error) {
+	size := img.Bounds().Dx()
+	var data []byte
+	if size >= pngIconSize {
+		var b bytes.Buffer
+		if err := png.Encode(&b, img); err != nil {
+			return iconImage{}, err
+		}
+		data = b.Bytes()
+	} else {
+		data = iconDIB(img)
+	}
+
+	// A width and height of 0 stand for 256
+	return iconImage{
+		IconDirEntryCommon: ico.IconDirEntryCommon{
+			Width:      uint8(size),
+			Height:     uint8(size),
+			Planes:     1,
+			BitCount:   32,
+			BytesInRes: uint32(len(data)),
+		},
+		Data: data,
+	}, nil
+}
+
+// iconDIB returns the DIB of a 32-bit icon image with its AND mask, which
+// marks the fully transparent pixels.
+func iconDIB(img *image.NRGBA) []byte {
+	size := img.Bounds().Dx()
+	maskStride := (size + 31) / 32 * 4
+	xorSize, maskSize := 4*size*size, maskStride*size
+
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, ico.BITMAPINFOHEADER{
+		Size:      40,
+		Width:     int32(size),
+		Height:    int32(2 * size),
+		Planes:    1,
+		BitCount:  32,
+		SizeImage: uint32(xorSize + maskSize),
+	})
+
+	// Rows go bottom-up, with the pixels as BGRA
+	pixels := make([]byte, xorSize)
+	mask := make([]byte, maskSize)
+	for y := 0; y < size; y++ {
+		row := size - 1 - y
+		for x := 0; x < size; x++ {
+			c := img.NRGBAAt(x, y)
+			copy(pixels[4*(row*size+x):], []byte{c.B, c.G, c.R, c.A})
+			if c.A == 0 {
+				mask[row*maskStride+x/8] |= 0x80 >> (x % 8)
+			}
+		}
+	}
+	b.Write(pixels)
+	b.Write(mask)
+	return b.Bytes()
+}
@@ -0,0 +1,203 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/akavel/rsrc/ico"
+	"github.com/stretchr/testify/assert"
+)
+
+// writePNG saves a w by h image filled with c, whose top left pixel is
+// transparent.
+func writePNG(t *testing.T, name string, w, h int, c color.NRGBA) {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	img.SetNRGBA(0, 0, color.NRGBA{})
+
+	var b bytes.Buffer
+	assert.NoError(t, png.Encode(&b, img))
+	assert.NoError(t, os.WriteFile(name, b.Bytes(), 0644))
+}
+
+func TestReadPNGIcon(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "iconpng")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	blue := color.NRGBA{B: 0xff, A: 0xff}
+	single := filepath.Join(tmpdir, "app.png")
+	writePNG(t, single, 512, 512, red)
+
+	images, err := readPNGIcon(single)
+	assert.NoError(t, err)
+	var sizes []int
+	for _, img := range images {
+		assert.Equal(t, img.Width, img.Height)
+		assert.Equal(t, uint16(32), img.BitCount)
+		assert.Equal(t, uint32(len(img.Data)), img.BytesInRes)
+		sizes = append(sizes, int(img.Width))
+	}
+	assert.Equal(t, []int{16, 24, 32, 48, 64, 0}, sizes)
+
+	// 256 is a PNG
+	decoded, err := png.Decode(bytes.NewReader(images[5].Data))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 256, 256), decoded.Bounds())
+
+	// The others are DIBs
+	var header ico.BITMAPINFOHEADER
+	assert.NoError(t, binary.Read(bytes.NewReader(images[0].Data), binary.LittleEndian, &header))
+	assert.Equal(t, int32(16), header.Width)
+	assert.Equal(t, int32(32), header.Height)
+	assert.Len(t, images[0].Data, 40+16*16*4+16*4)
+	topRow := 40 + 15*16*4
+	assert.Equal(t, []byte{0, 0, 0xff, 0xff}, images[0].Data[topRow+4:topRow+8], "BGRA")
+
+	// A directory picks the source of each size
+	dir := filepath.Join(tmpdir, "icon")
+	assert.NoError(t, os.Mkdir(dir, 0755))
+	writePNG(t, filepath.Join(dir, "icon-16.png"), 16, 16, red)
+	writePNG(t, filepath.Join(dir, "icon-64.png"), 64, 64, blue)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README.txt"), []byte("not an image"), 0644))
+	images, err = readPNGIcon(dir)
+	assert.NoError(t, err)
+	sizes = sizes[:0]
+	for _, img := range images {
+		sizes = append(sizes, int(img.Width))
+	}
+	assert.Equal(t, []int{16, 24, 32, 48, 64}, sizes)
+	assert.Equal(t, []byte{0, 0, 0xff, 0xff}, images[0].Data[40:44], "16 is red")
+	assert.Equal(t, []byte{0xff, 0, 0, 0xff}, images[1].Data[40:44], "24 is blue")
+
+	// The AND mask marks the transparent top left pixel, in the last row
+	mask := images[0].Data[40+16*16*4:]
+	assert.Equal(t, byte(0x80), mask[15*4])
+	assert.Equal(t, byte(0), mask[0])
+
+	notSquare := filepath.Join(tmpdir, "wide.png")
+	writePNG(t, notSquare, 32, 16, red)
+	_, err = readPNGIcon(notSquare)
+	assert.EqualError(t, err, notSquare+" is 32x16, icons must be square")
+
+	tiny := filepath.Join(tmpdir, "tiny.png")
+	writePNG(t, tiny, 8, 8, red)
+	_, err = readPNGIcon(tiny)
+	assert.EqualError(t, err, "the largest image is 8x8, smaller than the 16x16 of the smallest icon")
+
+	empty := filepath.Join(tmpdir, "empty")
+	assert.NoError(t, os.Mkdir(empty, 0755))
+	_, err = readPNGIcon(empty)
+	assert.EqualError(t, err, "directory has no .png files")
+}
+
+func TestScaleImage(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	src.SetNRGBA(0, 0, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+
+	// Transparent pixels lower the alpha but do not darken the color
+	assert.Equal(t, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x40}, scaleImage(src, 1).NRGBAAt(0, 0))
+
+	// Sizes that do not divide evenly weight the pixels they cover
+	src = image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{R: 0xff, A: 0xff})
+	src.SetNRGBA(1, 0, color.NRGBA{B: 0xff, A: 0xff})
+	src.SetNRGBA(2, 0, color.NRGBA{B: 0xff, A: 0xff})
+	tall := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			tall.SetNRGBA(x, y, src.NRGBAAt(x, 0))
+		}
+	}
+	scaled := scaleImage(tall, 2)
+	assert.Equal(t, color.NRGBA{R: 0xaa, B: 0x55, A: 0xff}, scaled.NRGBAAt(0, 0))
+	assert.Equal(t, color.NRGBA{B: 0xff, A: 0xff}, scaled.NRGBAAt(1, 1))
+}
+
+func TestPNGIconResources(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "iconpng")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	name := filepath.Join(tmpdir, "app.png")
+	writePNG(t, name, 256, 256, color.NRGBA{G: 0xff, A: 0xff})
+
+	vi := &VersionInfo{IconPath: name}
+	assert.NoError(t, vi.Build())
+	vi.Walk()
+	res, err := vi.Resources()
+	assert.NoError(t, err)
+
+	er, err := newExecutableResources(res)
+	assert.NoError(t, err)
+	if assert.Len(t, er.Icons, 2) {
+		headers, err := ico.DecodeHeaders(bytes.NewReader(er.Icons[0].Data))
+		assert.NoError(t, err)
+		assert.Len(t, headers, 6)
+	}
+}
+
+func TestPNGIconRC(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "iconpng")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	name := filepath.Join(tmpdir, "app.png")
+	writePNG(t, name, 256, 256, color.NRGBA{G: 0xff, A: 0xff})
+	dir := filepath.Join(tmpdir, "tray")
+	assert.NoError(t, os.Mkdir(dir, 0755))
+	writePNG(t, filepath.Join(dir, "tray-16.png"), 16, 16, color.NRGBA{R: 0xff, A: 0xff})
+
+	vi := &VersionInfo{IconPath: name, Icons: []IconResource{{Path: dir, ID: ResourceID{Name: "TRAYICON"}}}}
+	assert.NoError(t, vi.Build())
+	vi.Walk()
+	want, err := vi.Resources()
+	assert.NoError(t, err)
+
+	// The script refers to .ico files written next to it.
+	_, err = vi.rc()
+	assert.EqualError(t, err, "the PNG icon "+name+" can not be written to a resource script, WriteRC converts it to an .ico file")
+	file := filepath.Join(tmpdir, "resource.rc")
+	assert.NoError(t, vi.WriteRC(file))
+	assert.Equal(t, name, vi.IconPath)
+
+	b, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	parsed := &VersionInfo{}
+	assert.NoError(t, parsed.ParseRC(b))
+	appIco := filepath.Join(tmpdir, "resource_app.ico")
+	trayIco := filepath.Join(tmpdir, "resource_tray.ico")
+	assert.Equal(t, appIco, parsed.ApplicationIconPath)
+	assert.Equal(t, []IconResource{
+		{Path: appIco, ID: ResourceID{ID: 1}},
+		{Path: trayIco, ID: ResourceID{Name: "TRAYICON"}},
+	}, parsed.Icons)
+
+	// The .ico files hold the same images.
+	parsed.Build()
+	parsed.Walk()
+	got, err := parsed.Resources()
+	assert.NoError(t, err)
+	imageData := func(res Resources) [][]byte {
+		var data [][]byte
+		for _, r := range res {
+			if r.Type == (ResourceID{ID: rtIcon}) {
+				data = append(data, r.Data)
+			}
+		}
+		return data
+	}
+	assert.ElementsMatch(t, imageData(want), imageData(got))
+}
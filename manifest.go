@@ -0,0 +1,422 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// *****************************************************************************
+// Application Manifest
+// *****************************************************************************
+
+/*
+Application manifests
+https://learn.microsoft.com/en-us/windows/win32/sbscs/application-manifests
+
+The manifest is an XML document stored as RT_MANIFEST 1. Each setting lives in
+the namespace of the Windows version that introduced it.
+*/
+
+// Manifest holds the settings of a generated application manifest. A
+// ManifestPath file is merged into it, see ManifestXML.
+type Manifest struct {
+	// Name of the assembly identity. It defaults to the CompanyName and
+	// ProductName of StringFileInfo, like Company.Product. The version is
+	// always FixedFileInfo.FileVersion.
+	Name string `json:",omitempty"`
+
+	// ExecutionLevel is asInvoker, highestAvailable or requireAdministrator.
+	// It defaults to asInvoker.
+	ExecutionLevel string `json:",omitempty"`
+
+	// UIAccess lets the program drive the UI of elevated windows. It needs
+	// a signed program in a secure location.
+	UIAccess bool `json:",omitempty"`
+
+	// DPIAwareness is unaware, system, permonitor or permonitorv2. Windows
+	// scales the program as a bitmap when it is empty.
+	DPIAwareness string `json:",omitempty"`
+
+	// LongPathAware allows paths over MAX_PATH when the system enables them.
+	LongPathAware bool `json:",omitempty"`
+
+	// ActiveCodePage is UTF-8 to make it the code page of the A functions,
+	// or Legacy.
+	ActiveCodePage string `json:",omitempty"`
+
+	// SupportedOS lists the Windows versions the program was tested on: 7,
+	// 8, 8.1, 10 and 11. It defaults to all of them.
+	SupportedOS []string `json:",omitempty"`
+
+	// CommonControls depends on version 6 of the common controls, which
+	// draws them in the visual style of the system.
+	CommonControls bool `json:",omitempty"`
+}
+
+// executionLevels are the values of requestedExecutionLevel.
+var executionLevels = []string{"asInvoker", "highestAvailable", "requireAdministrator"}
+
+type dpiSetting struct{ dpiAware, dpiAwareness string }
+
+// dpiSettings are the dpiAware and dpiAwareness values of each DPIAwareness.
+// dpiAwareness takes precedence from Windows 10 1607 on, which falls back to
+// the next value of the list when it does not know one.
+var dpiSettings = map[string]dpiSetting{
+	"unaware":      {"false", ""},
+	"system":       {"true", ""},
+	"permonitor":   {"true/pm", "PerMonitor"},
+	"permonitorv2": {"true/pm", "PerMonitorV2, PerMonitor"},
+}
+
+// supportedOSIDs are the compatibility GUIDs of the SupportedOS names.
+// Windows 11 shares the one of Windows 10.
+var supportedOSIDs = []struct{ name, id string }{
+	{"7", "{35138b9a-5d96-4fbd-8e2d-a2440225f93a}"},
+	{"8", "{4a2f28e3-53b9-4441-ba9c-d69d4a4a6e38}"},
+	{"8.1", "{1f676c76-80e1-4239-95bb-83d0f6d0da78}"},
+	{"10", "{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"},
+	{"11", "{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"},
+}
+
+// executionLevel returns the level in the case the manifest uses.
+func (m *Manifest) executionLevel() (string, error) {
+	if m.ExecutionLevel == "" {
+		return executionLevels[0], nil
+	}
+	for _, level := range executionLevels {
+		if strings.EqualFold(m.ExecutionLevel, level) {
+			return level, nil
+		}
+	}
+	return "", fmt.Errorf("unknown execution level %q, expected asInvoker, highestAvailable or requireAdministrator", m.ExecutionLevel)
+}
+
+// dpi returns the dpiAware and dpiAwareness values, which are empty when
+// DPIAwareness is not set.
+func (m *Manifest) dpi() (dpiSetting, error) {
+	dpi, ok := dpiSettings[strings.ToLower(m.DPIAwareness)]
+	if !ok && m.DPIAwareness != "" {
+		return dpi, fmt.Errorf("unknown DPI awareness %q, expected unaware, system, permonitor or permonitorv2", m.DPIAwareness)
+	}
+	return dpi, nil
+}
+
+// supportedOS returns the GUIDs of SupportedOS without duplicates.
+func (m *Manifest) supportedOS() ([]string, error) {
+	names := m.SupportedOS
+	if len(names) == 0 {
+		for _, v := range supportedOSIDs {
+			names = append(names, v.name)
+		}
+	}
+
+	var ids []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		id := ""
+		for _, v := range supportedOSIDs {
+			if v.name == strings.TrimSpace(name) {
+				id = v.id
+			}
+		}
+		if id == "" {
+			return nil, fmt.Errorf("unknown Windows version %q, expected 7, 8, 8.1, 10 or 11", name)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// validate reports the settings ManifestXML can not use.
+func (m *Manifest) validate(issues *[]Issue) {
+	if _, err := m.executionLevel(); err != nil {
+		*issues = append(*issues, Issue{SeverityError, "Manifest.ExecutionLevel", err.Error()})
+	}
+	if _, err := m.dpi(); err != nil {
+		*issues = append(*issues, Issue{SeverityError, "Manifest.DPIAwareness", err.Error()})
+	}
+	if m.ActiveCodePage != "" && !strings.EqualFold(m.ActiveCodePage, "UTF-8") && !strings.EqualFold(m.ActiveCodePage, "Legacy") {
+		*issues = append(*issues, Issue{SeverityWarning, "Manifest.ActiveCodePage",
+			fmt.Sprintf("%q is neither UTF-8 nor Legacy, which Windows 10 understands", m.ActiveCodePage)})
+	}
+	if _, err := m.supportedOS(); err != nil {
+		*issues = append(*issues, Issue{SeverityError, "Manifest.SupportedOS", err.Error()})
+	}
+}
+
+// manifestName returns an assembly name made of the company and product
+// names, without the characters a name can not have.
+func manifestName(sfi StringFileInfo) string {
+	product := sfi.ProductName
+	if product == "" {
+		product = sfi.InternalName
+	}
+	var parts []string
+	for _, s := range []string{sfi.CompanyName, product} {
+		s = strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+				return r
+			}
+			return -1
+		}, s)
+		if s = strings.Trim(s, "."); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	if len(parts) == 0 {
+		return "Application"
+	}
+	return strings.Join(parts, ".")
+}
+
+// ManifestXML returns the manifest generated from the Manifest settings, with
+// the name and version of the assembly taken from the version info. When
+// ManifestPath is set too, that file is merged into it, so it only needs the
+// elements and attributes that differ.
+func (vi *VersionInfo) ManifestXML() ([]byte, error) {
+	m := vi.Manifest
+	if m == nil {
+		return nil, fmt.Errorf("there are no Manifest settings")
+	}
+	level, err := m.executionLevel()
+	if err != nil {
+		return nil, err
+	}
+	osIDs, err := m.supportedOS()
+	if err != nil {
+		return nil, err
+	}
+	dpi, err := m.dpi()
+	if err != nil {
+		return nil, err
+	}
+
+	name := m.Name
+	if name == "" {
+		name = manifestName(vi.StringFileInfo)
+	}
+
+	// Parts outside of 16 bits are reported by Validate
+	var version []string
+	for _, part := range vi.FixedFileInfo.FileVersion.parts() {
+		if part.value < 0 || part.value > 0xffff {
+			part.value = 0
+		}
+		version = append(version, fmt.Sprint(part.value))
+	}
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">` + "\n")
+	fmt.Fprintf(&b, `  <assemblyIdentity type="win32" name="%s" version="%s" processorArchitecture="*"/>`+"\n",
+		xmlEscape(name), strings.Join(version, "."))
+
+	if m.CommonControls {
+		b.WriteString("  <dependency>\n")
+		b.WriteString("    <dependentAssembly>\n")
+		b.WriteString(`      <assemblyIdentity type="win32" name="Microsoft.Windows.Common-Controls" version="6.0.0.0"` +
+			` processorArchitecture="*" publicKeyToken="6595b64144ccf1df" language="*"/>` + "\n")
+		b.WriteString("    </dependentAssembly>\n")
+		b.WriteString("  </dependency>\n")
+	}
+
+	b.WriteString(`  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">` + "\n")
+	b.WriteString("    <security>\n")
+	b.WriteString("      <requestedPrivileges>\n")
+	fmt.Fprintf(&b, `        <requestedExecutionLevel level="%s" uiAccess="%t"/>`+"\n", level, m.UIAccess)
+	b.WriteString("      </requestedPrivileges>\n")
+	b.WriteString("    </security>\n")
+	b.WriteString("  </trustInfo>\n")
+
+	b.WriteString(`  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">` + "\n")
+	b.WriteString("    <application>\n")
+	for _, id := range osIDs {
+		fmt.Fprintf(&b, `      <supportedOS Id="%s"/>`+"\n", id)
+	}
+	b.WriteString("    </application>\n")
+	b.WriteString("  </compatibility>\n")
+
+	var settings []string
+	setting := func(ns, element, value string) {
+		settings = append(settings, fmt.Sprintf(`      <%s xmlns="http://schemas.microsoft.com/SMI/%s/WindowsSettings">%s</%s>`,
+			element, ns, xmlEscape(value), element))
+	}
+	if dpi.dpiAware != "" {
+		setting("2005", "dpiAware", dpi.dpiAware)
+	}
+	if dpi.dpiAwareness != "" {
+		setting("2016", "dpiAwareness", dpi.dpiAwareness)
+	}
+	if m.LongPathAware {
+		setting("2016", "longPathAware", "true")
+	}
+	if m.ActiveCodePage != "" {
+		setting("2019", "activeCodePage", m.ActiveCodePage)
+	}
+	if len(settings) > 0 {
+		b.WriteString(`  <application xmlns="urn:schemas-microsoft-com:asm.v3">` + "\n")
+		b.WriteString("    <windowsSettings>\n")
+		b.WriteString(strings.Join(settings, "\n") + "\n")
+		b.WriteString("    </windowsSettings>\n")
+		b.WriteString("  </application>\n")
+	}
+
+	b.WriteString("</assembly>\n")
+
+	if vi.ManifestPath == "" {
+		return b.Bytes(), nil
+	}
+	fragment, err := os.ReadFile(vi.ManifestPath)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := mergeManifest(b.Bytes(), fragment)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", vi.ManifestPath, err)
+	}
+	return merged, nil
+}
+
+// xmlEscape returns s escaped for an attribute or element text.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// repeatedElements are the manifest elements that may appear more than once
+// under the same parent, with the value that tells them apart.
+var repeatedElements = map[string]func(n *xmlNode) string{
+	"supportedOS":      func(n *xmlNode) string { id, _ := n.attr("Id"); return strings.ToLower(id) },
+	"maxversiontested": func(n *xmlNode) string { id, _ := n.attr("Id"); return id },
+	"file":             func(n *xmlNode) string { name, _ := n.attr("name"); return strings.ToLower(name) },
+	"dependency": func(n *xmlNode) string {
+		for _, da := range n.children {
+			for _, id := range da.children {
+				if id.name.Local == "assemblyIdentity" {
+					name, _ := id.attr("name")
+					return strings.ToLower(name)
+				}
+			}
+		}
+		return ""
+	},
+}
+
+// sameElement reports whether over stands for the element n when merging.
+// The asm.v1, asm.v2 and asm.v3 namespaces are the same for this.
+func sameElement(n, over *xmlNode) bool {
+	asm := func(space string) string {
+		if space == nsAsmV2 || space == nsAsmV3 {
+			return nsAsmV1
+		}
+		return space
+	}
+	if n.name.Local != over.name.Local || asm(n.name.Space) != asm(over.name.Space) {
+		return false
+	}
+	if key, ok := repeatedElements[n.name.Local]; ok {
+		return key(n) == key(over)
+	}
+	return true
+}
+
+// mergeXML merges the element over into n. Its attributes and text replace
+// the ones of n, and each child is merged into the matching child of n or
+// added when there is none.
+func mergeXML(n, over *xmlNode) {
+	for _, a := range over.attrs {
+		replaced := false
+		for i := range n.attrs {
+			if n.attrs[i].Name == a.Name {
+				n.attrs[i].Value = a.Value
+				replaced = true
+			}
+		}
+		if !replaced {
+			n.attrs = append(n.attrs, a)
+		}
+	}
+	if strings.TrimSpace(over.text) != "" {
+		n.text = over.text
+	}
+	for _, c := range over.children {
+		merged := false
+		for _, m := range n.children {
+			if sameElement(m, c) {
+				mergeXML(m, c)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			n.children = append(n.children, c)
+		}
+	}
+}
+
+// writeXML writes n indented by two spaces per level. A default namespace is
+// declared where it changes, and attributes in other namespaces get prefixes.
+func writeXML(b *bytes.Buffer, n *xmlNode, space string, depth int) {
+	indent := strings.Repeat("  ", depth)
+	b.WriteString(indent + "<" + n.name.Local)
+	if n.name.Space != space {
+		fmt.Fprintf(b, ` xmlns="%s"`, xmlEscape(n.name.Space))
+	}
+	prefixes := map[string]string{}
+	for _, a := range n.attrs {
+		name := a.Name.Local
+		if a.Name.Space != "" {
+			prefix, ok := prefixes[a.Name.Space]
+			if !ok {
+				prefix = fmt.Sprintf("ns%d", len(prefixes))
+				prefixes[a.Name.Space] = prefix
+				fmt.Fprintf(b, ` xmlns:%s="%s"`, prefix, xmlEscape(a.Name.Space))
+			}
+			name = prefix + ":" + name
+		}
+		fmt.Fprintf(b, ` %s="%s"`, name, xmlEscape(a.Value))
+	}
+
+	text := strings.TrimSpace(n.text)
+	switch {
+	case len(n.children) > 0:
+		b.WriteString(">\n")
+		for _, c := range n.children {
+			writeXML(b, c, n.name.Space, depth+1)
+		}
+		b.WriteString(indent + "</" + n.name.Local + ">\n")
+	case text != "":
+		fmt.Fprintf(b, ">%s</%s>\n", xmlEscape(text), n.name.Local)
+	default:
+		b.WriteString("/>\n")
+	}
+}
+
+// mergeManifest merges the manifest fragment over into the manifest base.
+func mergeManifest(base, over []byte) ([]byte, error) {
+	root, err := parseXML(base)
+	if err != nil {
+		return nil, err
+	}
+	fragment, err := parseXML(over)
+	if err != nil {
+		return nil, err
+	}
+	if !sameElement(root, fragment) {
+		return nil, fmt.Errorf("the root element is %s, expected assembly in the namespace %s", xmlName(fragment.name), nsAsmV1)
+	}
+	mergeXML(root, fragment)
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	writeXML(&b, root, "", 0)
+	return b.Bytes(), nil
+}
@@ -0,0 +1,200 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestXML(t *testing.T) {
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON([]byte(`{
+		"FixedFileInfo": {"FileVersion": {"Major": 1, "Minor": 2, "Patch": 3, "Build": 4}},
+		"StringFileInfo": {"CompanyName": "Acme, Inc.", "ProductName": "Road Runner"},
+		"Manifest": {
+			"ExecutionLevel": "requireadministrator",
+			"UIAccess": true,
+			"DPIAwareness": "PerMonitorV2",
+			"LongPathAware": true,
+			"ActiveCodePage": "UTF-8",
+			"SupportedOS": ["10", "11"],
+			"CommonControls": true
+		}
+	}`)))
+	assert.Empty(t, vi.Validate())
+
+	b, err := vi.ManifestXML()
+	assert.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <assemblyIdentity type="win32" name="AcmeInc.RoadRunner" version="1.2.3.4" processorArchitecture="*"/>
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity type="win32" name="Microsoft.Windows.Common-Controls" version="6.0.0.0" processorArchitecture="*" publicKeyToken="6595b64144ccf1df" language="*"/>
+    </dependentAssembly>
+  </dependency>
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">
+    <security>
+      <requestedPrivileges>
+        <requestedExecutionLevel level="requireAdministrator" uiAccess="true"/>
+      </requestedPrivileges>
+    </security>
+  </trustInfo>
+  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
+    <application>
+      <supportedOS Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"/>
+    </application>
+  </compatibility>
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <dpiAware xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">true/pm</dpiAware>
+      <dpiAwareness xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">PerMonitorV2, PerMonitor</dpiAwareness>
+      <longPathAware xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">true</longPathAware>
+      <activeCodePage xmlns="http://schemas.microsoft.com/SMI/2019/WindowsSettings">UTF-8</activeCodePage>
+    </windowsSettings>
+  </application>
+</assembly>
+`, string(b))
+
+	// The defaults only request asInvoker and list every Windows version
+	vi = &VersionInfo{Manifest: &Manifest{Name: `Say "hi"`}}
+	b, err = vi.ManifestXML()
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `name="Say &#34;hi&#34;" version="0.0.0.0"`)
+	assert.Contains(t, string(b), `<requestedExecutionLevel level="asInvoker" uiAccess="false"/>`)
+	assert.Equal(t, 4, bytes.Count(b, []byte("<supportedOS ")))
+	assert.NotContains(t, string(b), "windowsSettings")
+	assert.NotContains(t, string(b), "Common-Controls")
+
+	var doc struct {
+		XMLName xml.Name `xml:"assembly"`
+	}
+	assert.NoError(t, xml.Unmarshal(b, &doc))
+}
+
+func TestManifestName(t *testing.T) {
+	assert.Equal(t, "Github.com.GoVersionInfo", manifestName(StringFileInfo{CompanyName: "Github.com", ProductName: "Go Version Info"}))
+	assert.Equal(t, "tool", manifestName(StringFileInfo{InternalName: "tool"}))
+	assert.Equal(t, "Application", manifestName(StringFileInfo{ProductName: "☺"}))
+}
+
+func TestManifestResource(t *testing.T) {
+	vi := &VersionInfo{Manifest: &Manifest{DPIAwareness: "system"}}
+	vi.StringFileInfo.ProductVersion = "2.0.1"
+	vi.StringFileInfo.FileVersion = "2.0.1"
+	assert.NoError(t, vi.Build())
+	vi.Walk()
+
+	res, err := vi.Resources()
+	assert.NoError(t, err)
+	er, err := newExecutableResources(res)
+	assert.NoError(t, err)
+	assert.Contains(t, string(er.Manifest), `version="2.0.1.0"`)
+	assert.Contains(t, string(er.Manifest), ">true</dpiAware>")
+
+	_, err = vi.rc()
+	assert.Error(t, err)
+
+	vi.Manifest = &Manifest{ExecutionLevel: "admin", DPIAwareness: "high", ActiveCodePage: "1252", SupportedOS: []string{"XP"}}
+	assert.Equal(t, []Issue{
+		{SeverityError, "Manifest.ExecutionLevel", `unknown execution level "admin", expected asInvoker, highestAvailable or requireAdministrator`},
+		{SeverityError, "Manifest.DPIAwareness", `unknown DPI awareness "high", expected unaware, system, permonitor or permonitorv2`},
+		{SeverityWarning, "Manifest.ActiveCodePage", `"1252" is neither UTF-8 nor Legacy, which Windows 10 understands`},
+		{SeverityError, "Manifest.SupportedOS", `unknown Windows version "XP", expected 7, 8, 8.1, 10 or 11`},
+	}, vi.Validate())
+
+	_, err = vi.Resources()
+	assert.EqualError(t, err, `Manifest: unknown execution level "admin", expected asInvoker, highestAvailable or requireAdministrator`)
+}
+
+func TestMergeManifest(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "manifest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	// The fragment uses asm.v2 and a prefix where the generated one uses
+	// asm.v3 and default namespaces
+	fragment := filepath.Join(tmpdir, "app.manifest")
+	assert.NoError(t, os.WriteFile(fragment, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!-- Only what differs from the defaults -->
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0" xmlns:asmv3="urn:schemas-microsoft-com:asm.v3">
+  <assemblyIdentity name="Acme.Tool"/>
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v2">
+    <security>
+      <requestedPrivileges>
+        <requestedExecutionLevel level="highestAvailable"/>
+      </requestedPrivileges>
+    </security>
+  </trustInfo>
+  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
+    <application>
+      <supportedOS Id="{E2011457-1546-43C5-A5FE-008DEEE3D3F0}"/>
+      <supportedOS Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"/>
+    </application>
+  </compatibility>
+  <asmv3:application>
+    <asmv3:windowsSettings>
+      <dpiAware xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">true/pm</dpiAware>
+      <gdiScaling xmlns="http://schemas.microsoft.com/SMI/2017/WindowsSettings">true</gdiScaling>
+    </asmv3:windowsSettings>
+  </asmv3:application>
+</assembly>
+`), 0644))
+
+	vi := &VersionInfo{
+		ManifestPath: fragment,
+		Manifest:     &Manifest{DPIAwareness: "system", SupportedOS: []string{"10"}, CommonControls: true},
+	}
+	vi.FixedFileInfo.FileVersion = FileVersion{Major: 3}
+	assert.Empty(t, vi.Validate())
+
+	b, err := vi.ManifestXML()
+	assert.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <assemblyIdentity type="win32" name="Acme.Tool" version="3.0.0.0" processorArchitecture="*"/>
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity type="win32" name="Microsoft.Windows.Common-Controls" version="6.0.0.0" processorArchitecture="*" publicKeyToken="6595b64144ccf1df" language="*"/>
+    </dependentAssembly>
+  </dependency>
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">
+    <security>
+      <requestedPrivileges>
+        <requestedExecutionLevel level="highestAvailable" uiAccess="false"/>
+      </requestedPrivileges>
+    </security>
+  </trustInfo>
+  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
+    <application>
+      <supportedOS Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"/>
+      <supportedOS Id="{E2011457-1546-43C5-A5FE-008DEEE3D3F0}"/>
+    </application>
+  </compatibility>
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <dpiAware xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">true/pm</dpiAware>
+      <gdiScaling xmlns="http://schemas.microsoft.com/SMI/2017/WindowsSettings">true</gdiScaling>
+    </windowsSettings>
+  </application>
+</assembly>
+`, string(b))
+
+	assert.NoError(t, vi.Build())
+	vi.Walk()
+	res, err := vi.Resources()
+	assert.NoError(t, err)
+	er, err := newExecutableResources(res)
+	if assert.NoError(t, err) {
+		assert.Equal(t, b, er.Manifest)
+	}
+
+	// A fragment with another root element can not be merged
+	assert.NoError(t, os.WriteFile(fragment, []byte(`<trustInfo xmlns="urn:schemas-microsoft-com:asm.v3"/>`), 0644))
+	_, err = vi.ManifestXML()
+	assert.EqualError(t, err, fragment+": the root element is trustInfo (urn:schemas-microsoft-com:asm.v3), expected assembly in the namespace urn:schemas-microsoft-com:asm.v1")
+}
@@ -0,0 +1,287 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// *****************************************************************************
+// Manifest Linting
+// *****************************************************************************
+
+/*
+Manifest file schema
+https://learn.microsoft.com/en-us/windows/win32/sbscs/manifest-file-schema
+
+Windows refuses to start a program whose manifest it can not parse, with a
+side-by-side configuration error. Unknown elements in the assembly namespaces
+are such errors, while WindowsSettings in the wrong namespace are ignored.
+*/
+
+const (
+	nsAsmV1         = "urn:schemas-microsoft-com:asm.v1"
+	nsAsmV2         = "urn:schemas-microsoft-com:asm.v2"
+	nsAsmV3         = "urn:schemas-microsoft-com:asm.v3"
+	nsCompatibility = "urn:schemas-microsoft-com:compatibility.v1"
+)
+
+// asmElements are the elements of the asm.v1, asm.v2 and asm.v3 namespaces.
+var asmElements = map[string]bool{
+	"assembly": true, "assemblyIdentity": true, "noInherit": true, "noInheritable": true,
+	"description": true, "dependency": true, "dependentAssembly": true, "file": true,
+	"comClass": true, "progid": true, "typelib": true, "comInterfaceExternalProxyStub": true,
+	"comInterfaceProxyStub": true, "windowClass": true, "clrClass": true, "clrSurrogate": true,
+	"bindingRedirect": true, "trustInfo": true, "security": true, "requestedPrivileges": true,
+	"requestedExecutionLevel": true, "application": true, "windowsSettings": true,
+}
+
+// compatibilityElements are the elements of the compatibility namespace.
+var compatibilityElements = map[string]bool{
+	"compatibility": true, "application": true, "supportedOS": true, "maxversiontested": true,
+}
+
+// windowsSettings are the years of the SMI namespaces the settings belong to.
+var windowsSettings = map[string]string{
+	"autoElevate":                       "2005",
+	"disableTheming":                    "2005",
+	"dpiAware":                          "2005",
+	"disableWindowFiltering":            "2011",
+	"printerDriverIsolation":            "2011",
+	"highResolutionScrollingAware":      "2013",
+	"ultraHighResolutionScrollingAware": "2013",
+	"dpiAwareness":                      "2016",
+	"longPathAware":                     "2016",
+	"gdiScaling":                        "2017",
+	"activeCodePage":                    "2019",
+	"heapType":                          "2020",
+}
+
+// windowsSettingsNS matches the namespaces of windowsSettings.
+var windowsSettingsNS = regexp.MustCompile(`^http://schemas\.microsoft\.com/SMI/(\d{4})/WindowsSettings$`)
+
+// vistaOSID is the supportedOS GUID of Windows Vista, which SupportedOS does
+// not offer.
+const vistaOSID = "{e2011457-1546-43c5-a5fe-008deee3d3f0}"
+
+// xmlNode is an element of an XML document with its namespace resolved.
+type xmlNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*xmlNode
+	text     string
+	line     int
+}
+
+// attr returns the value of the attribute without a namespace.
+func (n *xmlNode) attr(name string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// parseXML reads a document into a tree. Comments, processing instructions
+// and the text around child elements are dropped.
+func parseXML(b []byte) (*xmlNode, error) {
+	d := xml.NewDecoder(bytes.NewReader(bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))))
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		tok, err := d.Token()
+		if err == io.EOF && root != nil {
+			return root, nil
+		} else if err == io.EOF {
+			return nil, errors.New("the document has no root element")
+		} else if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			line, _ := d.InputPos()
+			n := &xmlNode{name: t.Name, line: line}
+			for _, a := range t.Attr {
+				if a.Name.Space != "xmlns" && !(a.Name.Space == "" && a.Name.Local == "xmlns") {
+					n.attrs = append(n.attrs, a)
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+}
+
+// manifest returns the ManifestPath file or the generated manifest. Manifests
+// lintManifest finds errors in are rejected, since Windows would not start
+// the program.
+func (vi *VersionInfo) manifest() ([]byte, error) {
+	name := vi.ManifestPath
+	var b []byte
+	var err error
+	if vi.Manifest != nil {
+		name = "Manifest"
+		if b, err = vi.ManifestXML(); err != nil {
+			return nil, fmt.Errorf("Manifest: %w", err)
+		}
+	} else if b, err = os.ReadFile(vi.ManifestPath); err != nil {
+		return nil, err
+	}
+
+	var msgs []string
+	for _, issue := range lintManifest(name, b, false) {
+		if issue.Severity == SeverityError {
+			msgs = append(msgs, issue.Message)
+		}
+	}
+	if len(msgs) > 0 {
+		return nil, fmt.Errorf("%s: %s", name, strings.Join(msgs, "; "))
+	}
+	return b, nil
+}
+
+// LintManifest checks that an application manifest is well formed XML with
+// the assembly root, known elements in the Windows namespaces and valid
+// assembly identities. The error is only set when the file can not be read.
+func LintManifest(filename string) ([]Issue, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return lintManifest(filename, b, false), nil
+}
+
+// lintManifest checks the manifest b read from filename. A fragment that is
+// merged into a generated manifest may leave out required attributes.
+func lintManifest(filename string, b []byte, fragment bool) []Issue {
+	var issues []Issue
+	add := func(severity Severity, n *xmlNode, format string, a ...interface{}) {
+		msg := fmt.Sprintf(format, a...)
+		if n != nil {
+			msg = fmt.Sprintf("line %d: %s", n.line, msg)
+		}
+		issues = append(issues, Issue{severity, filename, msg})
+	}
+
+	root, err := parseXML(b)
+	if err != nil {
+		add(SeverityError, nil, "%v", err)
+		return issues
+	}
+	if root.name.Local != "assembly" || root.name.Space != nsAsmV1 {
+		add(SeverityError, root, "the root element is %s, expected assembly in the namespace %s", xmlName(root.name), nsAsmV1)
+		return issues
+	}
+	if v, _ := root.attr("manifestVersion"); v != "1.0" {
+		add(SeverityError, root, "assembly has manifestVersion %q, expected \"1.0\"", v)
+	}
+
+	var walk func(n *xmlNode)
+	walk = func(n *xmlNode) {
+		switch {
+		case n.name.Space == nsAsmV1 || n.name.Space == nsAsmV2 || n.name.Space == nsAsmV3:
+			if !asmElements[n.name.Local] {
+				add(SeverityError, n, "unknown element %s", xmlName(n.name))
+			}
+		case n.name.Space == nsCompatibility:
+			if !compatibilityElements[n.name.Local] {
+				add(SeverityError, n, "unknown element %s", xmlName(n.name))
+			}
+		case windowsSettingsNS.MatchString(n.name.Space):
+			year, ok := windowsSettings[n.name.Local]
+			got := windowsSettingsNS.FindStringSubmatch(n.name.Space)[1]
+			if !ok {
+				// Newer Windows versions keep adding settings, which older
+				// ones skip
+				add(SeverityWarning, n, "unknown setting %s, Windows ignores it if it does not know it either", xmlName(n.name))
+			} else if year != got {
+				add(SeverityWarning, n, "%s belongs in the namespace http://schemas.microsoft.com/SMI/%s/WindowsSettings, Windows ignores it in the one of %s",
+					n.name.Local, year, got)
+			}
+		case n.name.Space == "":
+			add(SeverityError, n, "element %s has no namespace", n.name.Local)
+		}
+
+		switch n.name.Local {
+		case "assemblyIdentity":
+			name, hasName := n.attr("name")
+			version, hasVersion := n.attr("version")
+			if (!hasName && !fragment) || (hasName && name == "") {
+				add(SeverityError, n, "assemblyIdentity has no name")
+			}
+			if !hasVersion && !fragment {
+				add(SeverityError, n, "assemblyIdentity has no version")
+			} else if hasVersion && !isVersionQuad(version) {
+				add(SeverityError, n, "assemblyIdentity version %q is not four numbers from 0 to 65535", version)
+			}
+		case "requestedExecutionLevel":
+			level, hasLevel := n.attr("level")
+			known := false
+			for _, l := range executionLevels {
+				known = known || level == l
+			}
+			if !known && (hasLevel || !fragment) {
+				add(SeverityError, n, "unknown execution level %q, expected asInvoker, highestAvailable or requireAdministrator", level)
+			}
+			if ui, ok := n.attr("uiAccess"); ok && ui != "true" && ui != "false" {
+				add(SeverityError, n, "uiAccess is %q, expected true or false", ui)
+			}
+		case "supportedOS":
+			id, _ := n.attr("Id")
+			known := strings.EqualFold(id, vistaOSID)
+			for _, v := range supportedOSIDs {
+				known = known || strings.EqualFold(id, v.id)
+			}
+			if !known {
+				add(SeverityWarning, n, "supportedOS Id %q is not the GUID of a Windows version", id)
+			}
+		}
+
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	return issues
+}
+
+// isVersionQuad reports whether s is a version like 1.0.0.0.
+func isVersionQuad(s string) bool {
+	parts := strings.Split(s, ".")
+	if len(parts) != 4 {
+		return false
+	}
+	for _, p := range parts {
+		if _, err := strconv.ParseUint(p, 10, 16); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// xmlName returns the name of an element with its namespace.
+func xmlName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return fmt.Sprintf("%s (%s)", n.Local, n.Space)
+}
@@ -0,0 +1,120 @@
+package goversioninfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintManifest(t *testing.T) {
+	issues, err := LintManifest("testdata/resource/goversioninfo.exe.manifest")
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+
+	b, err := (&VersionInfo{Manifest: &Manifest{DPIAwareness: "permonitorv2", LongPathAware: true, CommonControls: true}}).ManifestXML()
+	assert.NoError(t, err)
+	assert.Empty(t, lintManifest("app.manifest", b, false))
+
+	manifest := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <assemblyIdentity type="win32" name="App" version="1.0.0"/>
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity type="win32" version="6.0.0.0"/>
+    </dependentAssembly>
+  </dependency>
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">
+    <security>
+      <requestedPrivileges>
+        <requestedExecutionLevel level="AsInvoker" uiAccess="no"/>
+      </requestedPrivileges>
+    </security>
+  </trustInfo>
+  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
+    <application>
+      <supportedOs Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"/>
+      <supportedOS Id="{00000000-0000-0000-0000-000000000000}"/>
+    </application>
+  </compatibility>
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <dpiAwareness xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">PerMonitorV2</dpiAwareness>
+      <dpiAwarness xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">PerMonitorV2</dpiAwarness>
+      <longPathAware>true</longPathAware>
+    </windowsSettings>
+  </application>
+</assembly>
+`
+	assert.Equal(t, []Issue{
+		{SeverityError, "app.manifest", `line 3: assemblyIdentity version "1.0.0" is not four numbers from 0 to 65535`},
+		{SeverityError, "app.manifest", "line 6: assemblyIdentity has no name"},
+		{SeverityError, "app.manifest", `line 12: unknown execution level "AsInvoker", expected asInvoker, highestAvailable or requireAdministrator`},
+		{SeverityError, "app.manifest", `line 12: uiAccess is "no", expected true or false`},
+		{SeverityError, "app.manifest", "line 18: unknown element supportedOs (urn:schemas-microsoft-com:compatibility.v1)"},
+		{SeverityWarning, "app.manifest", `line 19: supportedOS Id "{00000000-0000-0000-0000-000000000000}" is not the GUID of a Windows version`},
+		{SeverityWarning, "app.manifest", "line 24: dpiAwareness belongs in the namespace http://schemas.microsoft.com/SMI/2016/WindowsSettings, Windows ignores it in the one of 2005"},
+		{SeverityWarning, "app.manifest", "line 25: unknown setting dpiAwarness (http://schemas.microsoft.com/SMI/2016/WindowsSettings), Windows ignores it if it does not know it either"},
+		{SeverityError, "app.manifest", "line 26: unknown element longPathAware (urn:schemas-microsoft-com:asm.v3)"},
+	}, lintManifest("app.manifest", []byte(manifest), false))
+
+	tests := []struct {
+		data string
+		msg  string
+	}{
+		{`<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">`, "XML syntax error on line 1: unexpected EOF"},
+		{`<!-- nothing -->`, "the document has no root element"},
+		{`<assembly manifestVersion="1.0"/>`, "line 1: the root element is assembly, expected assembly in the namespace urn:schemas-microsoft-com:asm.v1"},
+		{`<assembly xmlns="urn:schemas-microsoft-com:asm.v1"/>`, `line 1: assembly has manifestVersion "", expected "1.0"`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, []Issue{{SeverityError, "app.manifest", tt.msg}}, lintManifest("app.manifest", []byte(tt.data), false))
+	}
+
+	// Fragments may leave out what the generated manifest has
+	fragment := `<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <assemblyIdentity name="App"/>
+</assembly>`
+	assert.Empty(t, lintManifest("app.manifest", []byte(fragment), true))
+	assert.Len(t, lintManifest("app.manifest", []byte(fragment), false), 1)
+}
+
+func TestValidateManifest(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "manifest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	name := filepath.Join(tmpdir, "app.manifest")
+	assert.NoError(t, os.WriteFile(name, []byte(`<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">
+    <securty/>
+  </trustInfo>
+</assembly>`), 0644))
+
+	vi := &VersionInfo{ManifestPath: name}
+	assert.Equal(t, []Issue{
+		{SeverityError, "ManifestPath", name + ": line 3: unknown element securty (urn:schemas-microsoft-com:asm.v3)"},
+	}, vi.Validate())
+
+	// Embedding fails with the same message
+	_, err = vi.Resources()
+	assert.EqualError(t, err, name+": line 3: unknown element securty (urn:schemas-microsoft-com:asm.v3)")
+
+	// Settings of newer Windows versions are embedded with a warning
+	assert.NoError(t, os.WriteFile(name, []byte(`<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <futureSetting xmlns="http://schemas.microsoft.com/SMI/2030/WindowsSettings">true</futureSetting>
+    </windowsSettings>
+  </application>
+</assembly>`), 0644))
+	assert.Equal(t, []Issue{
+		{SeverityWarning, "ManifestPath", name + ": line 4: unknown setting futureSetting (http://schemas.microsoft.com/SMI/2030/WindowsSettings), Windows ignores it if it does not know it either"},
+	}, vi.Validate())
+	_, err = vi.manifest()
+	assert.NoError(t, err)
+
+	_, err = LintManifest(filepath.Join(tmpdir, "missing.manifest"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,427 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// *****************************************************************************
+// Message Tables
+// *****************************************************************************
+
+/*
+Message text files
+https://learn.microsoft.com/en-us/windows/win32/eventlog/message-text-files
+
+MESSAGE_RESOURCE_DATA structure
+https://learn.microsoft.com/en-us/windows/win32/api/winnt/ns-winnt-message_resource_data
+
+A message ID holds the severity in bits 30-31, the facility in bits 16-27 and
+the code in bits 0-15. mc.exe writes one RT_MESSAGETABLE resource with ID 1 for
+each language. It starts with the number of blocks of consecutive IDs, then
+the LowId, HighId and offset to the entries of each block. Every entry is its
+length and flags as WORDs and the text, here in UTF-16 with a terminating zero
+and padded to four bytes.
+*/
+
+const (
+	rtMessageTable = 11
+
+	messageUnicode = 0x0001
+)
+
+// MessageTable holds the messages of an mc.exe message text file, which the
+// Event Log and FormatMessage look up by ID.
+type MessageTable struct {
+	Messages []Message
+
+	// OutputBase is 10 or 16, the base WriteGo writes the IDs in.
+	OutputBase int
+}
+
+// Message is one message of a message text file.
+type Message struct {
+	// ID holds the severity, facility and code of the message.
+	ID uint32
+
+	// SymbolicName is the name of the constant WriteGo writes, none when it
+	// is empty.
+	SymbolicName string
+
+	// Text holds the text of each language. Every line ends with \r\n, as
+	// mc.exe writes it, unless it ends with %0. Escapes like %n and %1 are
+	// kept for FormatMessage.
+	Text map[LangID]string
+}
+
+// messageName is a name of a SeverityNames, FacilityNames or LanguageNames
+// list.
+type messageName struct {
+	value uint32
+	extra string
+}
+
+// ReadMC reads a message text file with ParseMC.
+func ReadMC(filename string) (*MessageTable, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMC(b)
+}
+
+// ParseMC parses a message text file in the format of mc.exe, with the
+// MessageIdTypedef, SeverityNames, FacilityNames, LanguageNames and
+// OutputBase header statements and messages with MessageId, Severity,
+// Facility, SymbolicName and Language. Comment lines start with a semicolon.
+// A %0 at the end of a text line joins it to the next one, the other escapes
+// are checked and kept. Files are read as UTF-8, or UTF-16 when they start
+// with a byte order mark.
+func ParseMC(b []byte) (*MessageTable, error) {
+	severities := map[string]messageName{
+		"success": {0x0, ""}, "informational": {0x1, ""}, "warning": {0x2, ""}, "error": {0x3, ""},
+	}
+	facilities := map[string]messageName{
+		"system": {0x0ff, ""}, "application": {0xfff, ""},
+	}
+	languages := map[string]messageName{
+		"english": {uint32(LngUSEnglish), "MSG00409"},
+	}
+
+	mt := &MessageTable{OutputBase: 16}
+	var msg *Message
+	var severity, facility uint32
+	last := map[uint32]uint32{}
+	ids := map[uint32]int{}
+	names := map[string]int{}
+
+	lines := strings.Split(strings.ReplaceAll(decodeRCText(b), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		lineNo := i + 1
+		errorf := func(format string, a ...interface{}) error {
+			return fmt.Errorf("line %d: %s", lineNo, fmt.Sprintf(format, a...))
+		}
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, errorf("expected a keyword like MessageId=, found %q", line)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		keyword := strings.ToLower(key)
+
+		// Name lists may span lines up to the closing parenthesis
+		if strings.HasPrefix(value, "(") {
+			for !strings.Contains(value, ")") && i+1 < len(lines) {
+				i++
+				value += " " + strings.TrimSpace(lines[i])
+			}
+			if !strings.HasSuffix(value, ")") {
+				return nil, errorf("%s has no closing parenthesis", key)
+			}
+			value = strings.TrimSpace(value[1 : len(value)-1])
+		}
+
+		switch keyword {
+		case "messageidtypedef":
+			// Only used by the C header of mc.exe
+		case "severitynames", "facilitynames", "languagenames":
+			list, err := parseMessageNames(value, keyword == "languagenames")
+			if err != nil {
+				return nil, errorf("%s: %v", key, err)
+			}
+			// The lists add to the predefined names
+			target := languages
+			switch keyword {
+			case "severitynames":
+				target = severities
+			case "facilitynames":
+				target = facilities
+			}
+			for name, value := range list {
+				target[name] = value
+			}
+		case "outputbase":
+			base, err := strconv.Atoi(value)
+			if err != nil || (base != 10 && base != 16) {
+				return nil, errorf("OutputBase is %q, expected 10 or 16", value)
+			}
+			mt.OutputBase = base
+		case "messageid":
+			code := last[facility] + 1
+			if strings.HasPrefix(value, "+") {
+				n, err := strconv.ParseUint(value[1:], 0, 32)
+				if err != nil {
+					return nil, errorf("MessageId %q is not a number", value)
+				}
+				code = last[facility] + uint32(n)
+			} else if value != "" {
+				n, err := strconv.ParseUint(value, 0, 32)
+				if err != nil {
+					return nil, errorf("MessageId %q is not a number", value)
+				}
+				code = uint32(n)
+			}
+			if code > 0xffff {
+				return nil, errorf("MessageId %d does not fit in 16 bits", code)
+			}
+			last[facility] = code
+			mt.Messages = append(mt.Messages, Message{ID: code, Text: map[LangID]string{}})
+			msg = &mt.Messages[len(mt.Messages)-1]
+		case "severity", "facility", "symbolicname", "language":
+			if msg == nil {
+				return nil, errorf("%s before the first MessageId", key)
+			}
+		default:
+			return nil, errorf("unknown keyword %s", key)
+		}
+
+		switch keyword {
+		case "severity":
+			s, ok := severities[strings.ToLower(value)]
+			if !ok {
+				return nil, errorf("unknown severity %q", value)
+			}
+			severity = s.value
+		case "facility":
+			f, ok := facilities[strings.ToLower(value)]
+			if !ok {
+				return nil, errorf("unknown facility %q", value)
+			}
+			// The code counts in the facility of the message
+			last[f.value] = msg.ID & 0xffff
+			facility = f.value
+		case "symbolicname":
+			if other, ok := names[value]; ok {
+				return nil, errorf("SymbolicName %s is already used on line %d", value, other)
+			}
+			names[value] = lineNo
+			msg.SymbolicName = value
+		case "language":
+			l, ok := languages[strings.ToLower(value)]
+			if !ok {
+				return nil, errorf("unknown language %q", value)
+			}
+			lang := LangID(l.value)
+			if _, ok := msg.Text[lang]; ok {
+				return nil, errorf("the message has two texts in %s", value)
+			}
+			var text strings.Builder
+			for {
+				i++
+				if i >= len(lines) {
+					return nil, errorf("the text in %s has no line with a single period", value)
+				}
+				if strings.TrimRight(lines[i], " \t\r") == "." {
+					break
+				}
+				zero, err := checkMessageEscapes(lines[i])
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", i+1, err)
+				}
+				// %0 joins the line to the next one, or ends the text
+				// without a line break
+				if zero {
+					text.WriteString(strings.TrimSuffix(lines[i], "%0"))
+				} else {
+					text.WriteString(lines[i])
+					text.WriteString("\r\n")
+				}
+			}
+			msg.Text[lang] = text.String()
+
+			// The severity and facility are known after the header of the
+			// message
+			id := severity<<30 | facility<<16 | msg.ID&0xffff
+			if len(msg.Text) == 1 {
+				if other, ok := ids[id]; ok {
+					return nil, errorf("message ID 0x%08X is already used on line %d", id, other)
+				}
+				ids[id] = lineNo
+			}
+			msg.ID = id
+		}
+	}
+
+	for _, m := range mt.Messages {
+		if len(m.Text) == 0 {
+			return nil, fmt.Errorf("message 0x%08X has no text", m.ID)
+		}
+	}
+	return mt, nil
+}
+
+// checkMessageEscapes checks the escapes of a line of message text, which
+// FormatMessage expands: the inserts %1 to %99, and %n, %r, %t, %b, %., %!
+// and %% for a line break, a carriage return, a tab, a space, a period, an
+// exclamation mark and a percent sign. It tells if the line ends with %0.
+func checkMessageEscapes(line string) (bool, error) {
+	for j := 0; j < len(line); j++ {
+		if line[j] != '%' {
+			continue
+		}
+		j++
+		if j == len(line) {
+			return false, fmt.Errorf("the line ends with a single %%, write %%%% for a percent sign")
+		}
+		switch c := line[j]; {
+		case c == '0':
+			if j != len(line)-1 {
+				return false, fmt.Errorf("%%0 ends the line, but text follows it")
+			}
+			return true, nil
+		case c >= '1' && c <= '9', strings.IndexByte("nrtb.!%", c) >= 0:
+		default:
+			r, _ := utf8.DecodeRuneInString(line[j:])
+			return false, fmt.Errorf("unknown escape %%%c, expected %%1 to %%99, %%0, %%n, %%r, %%t, %%b, %%., %%! or %%%%", r)
+		}
+	}
+	return false, nil
+}
+
+// parseMessageNames reads the name=number:extra pairs of a name list. The
+// extra part is the symbol of a severity or facility and the file name of a
+// language, which must be set.
+func parseMessageNames(s string, needExtra bool) (map[string]messageName, error) {
+	names := map[string]messageName{}
+	for _, pair := range strings.Fields(s) {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("expected name=number, found %q", pair)
+		}
+		number, extra, _ := strings.Cut(value, ":")
+		if needExtra && extra == "" {
+			return nil, fmt.Errorf("expected name=number:filename, found %q", pair)
+		}
+		n, err := strconv.ParseUint(number, 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", number)
+		}
+		names[strings.ToLower(name)] = messageName{uint32(n), extra}
+	}
+	return names, nil
+}
+
+// languages returns the languages of the messages in ascending order.
+func (mt *MessageTable) languages() []LangID {
+	seen := map[LangID]bool{}
+	var langs []LangID
+	for _, m := range mt.Messages {
+		for lang := range m.Text {
+			if !seen[lang] {
+				seen[lang] = true
+				langs = append(langs, lang)
+			}
+		}
+	}
+	sort.Slice(langs, func(i, j int) bool { return langs[i] < langs[j] })
+	return langs
+}
+
+// resourceData returns the MESSAGE_RESOURCE_DATA of the messages in the
+// language, in the order of their IDs.
+func (mt *MessageTable) resourceData(lang LangID) []byte {
+	var msgs []Message
+	for _, m := range mt.Messages {
+		if _, ok := m.Text[lang]; ok {
+			msgs = append(msgs, m)
+		}
+	}
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].ID < msgs[j].ID })
+
+	type block struct{ low, high uint32 }
+	var blocks []block
+	for _, m := range msgs {
+		if n := len(blocks); n > 0 && blocks[n-1].high+1 == m.ID {
+			blocks[n-1].high = m.ID
+		} else {
+			blocks = append(blocks, block{m.ID, m.ID})
+		}
+	}
+
+	var entries bytes.Buffer
+	offsets := make([]uint32, len(blocks))
+	header := uint32(4 + 12*len(blocks))
+	next := 0
+	for i, b := range blocks {
+		offsets[i] = header + uint32(entries.Len())
+		for ; next < len(msgs) && msgs[next].ID <= b.high; next++ {
+			size := 2 * (len(utf16.Encode([]rune(msgs[next].Text[lang]))) + 1)
+			zeros := 2 + (4-(4+size)%4)%4
+			binary.Write(&entries, binary.LittleEndian, uint16(4+size+zeros-2))
+			binary.Write(&entries, binary.LittleEndian, uint16(messageUnicode))
+			entries.Write(padString(msgs[next].Text[lang], zeros))
+		}
+	}
+
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, uint32(len(blocks)))
+	for i, blk := range blocks {
+		binary.Write(&b, binary.LittleEndian, [3]uint32{blk.low, blk.high, offsets[i]})
+	}
+	b.Write(entries.Bytes())
+	return b.Bytes()
+}
+
+// addMessageTable adds an RT_MESSAGETABLE resource with ID 1 for each
+// language.
+func addMessageTable(res *Resources, mt *MessageTable) {
+	for _, lang := range mt.languages() {
+		*res = append(*res, Resource{Type: ResourceID{ID: rtMessageTable}, Name: ResourceID{ID: 1}, LangID: lang, Data: mt.resourceData(lang)})
+	}
+}
+
+// WriteGo creates a Go file with a constant for each message with a
+// SymbolicName, to pass to the Event Log or FormatMessage. The comment of a
+// constant is its text in U.S. English, or else in the first language.
+func (mt *MessageTable) WriteGo(filename, packageName string) error {
+	if len(packageName) == 0 {
+		packageName = "main"
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Auto-generated file by goversioninfo. Do not edit.\npackage %v\n\n", packageName)
+	b.WriteString("// Message IDs of the message table.\nconst (\n")
+	langs := append([]LangID{LngUSEnglish}, mt.languages()...)
+	first := true
+	for _, m := range mt.Messages {
+		if m.SymbolicName == "" {
+			continue
+		}
+		if !first {
+			b.WriteString("\n")
+		}
+		first = false
+		for _, lang := range langs {
+			if text, ok := m.Text[lang]; ok {
+				for _, line := range strings.Split(strings.TrimSuffix(text, "\r\n"), "\r\n") {
+					b.WriteString(strings.TrimRight("\t// "+line, " \t") + "\n")
+				}
+				break
+			}
+		}
+		if mt.OutputBase == 10 {
+			fmt.Fprintf(&b, "\t%s = %d\n", m.SymbolicName, m.ID)
+		} else {
+			fmt.Fprintf(&b, "\t%s = 0x%08X\n", m.SymbolicName, m.ID)
+		}
+	}
+	b.WriteString(")\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("SymbolicName is not a Go identifier: %w", err)
+	}
+	return os.WriteFile(filename, src, 0644)
+}
@@ -0,0 +1,165 @@
+package goversioninfo
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMC(t *testing.T) {
+	mt, err := ReadMC("testdata/mc/events.mc")
+	assert.NoError(t, err)
+	assert.Equal(t, &MessageTable{OutputBase: 16, Messages: []Message{
+		{ID: 0x41000001, SymbolicName: "MSG_SERVICE_STARTED", Text: map[LangID]string{
+			LngUSEnglish: "The service started.\r\n",
+			LngGerman:    "Der Dienst wurde gestartet.\r\n",
+		}},
+		{ID: 0x41000002, SymbolicName: "MSG_SERVICE_STOPPED", Text: map[LangID]string{
+			LngUSEnglish: "The service stopped.\r\n",
+		}},
+		{ID: 0xC100000A, SymbolicName: "MSG_CONFIG_INVALID", Text: map[LangID]string{
+			LngUSEnglish: "The configuration file %1 is invalid:\r\n%2\r\n",
+		}},
+		{ID: 0xC100000B, SymbolicName: "MSG_CONFIG_RETRY", Text: map[LangID]string{
+			LngUSEnglish: "Reading the configuration again in %1 seconds, attempt %2 of %3%.%n\r\n%.%.%. 100%% done",
+		}},
+	}}, mt)
+
+	tests := []struct {
+		data string
+		msg  string
+	}{
+		{"MessageId=1\nLanguage=English\ntext\n", "line 2: the text in English has no line with a single period"},
+		{"Severity=Error\n", "line 1: Severity before the first MessageId"},
+		{"MessageId=1\nSeverity=Fatal\n", `line 2: unknown severity "Fatal"`},
+		{"MessageId=1\nLanguage=French\n", `line 2: unknown language "French"`},
+		{"MessageId=70000\n", "line 1: MessageId 70000 does not fit in 16 bits"},
+		{"MessageId=1\nLanguage=English\na\n.\nMessageId=1\nLanguage=English\nb\n.\n", "line 6: message ID 0x00000001 is already used on line 2"},
+		{"MessageId=1\nSymbolicName=A\nLanguage=English\na\n.\nMessageId=2\nSymbolicName=A\n", "line 7: SymbolicName A is already used on line 2"},
+		{"LanguageNames=(French=0x40c)\n", `line 1: LanguageNames: expected name=number:filename, found "French=0x40c"`},
+		{"SeverityNames=(Fatal=0x3\n", "line 1: SeverityNames has no closing parenthesis"},
+		{"OutputBase=8\n", `line 1: OutputBase is "8", expected 10 or 16`},
+		{"Message text\n", `line 1: expected a keyword like MessageId=, found "Message text"`},
+		{"MessageId=1\n", "message 0x00000001 has no text"},
+		{"MessageId=1\nLanguage=English\n100%\n.\n", "line 3: the line ends with a single %, write %% for a percent sign"},
+		{"MessageId=1\nLanguage=English\n%s failed\n.\n", "line 3: unknown escape %s, expected %1 to %99, %0, %n, %r, %t, %b, %., %! or %%"},
+		{"MessageId=1\nLanguage=English\nStop%0 here\n.\n", "line 3: %0 ends the line, but text follows it"},
+	}
+	for _, tt := range tests {
+		_, err := ParseMC([]byte(tt.data))
+		assert.EqualError(t, err, tt.msg)
+	}
+}
+
+func TestMessageTableResourceData(t *testing.T) {
+	mt, err := ReadMC("testdata/mc/events.mc")
+	assert.NoError(t, err)
+	assert.Equal(t, []LangID{LngGerman, LngUSEnglish}, mt.languages())
+
+	b := mt.resourceData(LngUSEnglish)
+	le := binary.LittleEndian
+
+	// Two blocks, as 0x41000002 and 0xC100000A are not consecutive
+	assert.Equal(t, uint32(2), le.Uint32(b))
+	assert.Equal(t, []uint32{0x41000001, 0x41000002, 28}, []uint32{le.Uint32(b[4:]), le.Uint32(b[8:]), le.Uint32(b[12:])})
+	assert.Equal(t, []uint32{0xC100000A, 0xC100000B, 132}, []uint32{le.Uint32(b[16:]), le.Uint32(b[20:]), le.Uint32(b[24:])})
+
+	// "The service started.\r\n" is 44 bytes, 46 with the zero and 52 with
+	// the header and padding
+	assert.Equal(t, uint16(52), le.Uint16(b[28:]))
+	assert.Equal(t, uint16(messageUnicode), le.Uint16(b[30:]))
+	assert.Equal(t, padString("The service started.\r\n", 2), b[32:78])
+	assert.Equal(t, []byte{0, 0, 0, 0}, b[76:80])
+	assert.Equal(t, uint16(52), le.Uint16(b[80:]))
+
+	// 43 characters need no padding after the zero, 86 need two bytes
+	assert.Equal(t, uint16(4+86+2), le.Uint16(b[132:]))
+	assert.Equal(t, uint16(4+172+2+2), le.Uint16(b[132+4+86+2:]))
+	assert.Len(t, b, 132+4+86+2+4+172+2+2)
+}
+
+func TestMessageTableResources(t *testing.T) {
+	vi := &VersionInfo{MessageTablePath: "testdata/mc/events.mc"}
+	assert.Empty(t, vi.Validate())
+	assert.NoError(t, vi.Build())
+	vi.Walk()
+
+	res, err := vi.Resources()
+	assert.NoError(t, err)
+	var langs []LangID
+	for _, r := range res {
+		if r.Type == (ResourceID{ID: rtMessageTable}) {
+			assert.Equal(t, ResourceID{ID: 1}, r.Name)
+			langs = append(langs, r.LangID)
+		}
+	}
+	assert.Equal(t, []LangID{LngGerman, LngUSEnglish}, langs)
+
+	_, err = vi.rc()
+	assert.Error(t, err)
+
+	vi.FileResources = []FileResource{{Type: ResourceID{Name: "RT_MESSAGETABLE"}, ID: ResourceID{ID: 1}, Path: "testdata/mc/events.mc"}}
+	assert.Equal(t, []Issue{
+		{SeverityError, "Resources[0].ID", "RT_MESSAGETABLE 1 is already used by the message table"},
+	}, vi.Validate())
+
+	tmpdir, err := os.MkdirTemp("", "mc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	name := filepath.Join(tmpdir, "bad.mc")
+	assert.NoError(t, os.WriteFile(name, []byte("MessageId=1\nLanguage=Klingon\n"), 0644))
+	vi = &VersionInfo{MessageTablePath: name}
+	assert.Equal(t, []Issue{
+		{SeverityError, "MessageTablePath", name + `: line 2: unknown language "Klingon"`},
+	}, vi.Validate())
+	_, err = vi.Resources()
+	assert.EqualError(t, err, name+`: line 2: unknown language "Klingon"`)
+}
+
+func TestMessageTableWriteGo(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "mc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	mt, err := ReadMC("testdata/mc/events.mc")
+	assert.NoError(t, err)
+	name := filepath.Join(tmpdir, "messages.go")
+	assert.NoError(t, mt.WriteGo(name, "service"))
+	b, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, `// Auto-generated file by goversioninfo. Do not edit.
+package service
+
+// Message IDs of the message table.
+const (
+	// The service started.
+	MSG_SERVICE_STARTED = 0x41000001
+
+	// The service stopped.
+	MSG_SERVICE_STOPPED = 0x41000002
+
+	// The configuration file %1 is invalid:
+	// %2
+	MSG_CONFIG_INVALID = 0xC100000A
+
+	// Reading the configuration again in %1 seconds, attempt %2 of %3%.%n
+	// %.%.%. 100%% done
+	MSG_CONFIG_RETRY = 0xC100000B
+)
+`, string(b))
+
+	mt.OutputBase = 10
+	mt.Messages = mt.Messages[1:2]
+	assert.NoError(t, mt.WriteGo(name, ""))
+	b, err = os.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "package main\n")
+	assert.Contains(t, string(b), "\tMSG_SERVICE_STOPPED = 1090519042\n")
+
+	mt.Messages[0].SymbolicName = "MSG-STOPPED"
+	assert.Error(t, mt.WriteGo(name, "main"))
+}
@@ -0,0 +1,334 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// *****************************************************************************
+// Executable Patching
+// *****************************************************************************
+
+/*
+PE file layout
+https://learn.microsoft.com/en-us/windows/win32/debug/pe-format
+
+The resources are written as a new .rsrc section that replaces the old one
+in place when it fits, grows it when it is the last section, and is added as
+another section otherwise. Patching changes the file, so an Authenticode
+signature no longer matches and is removed.
+*/
+
+// Offsets into the PE headers that patching rewrites.
+const (
+	peSignatureOffset  = 0x3c
+	optSizeOfInitData  = 8
+	optSectionAlign    = 32
+	optFileAlign       = 36
+	optSizeOfImage     = 56
+	optSizeOfHeaders   = 60
+	optCheckSum        = 64
+	optDataDirectory32 = 96
+	optDataDirectory64 = 112
+
+	sectVirtualSize      = 8
+	sectVirtualAddress   = 12
+	sectSizeOfRawData    = 16
+	sectPointerToRawData = 20
+	sectCharacteristics  = 36
+
+	imageScnCntInitializedData = 0x00000040
+	imageScnMemRead            = 0x40000000
+)
+
+// PatchExecutable writes the version info, manifest and icons into the
+// Windows executable or DLL filename, in place of the ones it has. Its other
+// resources are kept, unless the ResPath .res file has one with the same
+// type and ID. The file is only parsed, so this works on any platform.
+func (vi *VersionInfo) PatchExecutable(filename string) error {
+	f, err := pe.Open(filename)
+	if err != nil {
+		return err
+	}
+	existing, err := executableResources(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	res, err := vi.Resources()
+	if err != nil {
+		return err
+	}
+	return patchResources(existing, res).WriteExecutable(filename)
+}
+
+// patchResources adds the resources of an executable that res does not
+// replace to res. The version info, the manifests and the icons of res each
+// replace all of the executable's, so Windows can not pick an old one.
+// Other resources replace the ones with the same type and ID.
+func patchResources(existing, res Resources) Resources {
+	kind := func(r Resource) ResourceID {
+		if r.Type == (ResourceID{ID: rtIcon}) {
+			return ResourceID{ID: rtGroupIcon}
+		}
+		return r.Type.key()
+	}
+	replaced := map[ResourceID]bool{}
+	for _, r := range res {
+		switch k := kind(r); k {
+		case ResourceID{ID: rtVersion}, ResourceID{ID: rtManifest}, ResourceID{ID: rtGroupIcon}:
+			replaced[k] = true
+		}
+	}
+
+	var kept Resources
+	for _, r := range existing {
+		if !replaced[kind(r)] {
+			kept = append(kept, r)
+		}
+	}
+	merged, _ := res.Merge(kept, ConflictReplace)
+	return merged
+}
+
+// WriteExecutable replaces the resources of the Windows executable or DLL
+// filename with rs. The section headers, SizeOfImage and the checksum are
+// updated to match.
+func (rs Resources) WriteExecutable(filename string) error {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	out, err := setResourceSection(b, rs)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return os.WriteFile(filename, out, fi.Mode().Perm())
+}
+
+// setResourceSection returns the image b with its resources replaced by rs.
+func setResourceSection(b []byte, rs Resources) ([]byte, error) {
+	f, err := pe.NewFile(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	le := binary.LittleEndian
+	optOff := int(le.Uint32(b[peSignatureOffset:])) + 4 + coffFileHeaderSize
+	sectOff := optOff + int(f.FileHeader.SizeOfOptionalHeader)
+	var dataDirOff int
+	var numDirs uint32
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		dataDirOff, numDirs = optOff+optDataDirectory32, oh.NumberOfRvaAndSizes
+	case *pe.OptionalHeader64:
+		dataDirOff, numDirs = optOff+optDataDirectory64, oh.NumberOfRvaAndSizes
+	default:
+		return nil, errors.New("not an executable image, the optional header is missing")
+	}
+	if numDirs <= pe.IMAGE_DIRECTORY_ENTRY_SECURITY {
+		return nil, fmt.Errorf("the optional header has only %d data directories", numDirs)
+	}
+	resDirOff := dataDirOff + 8*pe.IMAGE_DIRECTORY_ENTRY_RESOURCE
+	certDirOff := dataDirOff + 8*pe.IMAGE_DIRECTORY_ENTRY_SECURITY
+
+	sectionAlign := le.Uint32(b[optOff+optSectionAlign:])
+	fileAlign := le.Uint32(b[optOff+optFileAlign:])
+	if sectionAlign == 0 || fileAlign == 0 {
+		return nil, errors.New("the section or file alignment is zero")
+	}
+
+	// Find the section that holds only the resources, where the sections
+	// end in memory and where their data ends in the file. Resources that
+	// share a section with other data are left alone.
+	resRVA := le.Uint32(b[resDirOff:])
+	rsrc := -1
+	var imageEnd, rawEnd uint32
+	for i, s := range f.Sections {
+		if resRVA != 0 && resRVA == s.VirtualAddress && s.Name == ".rsrc" {
+			rsrc = i
+		}
+		if end := s.VirtualAddress + virtualSize(s); end > imageEnd {
+			imageEnd = end
+		}
+		if end := s.Offset + s.Size; end > rawEnd {
+			rawEnd = end
+		}
+	}
+	if rawEnd > uint32(len(b)) {
+		return nil, fmt.Errorf("section data ends at %#x, past the %d byte file", rawEnd, len(b))
+	}
+
+	// Anything after the sections is kept, except the signature
+	overlay := b[rawEnd:]
+	certOff, certSize := le.Uint32(b[certDirOff:]), le.Uint32(b[certDirOff+4:])
+	if certOff >= rawEnd && uint64(certOff)+uint64(certSize) == uint64(len(b)) {
+		overlay = b[rawEnd:certOff]
+	}
+
+	// The size of the resources does not depend on where they are loaded
+	probe, _, err := rs.section(0)
+	if err != nil {
+		return nil, err
+	}
+	size := uint32(len(probe))
+
+	var out, data []byte
+	var header int     // offset of the section header of the resources
+	var rawSize uint32 // SizeOfRawData of the section
+	switch {
+	case rsrc >= 0 && size <= f.Sections[rsrc].Size && f.Sections[rsrc].VirtualAddress+size <= nextSection(f.Sections, rsrc):
+		// Rewrite the section in place
+		s := f.Sections[rsrc]
+		header, rawSize = sectOff+rsrc*coffSectionHeaderSize, s.Size
+		if data, _, err = rs.section(s.VirtualAddress); err != nil {
+			return nil, err
+		}
+		out = append(append([]byte(nil), b[:rawEnd]...), overlay...)
+		copy(out[s.Offset:s.Offset+s.Size], make([]byte, s.Size))
+		copy(out[s.Offset:], data)
+
+	case rsrc >= 0 && f.Sections[rsrc].VirtualAddress+virtualSize(f.Sections[rsrc]) == imageEnd &&
+		f.Sections[rsrc].Offset+f.Sections[rsrc].Size == rawEnd:
+		// The last section can grow as much as it needs
+		s := f.Sections[rsrc]
+		header, rawSize = sectOff+rsrc*coffSectionHeaderSize, alignUp(size, fileAlign)
+		if data, _, err = rs.section(s.VirtualAddress); err != nil {
+			return nil, err
+		}
+		out = append(append([]byte(nil), b[:s.Offset]...), data...)
+		out = append(out, make([]byte, rawSize-size)...)
+		out = append(out, overlay...)
+
+	default:
+		// Add a section after the others, which needs room for its header
+		header, rawSize = sectOff+len(f.Sections)*coffSectionHeaderSize, alignUp(size, fileAlign)
+		firstData := le.Uint32(b[optOff+optSizeOfHeaders:])
+		for _, s := range f.Sections {
+			if s.Size != 0 && s.Offset < firstData {
+				firstData = s.Offset
+			}
+		}
+		if uint32(header+coffSectionHeaderSize) > firstData {
+			return nil, errors.New("there is no room for another section header")
+		}
+
+		rva := alignUp(imageEnd, sectionAlign)
+		if data, _, err = rs.section(rva); err != nil {
+			return nil, err
+		}
+		offset := alignUp(rawEnd, fileAlign)
+		out = append(append([]byte(nil), b[:rawEnd]...), make([]byte, offset-rawEnd)...)
+		out = append(out, data...)
+		out = append(out, make([]byte, rawSize-size)...)
+		out = append(out, overlay...)
+
+		if rsrc >= 0 {
+			// The old resources stay in the image, but nothing refers to them
+			copy(out[sectOff+rsrc*coffSectionHeaderSize:][:8], ".oldrsrc")
+		}
+		copy(out[header:header+coffSectionHeaderSize], make([]byte, coffSectionHeaderSize))
+		copy(out[header:], ".rsrc")
+		le.PutUint32(out[header+sectVirtualAddress:], rva)
+		le.PutUint32(out[header+sectPointerToRawData:], offset)
+		le.PutUint32(out[header+sectCharacteristics:], imageScnCntInitializedData|imageScnMemRead)
+		le.PutUint16(out[optOff-coffFileHeaderSize+2:], uint16(len(f.Sections)+1))
+	}
+
+	// Update the section header and the headers that depend on it
+	oldRawSize := le.Uint32(out[header+sectSizeOfRawData:])
+	le.PutUint32(out[header+sectVirtualSize:], size)
+	le.PutUint32(out[header+sectSizeOfRawData:], rawSize)
+	initData := le.Uint32(out[optOff+optSizeOfInitData:])
+	le.PutUint32(out[optOff+optSizeOfInitData:], initData+rawSize-oldRawSize)
+
+	sizeOfImage, err := imageSize(out, sectionAlign)
+	if err != nil {
+		return nil, err
+	}
+	le.PutUint32(out[optOff+optSizeOfImage:], sizeOfImage)
+
+	le.PutUint32(out[resDirOff:], le.Uint32(out[header+sectVirtualAddress:]))
+	le.PutUint32(out[resDirOff+4:], size)
+	le.PutUint64(out[certDirOff:], 0)
+
+	// The COFF symbol table of Go and MinGW binaries can follow the sections
+	symbols := optOff - coffFileHeaderSize + 8
+	if ptr := le.Uint32(out[symbols:]); ptr >= rawEnd {
+		le.PutUint32(out[symbols:], ptr+uint32(len(out)-len(overlay))-rawEnd)
+	}
+
+	le.PutUint32(out[optOff+optCheckSum:], peChecksum(out, optOff+optCheckSum))
+	return out, nil
+}
+
+// virtualSize returns the size of a section in memory. Some linkers leave
+// VirtualSize at zero and only set the size in the file.
+func virtualSize(s *pe.Section) uint32 {
+	if s.VirtualSize == 0 {
+		return s.Size
+	}
+	return s.VirtualSize
+}
+
+// nextSection returns the address of the section loaded after section i, or
+// the highest address for the last one.
+func nextSection(sections []*pe.Section, i int) uint32 {
+	next := ^uint32(0)
+	for _, s := range sections {
+		if s.VirtualAddress > sections[i].VirtualAddress && s.VirtualAddress < next {
+			next = s.VirtualAddress
+		}
+	}
+	return next
+}
+
+// imageSize returns the SizeOfImage of b, the end of its last section in
+// memory rounded up to the section alignment.
+func imageSize(b []byte, sectionAlign uint32) (uint32, error) {
+	f, err := pe.NewFile(bytes.NewReader(b))
+	if err != nil {
+		return 0, err
+	}
+	var end uint32
+	for _, s := range f.Sections {
+		if e := s.VirtualAddress + virtualSize(s); e > end {
+			end = e
+		}
+	}
+	return alignUp(end, sectionAlign), nil
+}
+
+// peChecksum computes the CheckSum of an image the way ImageHlp's
+// CheckSumMappedFile does: the 16-bit one's complement sum of the file with
+// the CheckSum field at offset skipped, plus the length of the file.
+func peChecksum(b []byte, offset int) uint32 {
+	var sum uint32
+	for i := 0; i < len(b); i += 2 {
+		if i == offset || i == offset+2 {
+			continue
+		}
+		word := uint32(b[i])
+		if i+1 < len(b) {
+			word |= uint32(b[i+1]) << 8
+		}
+		sum += word
+		sum = sum&0xffff + sum>>16
+	}
+	return sum + uint32(len(b))
+}
+
+// alignUp rounds n up to a multiple of align, which must be a power of two.
+func alignUp(n, align uint32) uint32 {
+	return (n + align - 1) &^ (align - 1)
+}
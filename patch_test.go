@@ -0,0 +1,238 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeChecksum(t *testing.T) {
+	// The field at 4 is skipped and the length is added
+	assert.Equal(t, uint32(6+10), peChecksum([]byte{1, 0, 2, 0, 0xff, 0xff, 0xff, 0xff, 3, 0}, 4))
+	// Carries fold back into the low 16 bits
+	assert.Equal(t, uint32(3+4), peChecksum([]byte{0xff, 0xff, 0x03, 0x00}, 8))
+	// An odd length counts the last byte as a word
+	assert.Equal(t, uint32(0x0201+0x03+5), peChecksum([]byte{1, 2, 3, 0, 0}, 3))
+}
+
+// checkImage asserts that the headers of a patched image are consistent.
+func checkImage(t *testing.T, exe string) *pe.File {
+	t.Helper()
+
+	b, err := os.ReadFile(exe)
+	assert.NoError(t, err)
+	f, err := pe.NewFile(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sizeOfImage, sectionAlign, checkSum uint32
+	var dd pe.DataDirectory
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		sizeOfImage, sectionAlign, checkSum = oh.SizeOfImage, oh.SectionAlignment, oh.CheckSum
+		dd = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE]
+	case *pe.OptionalHeader64:
+		sizeOfImage, sectionAlign, checkSum = oh.SizeOfImage, oh.SectionAlignment, oh.CheckSum
+		dd = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE]
+	}
+
+	var end uint32
+	var rsrc *pe.Section
+	for _, s := range f.Sections {
+		assert.LessOrEqual(t, int(s.Offset+s.Size), len(b), "section %s", s.Name)
+		if e := s.VirtualAddress + virtualSize(s); e > end {
+			end = e
+		}
+		if s.Name == ".rsrc" {
+			rsrc = s
+		}
+	}
+	assert.Equal(t, alignUp(end, sectionAlign), sizeOfImage)
+
+	optOff := int(binary.LittleEndian.Uint32(b[peSignatureOffset:])) + 4 + coffFileHeaderSize
+	assert.Equal(t, peChecksum(b, optOff+optCheckSum), checkSum)
+	assert.NotZero(t, checkSum)
+
+	if assert.NotNil(t, rsrc) {
+		assert.Equal(t, rsrc.VirtualAddress, dd.VirtualAddress)
+		assert.Equal(t, rsrc.VirtualSize, dd.Size)
+	}
+	return f
+}
+
+func TestPatchExecutable(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "patch")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	for _, arch := range []string{"amd64", "386"} {
+		t.Run(arch, func(t *testing.T) {
+			dir := filepath.Join(tmpdir, arch)
+			exe := buildExecutable(t, dir, "", arch)
+			before, err := pe.Open(exe)
+			assert.NoError(t, err)
+			sections := len(before.Sections)
+			before.Close()
+
+			jsonBytes, err := os.ReadFile("testdata/json/cmd.json")
+			assert.NoError(t, err)
+			vi := &VersionInfo{}
+			assert.NoError(t, vi.ParseJSON(jsonBytes))
+			vi.IconPath = "testdata/resource/icon.ico"
+			vi.ManifestPath = "testdata/resource/goversioninfo.exe.manifest"
+			assert.NoError(t, vi.Build())
+			vi.Walk()
+
+			// Without resources a section is added
+			assert.NoError(t, vi.PatchExecutable(exe))
+			f := checkImage(t, exe)
+			assert.Len(t, f.Sections, sections+1)
+			f.Close()
+
+			er, err := ReadExecutable(exe)
+			assert.NoError(t, err)
+			want, err := vi.Resources()
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, want, er.Resources)
+
+			// The last section grows
+			vi.StringFileInfo.Comments = strings.Repeat("grow ", 2000)
+			assert.NoError(t, vi.Build())
+			vi.Walk()
+			assert.NoError(t, vi.PatchExecutable(exe))
+			f = checkImage(t, exe)
+			assert.Len(t, f.Sections, sections+1)
+			f.Close()
+			er, err = ReadExecutable(exe)
+			assert.NoError(t, err)
+			assert.Equal(t, vi.StringFileInfo.Comments, er.VersionInfo.StringFileInfo.Comments)
+
+			// Smaller resources are rewritten in place
+			info, err := os.Stat(exe)
+			assert.NoError(t, err)
+			vi.StringFileInfo.Comments = "shrink"
+			assert.NoError(t, vi.Build())
+			vi.Walk()
+			assert.NoError(t, vi.PatchExecutable(exe))
+			checkImage(t, exe).Close()
+			after, err := os.Stat(exe)
+			assert.NoError(t, err)
+			assert.Equal(t, info.Size(), after.Size())
+			er, err = ReadExecutable(exe)
+			assert.NoError(t, err)
+			assert.Equal(t, "shrink", er.VersionInfo.StringFileInfo.Comments)
+			assert.Len(t, er.Icons, 2)
+			assert.NotNil(t, er.Manifest)
+		})
+	}
+}
+
+func TestPatchExecutableKeepsResources(t *testing.T) {
+	jsonBytes, err := os.ReadFile("testdata/json/cmd.json")
+	assert.NoError(t, err)
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON(jsonBytes))
+	vi.IconPath = "testdata/resource/icon.ico"
+	vi.ManifestPath = "testdata/resource/goversioninfo.exe.manifest"
+	assert.NoError(t, vi.Build())
+	vi.Walk()
+
+	tmpdir, err := os.MkdirTemp("", "patch")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	syso := filepath.Join(tmpdir, "resource.syso")
+	assert.NoError(t, vi.WriteSyso(syso, "amd64"))
+	exe := buildExecutable(t, tmpdir, syso, "amd64")
+
+	// Data after the sections is kept, a signature is dropped
+	b, err := os.ReadFile(exe)
+	assert.NoError(t, err)
+	f, err := pe.NewFile(bytes.NewReader(b))
+	assert.NoError(t, err)
+	certDir := int(binary.LittleEndian.Uint32(b[peSignatureOffset:])) + 4 + coffFileHeaderSize +
+		optDataDirectory64 + 8*pe.IMAGE_DIRECTORY_ENTRY_SECURITY
+	f.Close()
+	b = append(b, "overlay!"...)
+	binary.LittleEndian.PutUint32(b[certDir:], uint32(len(b)))
+	binary.LittleEndian.PutUint32(b[certDir+4:], 16)
+	b = append(b, make([]byte, 16)...)
+	assert.NoError(t, os.WriteFile(exe, b, 0755))
+
+	// Only the version changes, so the icons and the manifest stay
+	cfg := NewCLIConfig()
+	cfg.ConfigFile = ""
+	cfg.PatchFile = exe
+	cfg.VerBuild = 9
+	cfg.Comment = strings.Repeat("grow ", 2000)
+	assert.NoError(t, RunCLI(cfg))
+
+	checkImage(t, exe).Close()
+	b, err = os.ReadFile(exe)
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasSuffix(b, []byte("overlay!")))
+	assert.Zero(t, binary.LittleEndian.Uint64(b[certDir:]))
+
+	er, err := ReadExecutable(exe)
+	assert.NoError(t, err)
+	assert.Equal(t, FileVersion{6, 3, 9600, 9}, er.VersionInfo.FixedFileInfo.FileVersion)
+	assert.Equal(t, vi.StringFileInfo.CompanyName, er.VersionInfo.StringFileInfo.CompanyName)
+	assert.Len(t, er.Icons, 2)
+	manifest, err := os.ReadFile(vi.ManifestPath)
+	assert.NoError(t, err)
+	assert.Equal(t, manifest, er.Manifest)
+
+	// A new icon replaces all of the old ones
+	cfg.IconPath = "testdata/resource/icon.ico"
+	cfg.ApplicationIconPath = "testdata/resource/icon.ico"
+	assert.NoError(t, RunCLI(cfg))
+	er, err = ReadExecutable(exe)
+	assert.NoError(t, err)
+	groups := 0
+	for _, r := range er.Resources {
+		if r.Type == (ResourceID{ID: rtGroupIcon}) {
+			groups++
+		}
+	}
+	assert.Equal(t, 2, groups)
+}
+
+func TestPatchResources(t *testing.T) {
+	existing := Resources{
+		{Type: ResourceID{ID: rtVersion}, Name: ResourceID{ID: 1}, LangID: 0, Data: []byte("old")},
+		{Type: ResourceID{ID: rtIcon}, Name: ResourceID{ID: 7}, Data: []byte("old")},
+		{Type: ResourceID{ID: rtGroupIcon}, Name: ResourceID{Name: "MAINICON"}, Data: []byte("old")},
+		{Type: ResourceID{ID: rtManifest}, Name: ResourceID{ID: 2}, Data: []byte("old")},
+		{Type: ResourceID{ID: 10}, Name: ResourceID{ID: 1}, Data: []byte("kept")},
+		{Type: ResourceID{ID: 10}, Name: ResourceID{ID: 2}, Data: []byte("old")},
+	}
+	var res Resources
+	res.add(rtVersion, 1, []byte("new"))
+	res.add(rtManifest, 1, []byte("new"))
+	res.add(10, 2, []byte("new"))
+
+	assert.Equal(t, Resources{res[0], res[1], res[2], existing[1], existing[2], existing[4]},
+		patchResources(existing, res))
+
+	res.add(rtIcon, 1, []byte("new"))
+	res.add(rtGroupIcon, 2, []byte("new"))
+	assert.Equal(t, append(res[:5:5], existing[4]), patchResources(existing, res))
+}
+
+func TestPatchExecutableNotPE(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "patch")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	name := filepath.Join(tmpdir, "cmd.hex")
+	assert.NoError(t, os.WriteFile(name, []byte("not an executable"), 0644))
+	assert.Error(t, Resources{}.WriteExecutable(name))
+	assert.Error(t, (&VersionInfo{}).PatchExecutable(name))
+}
@@ -0,0 +1,1310 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// *****************************************************************************
+// Resource Scripts
+// *****************************************************************************
+
+/*
+Resource script syntax
+https://learn.microsoft.com/en-us/windows/win32/menurc/about-resource-files
+
+Only the statements this package can build are understood: VERSIONINFO, ICON,
+RT_MANIFEST, STRINGTABLE, and RCDATA, HTML and user-defined resources that
+name a file. The preprocessor handles object-like #define and #undef,
+#ifdef, #ifndef, #else and #endif. #include and #pragma lines are skipped, so
+symbols from headers like winver.h are predefined.
+*/
+
+// rcSymbols are the constants of winver.h, winuser.h and winnt.h that resource
+// scripts commonly use.
+var rcSymbols = func() map[string]uint32 {
+	m := map[string]uint32{
+		"VS_VERSION_INFO":      1,
+		"VS_FFI_FILEFLAGSMASK": fileFlagsMaskAll,
+
+		"RT_MANIFEST":                         rtManifest,
+		"CREATEPROCESS_MANIFEST_RESOURCE_ID":  1,
+		"ISOLATIONAWARE_MANIFEST_RESOURCE_ID": 2,
+		"IDI_APPLICATION":                     32512,
+
+		"LANG_NEUTRAL":       0x00,
+		"LANG_ENGLISH":       0x09,
+		"SUBLANG_NEUTRAL":    0x00,
+		"SUBLANG_DEFAULT":    0x01,
+		"SUBLANG_ENGLISH_US": 0x01,
+	}
+	for _, symbols := range [][]symbol{fileFlagSymbols, fileOSSymbols, fileTypeSymbols, driverSubTypeSymbols, fontSubTypeSymbols} {
+		for _, s := range symbols {
+			m[s.name] = s.value
+		}
+	}
+	return m
+}()
+
+// rcStatements are the resource types of rc.exe this package can not build.
+// Other identifiers in the place of the type are user-defined types.
+var rcStatements = map[string]bool{
+	"ACCELERATORS": true, "BITMAP": true, "CURSOR": true, "DIALOG": true, "DIALOGEX": true,
+	"FONT": true, "MENU": true, "MENUEX": true, "MESSAGETABLE": true, "STRINGTABLE": true,
+	"TEXTINCLUDE": true, "TOOLBAR": true, "DLGINCLUDE": true, "PLUGPLAY": true, "VXD": true,
+	"ANICURSOR": true, "ANIICON": true, "DESIGNINFO": true,
+}
+
+// rcMemoryFlags are the obsolete load options that may follow the type.
+var rcMemoryFlags = map[string]bool{
+	"PRELOAD": true, "LOADONCALL": true, "FIXED": true, "MOVEABLE": true,
+	"DISCARDABLE": true, "PURE": true, "IMPURE": true, "SHARED": true, "NONSHARED": true,
+}
+
+// RCError describes a problem in a resource script.
+type RCError struct {
+	Line int
+	Msg  string
+}
+
+func (e *RCError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// ParseRC parses a resource script with a VERSIONINFO statement and optional
+// ICON, RT_MANIFEST, STRINGTABLE, RCDATA, HTML and user-defined statements,
+// like testdata/rc/versioninfo.rc. File names are used as they are written,
+// like the paths of a JSON file. The icon with ID IDI_APPLICATION (32512)
+// becomes the application icon, the others keep their IDs or names in Icons.
+// Icons, string tables and RCDATA, HTML and user-defined resources keep the
+// language of the last LANGUAGE statement. Scripts are read as UTF-8, or
+// UTF-16 when they start with a byte order mark.
+func (vi *VersionInfo) ParseRC(rcBytes []byte) error {
+	toks, err := lexRC(decodeRCText(rcBytes))
+	if err != nil {
+		return err
+	}
+	p := &rcParser{toks: toks, vi: vi, lang: LngUSEnglish}
+	return p.parse()
+}
+
+// decodeRCText returns the script as a string.
+func decodeRCText(b []byte) string {
+	switch {
+	case bytes.HasPrefix(b, []byte{0xff, 0xfe}):
+		u16 := make([]uint16, (len(b)-2)/2)
+		for i := range u16 {
+			u16[i] = binary.LittleEndian.Uint16(b[2+2*i:])
+		}
+		return string(utf16.Decode(u16))
+	case bytes.HasPrefix(b, []byte{0xef, 0xbb, 0xbf}):
+		return string(b[3:])
+	}
+	return string(b)
+}
+
+type rcTokenKind int
+
+const (
+	rcEOF rcTokenKind = iota
+	rcIdent
+	rcNumber
+	rcString
+	rcPunct
+)
+
+type rcToken struct {
+	kind rcTokenKind
+	text string // identifier, punctuation or the decoded string
+	num  uint32
+	line int
+}
+
+func (t rcToken) String() string {
+	switch t.kind {
+	case rcEOF:
+		return "end of file"
+	case rcString:
+		return strconv.Quote(t.text)
+	case rcNumber:
+		return strconv.FormatUint(uint64(t.num), 10)
+	}
+	return t.text
+}
+
+// rcLexer turns a script into tokens, running the preprocessor on the way.
+type rcLexer struct {
+	src     string
+	pos     int
+	line    int
+	defines map[string][]rcToken
+	cond    []bool // one entry per open #if, true when its lines are used
+}
+
+// lexRC returns the tokens of src with macros expanded.
+func lexRC(src string) ([]rcToken, error) {
+	l := &rcLexer{src: src, line: 1, defines: map[string][]rcToken{}}
+	toks, err := l.run(true)
+	if err != nil {
+		return nil, err
+	}
+	if len(l.cond) != 0 {
+		return nil, &RCError{Line: l.line, Msg: "missing #endif"}
+	}
+	return toks, nil
+}
+
+func (l *rcLexer) errorf(format string, args ...interface{}) error {
+	return &RCError{Line: l.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (l *rcLexer) active() bool {
+	for _, c := range l.cond {
+		if !c {
+			return false
+		}
+	}
+	return true
+}
+
+// run lexes the rest of the input. Directives are only handled when
+// directives is set, which is not the case for the body of a #define.
+func (l *rcLexer) run(directives bool) ([]rcToken, error) {
+	var toks []rcToken
+	bol := true
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+			bol = true
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			l.pos++
+			continue
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+			continue
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return nil, l.errorf("comment is not closed")
+			}
+			l.line += strings.Count(l.src[l.pos:l.pos+2+end], "\n")
+			l.pos += end + 4
+			continue
+		case c == '#' && bol && directives:
+			if err := l.directive(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		bol = false
+
+		if !l.active() {
+			// Skipped lines only need to be lexed for comments and strings
+			start := l.pos
+			if _, err := l.token(); err != nil {
+				l.pos = start + 1
+			}
+			continue
+		}
+		tok, err := l.token()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind == rcIdent {
+			expanded, err := l.expand(tok, nil)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, expanded...)
+			continue
+		}
+		toks = append(toks, tok)
+	}
+	return toks, nil
+}
+
+// expand replaces a macro by its definition, which may use other macros.
+func (l *rcLexer) expand(tok rcToken, seen []string) ([]rcToken, error) {
+	def, ok := l.defines[tok.text]
+	if !ok {
+		return []rcToken{tok}, nil
+	}
+	for _, s := range seen {
+		if s == tok.text {
+			return nil, &RCError{Line: tok.line, Msg: fmt.Sprintf("macro %s refers to itself", tok.text)}
+		}
+	}
+	var out []rcToken
+	for _, t := range def {
+		t.line = tok.line
+		if t.kind != rcIdent {
+			out = append(out, t)
+			continue
+		}
+		expanded, err := l.expand(t, append(seen, tok.text))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, expanded...)
+	}
+	return out, nil
+}
+
+// directive handles a preprocessor line, including lines continued with a
+// backslash.
+func (l *rcLexer) directive() error {
+	line := l.line
+	start := l.pos + 1
+	for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+		if strings.HasPrefix(l.src[l.pos:], "\\\n") || strings.HasPrefix(l.src[l.pos:], "\\\r\n") {
+			l.line++
+			l.pos = strings.IndexByte(l.src[l.pos:], '\n') + l.pos
+		}
+		l.pos++
+	}
+	text := strings.NewReplacer("\\\r\n", " ", "\\\n", " ").Replace(l.src[start:l.pos])
+	text = strings.TrimSpace(text)
+
+	name := text
+	rest := ""
+	if i := strings.IndexAny(text, " \t"); i >= 0 {
+		name, rest = text[:i], strings.TrimSpace(text[i:])
+	}
+
+	switch name {
+	case "ifdef", "ifndef":
+		_, defined := l.defines[rcMacroName(rest)]
+		l.cond = append(l.cond, defined == (name == "ifdef"))
+		return nil
+	case "if", "elif":
+		// Conditions inside skipped lines are skipped as a whole
+		if l.active() {
+			return &RCError{Line: line, Msg: fmt.Sprintf("preprocessor directive #%s is not supported", name)}
+		}
+		if name == "if" {
+			l.cond = append(l.cond, false)
+		}
+		return nil
+	case "else":
+		if len(l.cond) == 0 {
+			return &RCError{Line: line, Msg: "#else without #ifdef"}
+		}
+		l.cond[len(l.cond)-1] = !l.cond[len(l.cond)-1]
+		return nil
+	case "endif":
+		if len(l.cond) == 0 {
+			return &RCError{Line: line, Msg: "#endif without #ifdef"}
+		}
+		l.cond = l.cond[:len(l.cond)-1]
+		return nil
+	}
+
+	if !l.active() {
+		return nil
+	}
+
+	switch name {
+	case "define":
+		macro := rcMacroName(rest)
+		if macro == "" {
+			return &RCError{Line: line, Msg: "#define without a name"}
+		}
+		body := strings.TrimSpace(rest[len(macro):])
+		if strings.HasPrefix(rest[len(macro):], "(") {
+			return &RCError{Line: line, Msg: fmt.Sprintf("function-like macro %s is not supported", macro)}
+		}
+		sub := &rcLexer{src: body, line: line}
+		def, err := sub.run(false)
+		if err != nil {
+			return err
+		}
+		l.defines[macro] = def
+	case "undef":
+		delete(l.defines, rcMacroName(rest))
+	case "include", "pragma", "":
+		// Headers are not read, and code pages other than UTF-8 are not
+		// supported.
+	default:
+		return &RCError{Line: line, Msg: fmt.Sprintf("preprocessor directive #%s is not supported", name)}
+	}
+	return nil
+}
+
+// rcMacroName returns the identifier at the start of s.
+func rcMacroName(s string) string {
+	i := 0
+	for i < len(s) && isRCIdentChar(s[i], i == 0) {
+		i++
+	}
+	return s[:i]
+}
+
+func isRCIdentChar(c byte, first bool) bool {
+	switch {
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= utf8.RuneSelf:
+		return true
+	case c >= '0' && c <= '9' || c == '.':
+		// A dot lets file names like icon.ico go without quotes
+		return !first
+	}
+	return false
+}
+
+// token reads a single identifier, number, string or punctuation mark.
+func (l *rcLexer) token() (rcToken, error) {
+	tok := rcToken{line: l.line}
+	c := l.src[l.pos]
+
+	switch {
+	case c == '"' || (c == 'L' || c == 'l') && strings.HasPrefix(l.src[l.pos+1:], `"`):
+		if c != '"' {
+			l.pos++
+		}
+		s, err := l.str()
+		if err != nil {
+			return tok, err
+		}
+		tok.kind, tok.text = rcString, s
+	case isRCIdentChar(c, true):
+		start := l.pos
+		for l.pos < len(l.src) && isRCIdentChar(l.src[l.pos], false) {
+			l.pos++
+		}
+		tok.kind, tok.text = rcIdent, l.src[start:l.pos]
+	case c >= '0' && c <= '9':
+		start := l.pos
+		for l.pos < len(l.src) && isRCIdentChar(l.src[l.pos], false) {
+			l.pos++
+		}
+		tok.kind, tok.text = rcNumber, l.src[start:l.pos]
+		n, err := parseRCNumber(tok.text)
+		if err != nil {
+			return tok, l.errorf("%v", err)
+		}
+		tok.num = n
+	case strings.IndexByte(",{}()|&+-~", c) >= 0:
+		l.pos++
+		tok.kind, tok.text = rcPunct, string(c)
+	default:
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+		return tok, l.errorf("unexpected character %q", r)
+	}
+	return tok, nil
+}
+
+// parseRCNumber parses a decimal or 0x prefixed hex number with an optional
+// L or U suffix.
+func parseRCNumber(s string) (uint32, error) {
+	digits := strings.TrimRight(s, "LlUu")
+	base := 10
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		digits, base = digits[2:], 16
+	}
+	n, err := strconv.ParseUint(digits, base, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %s", s)
+	}
+	return uint32(n), nil
+}
+
+// str reads a quoted string. Quotes are escaped by doubling them or with a
+// backslash, and the C escapes \n, \r, \t, \a, \\, \xhh and \ooo are known.
+func (l *rcLexer) str() (string, error) {
+	var b strings.Builder
+	l.pos++ // opening quote
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+			return "", l.errorf("string is not closed")
+		}
+		c := l.src[l.pos]
+		l.pos++
+		switch c {
+		case '"':
+			if l.pos < len(l.src) && l.src[l.pos] == '"' {
+				b.WriteByte('"')
+				l.pos++
+				continue
+			}
+			return b.String(), nil
+		case '\\':
+			if err := l.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (l *rcLexer) escape(b *strings.Builder) error {
+	if l.pos >= len(l.src) {
+		return l.errorf("string is not closed")
+	}
+	c := l.src[l.pos]
+	l.pos++
+	switch c {
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case 't':
+		b.WriteByte('\t')
+	case 'a':
+		b.WriteByte('\a')
+	case '\\', '"', '\'':
+		b.WriteByte(c)
+	case 'x', 'X':
+		start := l.pos
+		for l.pos < len(l.src) && l.pos-start < 4 && strings.IndexByte("0123456789abcdefABCDEF", l.src[l.pos]) >= 0 {
+			l.pos++
+		}
+		if l.pos == start {
+			return l.errorf(`\x escape without hex digits`)
+		}
+		n, _ := strconv.ParseUint(l.src[start:l.pos], 16, 32)
+		b.WriteRune(rune(n))
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		start := l.pos - 1
+		for l.pos < len(l.src) && l.pos-start < 3 && l.src[l.pos] >= '0' && l.src[l.pos] <= '7' {
+			l.pos++
+		}
+		n, _ := strconv.ParseUint(l.src[start:l.pos], 8, 32)
+		b.WriteRune(rune(n))
+	default:
+		// Unknown escapes keep the backslash, like rc.exe does
+		b.WriteByte('\\')
+		b.WriteByte(c)
+	}
+	return nil
+}
+
+// rcParser builds a VersionInfo from the tokens of a script.
+type rcParser struct {
+	toks []rcToken
+	pos  int
+	vi   *VersionInfo
+
+	hasVersion bool
+	tables     []StringTable
+	lang       LangID
+}
+
+func (p *rcParser) peek() rcToken {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	line := 1
+	if len(p.toks) > 0 {
+		line = p.toks[len(p.toks)-1].line
+	}
+	return rcToken{kind: rcEOF, line: line}
+}
+
+func (p *rcParser) next() rcToken {
+	t := p.peek()
+	if p.pos < len(p.toks) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *rcParser) errorf(t rcToken, format string, args ...interface{}) error {
+	return &RCError{Line: t.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// keyword reports whether t is one of the keywords, which are not case
+// sensitive.
+func (t rcToken) keyword(words ...string) bool {
+	if t.kind != rcIdent {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t rcToken) punct(s string) bool {
+	return t.kind == rcPunct && t.text == s
+}
+
+func (p *rcParser) parse() error {
+	for p.peek().kind != rcEOF {
+		if p.peek().keyword("STRINGTABLE") {
+			if err := p.stringTable(); err != nil {
+				return err
+			}
+			continue
+		}
+		if p.peek().keyword("LANGUAGE") {
+			// Only string tables and file resources keep the language, the
+			// others are written in the default one
+			p.next()
+			primary, err := p.expr()
+			if err != nil {
+				return err
+			}
+			if err := p.expect(","); err != nil {
+				return err
+			}
+			sub, err := p.expr()
+			if err != nil {
+				return err
+			}
+			p.lang = rcLangID(primary, sub)
+			continue
+		}
+
+		name, err := p.resourceName()
+		if err != nil {
+			return err
+		}
+
+		typ := p.next()
+		switch {
+		case typ.keyword("VERSIONINFO"):
+			err = p.versionInfo(typ)
+		case typ.keyword("ICON"):
+			err = p.icon(name)
+		case typ.keyword("RT_MANIFEST") || typ.kind == rcNumber && typ.num == rtManifest:
+			err = p.manifest(typ)
+		case typ.keyword("RCDATA"):
+			err = p.fileResource(name, ResourceID{ID: rtRCData})
+		case typ.keyword("HTML"):
+			err = p.fileResource(name, ResourceID{ID: rtHTML})
+		case typ.kind == rcNumber && typ.num > 0xffff:
+			err = p.errorf(typ, "resource type %d does not fit in 16 bits", typ.num)
+		case typ.kind == rcNumber:
+			err = p.fileResource(name, ResourceID{ID: uint16(typ.num)})
+		case typ.kind == rcString || typ.kind == rcIdent && !rcStatements[strings.ToUpper(typ.text)]:
+			err = p.fileResource(name, ResourceID{Name: typ.text})
+		case typ.kind == rcEOF:
+			err = p.errorf(typ, "resource %s has no type", name)
+		default:
+			err = p.errorf(typ, "resource type %s is not supported", typ)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	p.vi.setStringTables(p.tables)
+	return nil
+}
+
+// resourceName reads the ID or name that starts a resource statement.
+// Identifiers that are not defined are names, like rc.exe handles them.
+func (p *rcParser) resourceName() (ResourceID, error) {
+	t := p.peek()
+	switch {
+	case t.kind == rcString:
+		p.next()
+		return ResourceID{Name: t.text}, nil
+	case t.kind == rcIdent:
+		if n, ok := rcSymbols[t.text]; ok {
+			p.next()
+			return ResourceID{ID: uint16(n)}, nil
+		}
+		p.next()
+		return ResourceID{Name: t.text}, nil
+	}
+	n, err := p.expr()
+	if err != nil {
+		return ResourceID{}, err
+	}
+	if n > 0xffff {
+		return ResourceID{}, p.errorf(t, "resource ID %d does not fit in 16 bits", n)
+	}
+	return ResourceID{ID: uint16(n)}, nil
+}
+
+func (p *rcParser) expect(punct string) error {
+	t := p.next()
+	if !t.punct(punct) {
+		return p.errorf(t, "expected %q, found %s", punct, t)
+	}
+	return nil
+}
+
+// begin reads BEGIN or {.
+func (p *rcParser) begin() error {
+	t := p.next()
+	if !t.keyword("BEGIN") && !t.punct("{") {
+		return p.errorf(t, "expected BEGIN, found %s", t)
+	}
+	return nil
+}
+
+// end reports whether the next token is END or }, and reads it if so.
+func (p *rcParser) end() bool {
+	t := p.peek()
+	if t.keyword("END") || t.punct("}") {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// fileName reads the file of an ICON or RT_MANIFEST statement.
+func (p *rcParser) fileName() (string, error) {
+	for p.peek().kind == rcIdent && rcMemoryFlags[strings.ToUpper(p.peek().text)] {
+		p.next()
+	}
+	t := p.next()
+	if t.kind != rcString && t.kind != rcIdent {
+		return "", p.errorf(t, "expected a file name, found %s", t)
+	}
+	return t.text, nil
+}
+
+func (p *rcParser) icon(name ResourceID) error {
+	file, err := p.fileName()
+	if err != nil {
+		return err
+	}
+	if name == (ResourceID{ID: 32512}) {
+		p.vi.ApplicationIconPath = file
+		return nil
+	}
+	ir := IconResource{Path: file, ID: name}
+	if p.lang != LngUSEnglish {
+		lang := p.lang
+		ir.LangID = &lang
+	}
+	p.vi.Icons = append(p.vi.Icons, ir)
+	return nil
+}
+
+func (p *rcParser) fileResource(name, typ ResourceID) error {
+	file, err := p.fileName()
+	if err != nil {
+		return err
+	}
+	fr := FileResource{Type: typ, ID: name, Path: file}
+	if p.lang != LngUSEnglish {
+		lang := p.lang
+		fr.LangID = &lang
+	}
+	p.vi.FileResources = append(p.vi.FileResources, fr)
+	return nil
+}
+
+// rcLangID joins the primary and sub language of a LANGUAGE statement.
+func rcLangID(primary, sub uint32) LangID {
+	return LangID(primary&0x3ff | sub<<10)
+}
+
+// stringTable reads a STRINGTABLE statement. Its LANGUAGE only applies to the
+// table itself.
+func (p *rcParser) stringTable() error {
+	start := p.next()
+	lang := p.lang
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == rcIdent && rcMemoryFlags[strings.ToUpper(t.text)]:
+			p.next()
+			continue
+		case t.keyword("LANGUAGE"):
+			p.next()
+			primary, err := p.expr()
+			if err != nil {
+				return err
+			}
+			if err := p.expect(","); err != nil {
+				return err
+			}
+			sub, err := p.expr()
+			if err != nil {
+				return err
+			}
+			lang = rcLangID(primary, sub)
+			continue
+		case t.keyword("CHARACTERISTICS", "VERSION"):
+			p.next()
+			if _, err := p.expr(); err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+	if err := p.begin(); err != nil {
+		return err
+	}
+
+	var strs map[uint16]string
+	for _, st := range p.vi.StringResources {
+		if st.lang() == lang {
+			strs = st.Strings
+		}
+	}
+	if strs == nil {
+		st := StringResource{Strings: map[uint16]string{}}
+		if lang != LngUSEnglish {
+			st.LangID = &lang
+		}
+		p.vi.StringResources = append(p.vi.StringResources, st)
+		strs = st.Strings
+	}
+
+	for !p.end() {
+		t := p.peek()
+		if t.kind == rcEOF {
+			return p.errorf(start, "STRINGTABLE has no END")
+		}
+		id, err := p.expr()
+		if err != nil {
+			return err
+		}
+		if id > 0xffff {
+			return p.errorf(t, "string ID %d does not fit in 16 bits", id)
+		}
+		if p.peek().punct(",") {
+			p.next()
+		}
+		s := p.next()
+		if s.kind != rcString {
+			return p.errorf(s, "expected a string, found %s", s)
+		}
+		if _, ok := strs[uint16(id)]; ok {
+			return p.errorf(t, "string %d is defined twice", id)
+		}
+		strs[uint16(id)] = s.text
+	}
+	return nil
+}
+
+func (p *rcParser) manifest(typ rcToken) error {
+	file, err := p.fileName()
+	if err != nil {
+		return err
+	}
+	if p.vi.ManifestPath != "" {
+		return p.errorf(typ, "only one RT_MANIFEST is supported")
+	}
+	p.vi.ManifestPath = file
+	return nil
+}
+
+// expr evaluates a numeric expression with the unary operators - and ~, and
+// the binary operators +, -, & and |.
+func (p *rcParser) expr() (uint32, error) {
+	v, err := p.andExpr()
+	for err == nil && p.peek().punct("|") {
+		p.next()
+		var r uint32
+		r, err = p.andExpr()
+		v |= r
+	}
+	return v, err
+}
+
+func (p *rcParser) andExpr() (uint32, error) {
+	v, err := p.addExpr()
+	for err == nil && p.peek().punct("&") {
+		p.next()
+		var r uint32
+		r, err = p.addExpr()
+		v &= r
+	}
+	return v, err
+}
+
+func (p *rcParser) addExpr() (uint32, error) {
+	v, err := p.unary()
+	for err == nil && (p.peek().punct("+") || p.peek().punct("-")) {
+		op := p.next()
+		var r uint32
+		r, err = p.unary()
+		if op.text == "+" {
+			v += r
+		} else {
+			v -= r
+		}
+	}
+	return v, err
+}
+
+func (p *rcParser) unary() (uint32, error) {
+	t := p.next()
+	switch {
+	case t.kind == rcNumber:
+		return t.num, nil
+	case t.keyword("NOT") || t.punct("~"):
+		v, err := p.unary()
+		return ^v, err
+	case t.punct("-"):
+		v, err := p.unary()
+		return -v, err
+	case t.punct("+"):
+		return p.unary()
+	case t.punct("("):
+		v, err := p.expr()
+		if err != nil {
+			return 0, err
+		}
+		return v, p.expect(")")
+	case t.kind == rcIdent:
+		if v, ok := rcSymbols[t.text]; ok {
+			return v, nil
+		}
+		return 0, p.errorf(t, "undefined symbol %s", t.text)
+	}
+	return 0, p.errorf(t, "expected a number, found %s", t)
+}
+
+// versionInfo reads the fixed-info statements and the blocks of VERSIONINFO.
+func (p *rcParser) versionInfo(typ rcToken) error {
+	if p.hasVersion {
+		return p.errorf(typ, "only one VERSIONINFO is supported")
+	}
+	p.hasVersion = true
+
+	ff := &p.vi.FixedFileInfo
+	for {
+		t := p.peek()
+		if t.keyword("BEGIN") || t.punct("{") {
+			break
+		}
+		p.next()
+		var err error
+		switch {
+		case t.keyword("FILEVERSION"):
+			ff.FileVersion, err = p.version()
+		case t.keyword("PRODUCTVERSION"):
+			ff.ProductVersion, err = p.version()
+		case t.keyword("FILEFLAGSMASK"):
+			ff.FileFlagsMask, err = p.hexExpr()
+		case t.keyword("FILEFLAGS"):
+			ff.FileFlags, err = p.hexExpr()
+		case t.keyword("FILEOS"):
+			ff.FileOS, err = p.hexExpr()
+		case t.keyword("FILETYPE"):
+			ff.FileType, err = p.hexExpr()
+		case t.keyword("FILESUBTYPE"):
+			ff.FileSubType, err = p.hexExpr()
+		default:
+			err = p.errorf(t, "unknown VERSIONINFO statement %s", t)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := p.begin(); err != nil {
+		return err
+	}
+	for !p.end() {
+		t := p.next()
+		if !t.keyword("BLOCK") {
+			return p.errorf(t, "expected BLOCK or END, found %s", t)
+		}
+		name := p.next()
+		var err error
+		switch {
+		case name.kind == rcString && strings.EqualFold(name.text, "StringFileInfo"):
+			err = p.stringFileInfo()
+		case name.kind == rcString && strings.EqualFold(name.text, "VarFileInfo"):
+			err = p.varFileInfo()
+		default:
+			err = p.errorf(name, "unknown block %s, expected StringFileInfo or VarFileInfo", name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// version reads up to four comma separated parts of a version.
+func (p *rcParser) version() (FileVersion, error) {
+	var parts [4]int
+	for i := range parts {
+		t := p.peek()
+		v, err := p.expr()
+		if err != nil {
+			return FileVersion{}, err
+		}
+		if v > 0xffff {
+			return FileVersion{}, p.errorf(t, "version part %d does not fit in 16 bits", v)
+		}
+		parts[i] = int(v)
+		if !p.peek().punct(",") {
+			break
+		}
+		p.next()
+	}
+	return FileVersion{Major: parts[0], Minor: parts[1], Patch: parts[2], Build: parts[3]}, nil
+}
+
+// hexExpr reads an expression in the hex form of FixedFileInfo fields.
+func (p *rcParser) hexExpr() (string, error) {
+	v, err := p.expr()
+	return fmt.Sprintf("%02x", v), err
+}
+
+func (p *rcParser) stringFileInfo() error {
+	if err := p.begin(); err != nil {
+		return err
+	}
+	for !p.end() {
+		t := p.next()
+		if !t.keyword("BLOCK") {
+			return p.errorf(t, "expected BLOCK or END, found %s", t)
+		}
+		key := p.next()
+		if key.kind != rcString {
+			return p.errorf(key, "expected a string table key, found %s", key)
+		}
+		tr, err := parseTranslationString(key.text)
+		if err != nil {
+			return p.errorf(key, "string table key: %v", err)
+		}
+		st := StringTable{Translation: tr}
+
+		if err := p.begin(); err != nil {
+			return err
+		}
+		for !p.end() {
+			t := p.next()
+			if !t.keyword("VALUE") {
+				return p.errorf(t, "expected VALUE or END, found %s", t)
+			}
+			name := p.next()
+			if name.kind != rcString {
+				return p.errorf(name, "expected a string name, found %s", name)
+			}
+			if err := p.expect(","); err != nil {
+				return err
+			}
+
+			// Adjacent strings are joined and a trailing \0 is not part of
+			// the value.
+			var value strings.Builder
+			for p.peek().kind == rcString {
+				value.WriteString(p.next().text)
+			}
+			if value.Len() == 0 {
+				return p.errorf(p.peek(), "expected a string value for %s, found %s", name, p.peek())
+			}
+			st.StringFileInfo.Set(name.text, strings.TrimRight(value.String(), "\x00"))
+		}
+		p.tables = append(p.tables, st)
+	}
+	return nil
+}
+
+func (p *rcParser) varFileInfo() error {
+	if err := p.begin(); err != nil {
+		return err
+	}
+	for !p.end() {
+		t := p.next()
+		if !t.keyword("VALUE") {
+			return p.errorf(t, "expected VALUE or END, found %s", t)
+		}
+		name := p.next()
+		if name.kind != rcString || !strings.EqualFold(name.text, "Translation") {
+			return p.errorf(name, "unknown VarFileInfo value %s, expected Translation", name)
+		}
+
+		var words []uint16
+		for p.peek().punct(",") {
+			p.next()
+			w := p.peek()
+			v, err := p.expr()
+			if err != nil {
+				return err
+			}
+			if v > 0xffff {
+				return p.errorf(w, "translation part %d does not fit in 16 bits", v)
+			}
+			words = append(words, uint16(v))
+		}
+		if len(words) == 0 || len(words)%2 != 0 {
+			return p.errorf(name, "Translation needs pairs of language and charset IDs, %d given", len(words))
+		}
+		for i := 0; i < len(words); i += 2 {
+			p.vi.VarFileInfo.Translation = append(p.vi.VarFileInfo.Translation,
+				Translation{LangID: LangID(words[i]), CharsetID: CharsetID(words[i+1])})
+		}
+	}
+	return nil
+}
+
+// WriteRC creates a resource script from the version info with its icons and
+// manifest, in the style of testdata/rc/versioninfo.rc. The script can be
+// compiled with rc.exe or windres, or read back with ParseRC. Icons made from
+// PNG files or directories are saved as .ico files next to the script, which
+// refers to those instead.
+func (vi *VersionInfo) WriteRC(filename string) error {
+	conv, err := vi.rcIcons(filename)
+	if err != nil {
+		return err
+	}
+	b, err := conv.rc()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b, 0644)
+}
+
+// rcIcons returns a copy of the version info whose PNG icons are replaced by
+// .ico files written next to the script filename, like resource_app.ico for
+// app.png.
+func (vi *VersionInfo) rcIcons(filename string) (*VersionInfo, error) {
+	prefix := strings.TrimSuffix(filename, filepath.Ext(filename)) + "_"
+	converted := map[string]string{}
+	convert := func(icon string) (string, error) {
+		if icon == "" || !isPNGIcon(icon) {
+			return icon, nil
+		}
+		if ico, ok := converted[icon]; ok {
+			return ico, nil
+		}
+		images, err := readPNGIcon(icon)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", icon, err)
+		}
+		base := filepath.Base(filepath.Clean(icon))
+		ico := prefix + strings.TrimSuffix(base, filepath.Ext(base)) + ".ico"
+		if err := os.WriteFile(ico, icoFile(images), 0644); err != nil {
+			return "", err
+		}
+		converted[icon] = ico
+		return ico, nil
+	}
+
+	conv := *vi
+	var err error
+	if conv.IconPath != "" {
+		icons := strings.Split(conv.IconPath, ",")
+		for i := range icons {
+			if icons[i], err = convert(icons[i]); err != nil {
+				return nil, err
+			}
+		}
+		conv.IconPath = strings.Join(icons, ",")
+	}
+	if conv.ApplicationIconPath, err = convert(conv.ApplicationIconPath); err != nil {
+		return nil, err
+	}
+	conv.Icons = append([]IconResource(nil), vi.Icons...)
+	for i := range conv.Icons {
+		if conv.Icons[i].Path, err = convert(conv.Icons[i].Path); err != nil {
+			return nil, err
+		}
+	}
+	return &conv, nil
+}
+
+// rc returns the resource script of the version info.
+func (vi *VersionInfo) rc() ([]byte, error) {
+	if vi.ResPath != "" {
+		return nil, errors.New("the resources of a .res file can not be written to a resource script")
+	}
+	if vi.Manifest != nil {
+		return nil, errors.New("a generated manifest can not be written to a resource script, save ManifestXML to a file and set ManifestPath")
+	}
+	if vi.MessageTablePath != "" {
+		return nil, errors.New("a message table can not be written to a resource script, compile it with mc.exe and include the .rc file it writes")
+	}
+
+	var b bytes.Buffer
+	b.WriteString("#include <winver.h>\n")
+	b.WriteString("#pragma code_page(65001)\n\n")
+	if vi.ManifestPath != "" {
+		b.WriteString("#define RT_MANIFEST 24\n\n")
+	}
+
+	// Problems with the values are reported by Build
+	ff := vi.FixedFileInfo
+	values, _ := ff.values()
+	b.WriteString("1 VERSIONINFO\n")
+	fmt.Fprintf(&b, "FILEVERSION     %s\n", rcVersion(ff.FileVersion))
+	fmt.Fprintf(&b, "PRODUCTVERSION  %s\n", rcVersion(ff.ProductVersion))
+	fmt.Fprintf(&b, "FILEFLAGSMASK   %s\n", formatFileFlagsMask(values.FileFlagsMask))
+	fmt.Fprintf(&b, "FILEFLAGS       %s\n", formatFileFlags(values.FileFlags))
+	fmt.Fprintf(&b, "FILEOS          %s\n", formatFileOS(values.FileOS))
+	fmt.Fprintf(&b, "FILETYPE        %s\n", formatFileType(values.FileType))
+	fmt.Fprintf(&b, "FILESUBTYPE     %s\n", formatFileSubType(values.FileType, values.FileSubType))
+
+	b.WriteString("BEGIN\n")
+	b.WriteString("    BLOCK \"StringFileInfo\"\n")
+	b.WriteString("    BEGIN\n")
+	tables := append([]StringTable{{
+		Translation:    vi.VarFileInfo.Translation.first(),
+		StringFileInfo: vi.StringFileInfo,
+	}}, vi.StringTables...)
+	for _, t := range tables {
+		fmt.Fprintf(&b, "        BLOCK %s\n", rcQuote(t.Translation.getTranslationString()))
+		b.WriteString("        BEGIN\n")
+		v := reflect.ValueOf(t.StringFileInfo)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).Kind() == reflect.String && v.Field(i).String() != "" {
+				fmt.Fprintf(&b, "            VALUE %s, %s\n", rcQuote(v.Type().Field(i).Name), rcQuote(v.Field(i).String()))
+			}
+		}
+		for _, pair := range t.StringFileInfo.Custom {
+			if pair.Value != "" {
+				fmt.Fprintf(&b, "            VALUE %s, %s\n", rcQuote(pair.Key), rcQuote(pair.Value))
+			}
+		}
+		b.WriteString("        END\n")
+	}
+	b.WriteString("    END\n")
+	b.WriteString("    BLOCK \"VarFileInfo\"\n")
+	b.WriteString("    BEGIN\n")
+	b.WriteString("        VALUE \"Translation\"")
+	translations := vi.VarFileInfo.Translation
+	if len(translations) == 0 {
+		translations = Translations{translations.first()}
+	}
+	for _, t := range translations {
+		fmt.Fprintf(&b, ", 0x%04X, 0x%04X", uint16(t.LangID), uint16(t.CharsetID))
+	}
+	b.WriteString("\n")
+	b.WriteString("    END\n")
+	b.WriteString("END\n")
+
+	// rc.exe only reads .ico files, WriteRC converts the others
+	var icons []string
+	for _, icon := range strings.Split(vi.IconPath, ",") {
+		if icon != "" {
+			icons = append(icons, icon)
+		}
+	}
+	all := append(append([]string(nil), icons...), vi.ApplicationIconPath)
+	for _, icon := range vi.Icons {
+		all = append(all, icon.Path)
+	}
+	for _, icon := range all {
+		if icon != "" && isPNGIcon(icon) {
+			return nil, fmt.Errorf("the PNG icon %s can not be written to a resource script, WriteRC converts it to an .ico file", icon)
+		}
+	}
+	for i, icon := range icons {
+		fmt.Fprintf(&b, "\n%d ICON %s\n", i+1, rcQuote(icon))
+	}
+
+	// IDI_APPLICATION defaults to the first icon, like in Resources
+	appIcon := vi.ApplicationIconPath
+	if appIcon == "" && len(icons) > 0 {
+		appIcon = icons[0]
+	}
+	if appIcon != "" {
+		fmt.Fprintf(&b, "\n32512 ICON %s\n", rcQuote(appIcon))
+	}
+
+	// A LANGUAGE statement holds until the next one
+	for _, icon := range vi.Icons {
+		name := icon.ID.String()
+		if icon.ID.Name != "" {
+			name = rcQuote(icon.ID.Name)
+		}
+		if icon.LangID != nil {
+			fmt.Fprintf(&b, "\nLANGUAGE 0x%02X, 0x%02X", uint16(*icon.LangID)&0x3ff, uint16(*icon.LangID)>>10)
+		}
+		fmt.Fprintf(&b, "\n%s ICON %s\n", name, rcQuote(icon.Path))
+		if icon.LangID != nil {
+			b.WriteString("LANGUAGE 0x09, 0x01\n")
+		}
+	}
+
+	if vi.ManifestPath != "" {
+		fmt.Fprintf(&b, "\n1 RT_MANIFEST %s\n", rcQuote(vi.ManifestPath))
+	}
+
+	for _, fr := range vi.FileResources {
+		name := fr.ID.String()
+		if fr.ID.Name != "" {
+			name = rcQuote(fr.ID.Name)
+		}
+		rt := fr.resourceType()
+		typ := rt.String()
+		switch {
+		case rt.Name != "":
+			typ = rcQuote(rt.Name)
+		case rt.ID == rtRCData:
+			typ = "RCDATA"
+		case rt.ID == rtHTML:
+			typ = "HTML"
+		}
+		if fr.LangID != nil {
+			fmt.Fprintf(&b, "\nLANGUAGE 0x%02X, 0x%02X", uint16(*fr.LangID)&0x3ff, uint16(*fr.LangID)>>10)
+		}
+		fmt.Fprintf(&b, "\n%s %s %s\n", name, typ, rcQuote(fr.Path))
+		if fr.LangID != nil {
+			b.WriteString("LANGUAGE 0x09, 0x01\n")
+		}
+	}
+
+	for _, st := range vi.StringResources {
+		b.WriteString("\nSTRINGTABLE\n")
+		if st.LangID != nil {
+			fmt.Fprintf(&b, "LANGUAGE 0x%02X, 0x%02X\n", uint16(*st.LangID)&0x3ff, uint16(*st.LangID)>>10)
+		}
+		b.WriteString("BEGIN\n")
+		ids := make([]int, 0, len(st.Strings))
+		for id := range st.Strings {
+			ids = append(ids, int(id))
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			fmt.Fprintf(&b, "    %d, %s\n", id, rcQuote(st.Strings[uint16(id)]))
+		}
+		b.WriteString("END\n")
+	}
+
+	return b.Bytes(), nil
+}
+
+func rcVersion(v FileVersion) string {
+	return fmt.Sprintf("%d,%d,%d,%d", v.Major, v.Minor, v.Patch, v.Build)
+}
+
+// rcQuote returns s as a quoted string. Control characters are written as
+// three digit octal escapes, which can not run into the following text.
+func rcQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"':
+			b.WriteString(`""`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\%03o`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
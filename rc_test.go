@@ -0,0 +1,304 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRC(t *testing.T) {
+	for _, name := range []string{"cmd", "control", "explorer"} {
+		t.Run(name, func(t *testing.T) {
+			jsonBytes, err := os.ReadFile("testdata/json/" + name + ".json")
+			assert.NoError(t, err)
+			want := &VersionInfo{}
+			assert.NoError(t, want.ParseJSON(jsonBytes))
+			want.Build()
+			want.Walk()
+
+			rcBytes, err := os.ReadFile("testdata/rc/" + name + ".rc")
+			assert.NoError(t, err)
+			vi := &VersionInfo{}
+			if err := vi.ParseRC(rcBytes); err != nil {
+				t.Fatal("Could not parse the .rc file", err)
+			}
+			vi.Build()
+			vi.Walk()
+
+			assert.Equal(t, want.Buffer.Bytes(), vi.Buffer.Bytes())
+		})
+	}
+}
+
+func TestParseRCResources(t *testing.T) {
+	rcBytes, err := os.ReadFile("testdata/rc/versioninfo.rc")
+	assert.NoError(t, err)
+
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseRC(rcBytes))
+	assert.Equal(t, FileVersion{Major: 1}, vi.FixedFileInfo.FileVersion)
+	assert.Equal(t, "v1.0.0.0", vi.StringFileInfo.ProductVersion)
+	assert.Equal(t, []IconResource{{Path: "icon.ico", ID: ResourceID{ID: 1}}}, vi.Icons)
+	assert.Equal(t, "", vi.ApplicationIconPath)
+	assert.Equal(t, "goversioninfo.exe.manifest", vi.ManifestPath)
+}
+
+func TestParseRCSyntax(t *testing.T) {
+	rc := `// Comments and the preprocessor
+#include "resource.h"
+#pragma code_page(65001)
+#define VER_MAJOR 2
+#define VER_MINOR 5 /* minor */
+#define VER_FILEVERSION VER_MAJOR,VER_MINOR,0,7
+#define VER_DEBUG VS_FF_DEBUG
+#ifdef RELEASE
+#undef VER_DEBUG
+#define VER_DEBUG 0
+#else
+#define VER_NAME "Debug build"
+#endif
+#ifndef VER_NAME
+#error not reached
+#endif
+
+LANGUAGE LANG_ENGLISH, SUBLANG_ENGLISH_US
+
+VS_VERSION_INFO VERSIONINFO
+FILEVERSION VER_FILEVERSION
+PRODUCTVERSION 2,5
+FILEFLAGSMASK VS_FFI_FILEFLAGSMASK
+FILEFLAGS VER_DEBUG | VS_FF_PRERELEASE
+FILEOS VOS_NT_WINDOWS32
+FILETYPE VFT_DLL
+FILESUBTYPE VFT2_UNKNOWN
+{
+	BLOCK "StringFileInfo"
+	{
+		BLOCK "040704b0"
+		{
+			VALUE "ProductName", "Beispiel"
+		}
+		BLOCK "040904b0"
+		{
+			VALUE "ProductName", L"Example\0"
+			VALUE "FileDescription", VER_NAME
+			VALUE "Comments", "Say ""hi""\t\x263A \101 " "joined"
+			VALUE "BuildCommit", "abc123"
+		}
+	}
+	BLOCK "VarFileInfo"
+	{
+		VALUE "Translation", 0x409, 1200, 0x407, 1200
+	}
+}
+
+IDI_APPLICATION ICON PRELOAD "app.ico"
+1 ICON small.ico
+MAINICON ICON "main.ico"
+CREATEPROCESS_MANIFEST_RESOURCE_ID RT_MANIFEST "app.manifest"
+`
+
+	vi := &VersionInfo{}
+	if err := vi.ParseRC([]byte(rc)); err != nil {
+		t.Fatal("Could not parse the .rc file", err)
+	}
+
+	assert.Equal(t, FixedFileInfo{
+		FileVersion:    FileVersion{Major: 2, Minor: 5, Build: 7},
+		ProductVersion: FileVersion{Major: 2, Minor: 5},
+		FileFlagsMask:  "3f",
+		FileFlags:      "03",
+		FileOS:         "40004",
+		FileType:       "02",
+		FileSubType:    "00",
+	}, vi.FixedFileInfo)
+
+	// The table of the first translation is the default one.
+	assert.Equal(t, "Example", vi.StringFileInfo.ProductName)
+	assert.Equal(t, "Debug build", vi.StringFileInfo.FileDescription)
+	assert.Equal(t, "Say \"hi\"\t☺ A joined", vi.StringFileInfo.Comments)
+	assert.Equal(t, CustomStrings{{Key: "BuildCommit", Value: "abc123"}}, vi.StringFileInfo.Custom)
+	if assert.Len(t, vi.StringTables, 1) {
+		assert.Equal(t, Translation{LangID: LngGerman, CharsetID: CsUnicode}, vi.StringTables[0].Translation)
+		assert.Equal(t, "Beispiel", vi.StringTables[0].StringFileInfo.ProductName)
+	}
+	assert.Equal(t, Translations{
+		{LangID: LngUSEnglish, CharsetID: CsUnicode},
+		{LangID: LngGerman, CharsetID: CsUnicode},
+	}, vi.VarFileInfo.Translation)
+
+	assert.Equal(t, []IconResource{
+		{Path: "small.ico", ID: ResourceID{ID: 1}},
+		{Path: "main.ico", ID: ResourceID{Name: "MAINICON"}},
+	}, vi.Icons)
+	assert.Equal(t, "app.ico", vi.ApplicationIconPath)
+	assert.Equal(t, "app.manifest", vi.ManifestPath)
+}
+
+func TestParseRCUTF16(t *testing.T) {
+	rc := "1 VERSIONINFO\r\nBEGIN\r\nBLOCK \"StringFileInfo\"\r\nBEGIN\r\nBLOCK \"040904b0\"\r\nBEGIN\r\n" +
+		"VALUE \"ProductName\", \"Grüße\"\r\nEND\r\nEND\r\nEND\r\n"
+
+	b := []byte{0xff, 0xfe}
+	for _, c := range utf16.Encode([]rune(rc)) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseRC(b))
+	assert.Equal(t, "Grüße", vi.StringFileInfo.ProductName)
+}
+
+func TestParseRCErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rc   string
+		err  string
+	}{
+		{"unsupported type", "1 DIALOG 0, 0, 10, 10\nBEGIN\nEND", "line 1: resource type DIALOG is not supported"},
+		{"missing type", "\n\n1", "line 3: resource 1 has no type"},
+		{"two versions", "1 VERSIONINFO\nBEGIN\nEND\n2 VERSIONINFO\nBEGIN\nEND", "line 4: only one VERSIONINFO is supported"},
+		{"unknown statement", "1 VERSIONINFO\nFILEDATE 1\nBEGIN\nEND", "line 2: unknown VERSIONINFO statement FILEDATE"},
+		{"undefined symbol", "1 VERSIONINFO\nFILEFLAGS VS_FF_FAST\nBEGIN\nEND", "line 2: undefined symbol VS_FF_FAST"},
+		{"version part", "1 VERSIONINFO\nFILEVERSION 1,70000\nBEGIN\nEND", "line 2: version part 70000 does not fit in 16 bits"},
+		{"unknown block", "1 VERSIONINFO\nBEGIN\nBLOCK \"Other\"\nBEGIN\nEND\nEND", `line 3: unknown block "Other", expected StringFileInfo or VarFileInfo`},
+		{"table key", "1 VERSIONINFO\nBEGIN\nBLOCK \"StringFileInfo\"\nBEGIN\nBLOCK \"0409\"\nBEGIN\nEND\nEND\nEND", `line 5: string table key: "0409" is not 8 hex digits`},
+		{"number value", "1 VERSIONINFO\nBEGIN\nBLOCK \"StringFileInfo\"\nBEGIN\nBLOCK \"040904b0\"\nBEGIN\nVALUE \"Build\", 7\nEND\nEND\nEND", `line 7: expected a string value for "Build", found 7`},
+		{"odd translation", "1 VERSIONINFO\nBEGIN\nBLOCK \"VarFileInfo\"\nBEGIN\nVALUE \"Translation\", 0x409\nEND\nEND", `line 5: Translation needs pairs of language and charset IDs, 1 given`},
+		{"missing end", "1 VERSIONINFO\nBEGIN\nBLOCK \"VarFileInfo\"\nBEGIN\n", "line 4: expected VALUE or END, found end of file"},
+		{"string", "1 ICON \"icon.ico\n", "line 1: string is not closed"},
+		{"comment", "/* comment\n\n", "line 1: comment is not closed"},
+		{"character", "1 ICON 'icon.ico'", `line 1: unexpected character '\''`},
+		{"number", "1x2 ICON icon.ico", "line 1: invalid number 1x2"},
+		{"function macro", "#define VER(x) x\n", "line 1: function-like macro VER is not supported"},
+		{"recursive macro", "#define A B\n#define B A\n\nA ICON icon.ico", "line 4: macro A refers to itself"},
+		{"if", "\n#if 1\n#endif", "line 2: preprocessor directive #if is not supported"},
+		{"endif", "#ifdef A\n", "line 2: missing #endif"},
+		{"two manifests", "1 24 a.manifest\n2 24 b.manifest", "line 2: only one RT_MANIFEST is supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vi := &VersionInfo{}
+			assert.EqualError(t, vi.ParseRC([]byte(tt.rc)), tt.err)
+		})
+	}
+}
+
+func TestRunCLIWithRC(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "rc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	var files [][]byte
+	for _, config := range []string{"testdata/json/cmd.json", "testdata/rc/cmd.rc"} {
+		cfg := NewCLIConfig()
+		cfg.ConfigFile = config
+		cfg.OutputFile = filepath.Join(tmpdir, filepath.Base(config)+".syso")
+		assert.NoError(t, RunCLI(cfg))
+
+		b, err := os.ReadFile(cfg.OutputFile)
+		assert.NoError(t, err)
+		files = append(files, b)
+	}
+	assert.True(t, bytes.Equal(files[0], files[1]), "the .rc and .json files give different resources")
+}
+
+func TestWriteRC(t *testing.T) {
+	vi := &VersionInfo{}
+	vi.FixedFileInfo = FixedFileInfo{
+		FileVersion:   FileVersion{Major: 1, Minor: 2, Patch: 3, Build: 4},
+		FileFlagsMask: "3f",
+		FileFlags:     "23",
+		FileOS:        "040004",
+		FileType:      "03",
+		FileSubType:   "06",
+	}
+	vi.StringFileInfo.ProductName = `Say "hi"`
+	vi.StringFileInfo.Comments = "C:\\temp\r\n\x01"
+	vi.StringFileInfo.Custom = CustomStrings{{Key: "BuildCommit", Value: "abc123"}}
+	vi.StringTables = []StringTable{{
+		Translation:    Translation{LangID: LngGerman, CharsetID: CsUnicode},
+		StringFileInfo: StringFileInfo{ProductName: "Grüße"},
+	}}
+	vi.VarFileInfo.Translation = Translations{
+		{LangID: LngUSEnglish, CharsetID: CsUnicode},
+		{LangID: LngGerman, CharsetID: CsUnicode},
+	}
+	vi.IconPath = "icon.ico,other.ico"
+	vi.ManifestPath = "app.manifest"
+	vi.Build()
+	vi.Walk()
+
+	tmpdir, err := os.MkdirTemp("", "rc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+	file := filepath.Join(tmpdir, "resource.rc")
+	assert.NoError(t, vi.WriteRC(file))
+
+	b, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, `#include <winver.h>
+#pragma code_page(65001)
+
+#define RT_MANIFEST 24
+
+1 VERSIONINFO
+FILEVERSION     1,2,3,4
+PRODUCTVERSION  0,0,0,0
+FILEFLAGSMASK   VS_FFI_FILEFLAGSMASK
+FILEFLAGS       VS_FF_DEBUG | VS_FF_PRERELEASE | VS_FF_SPECIALBUILD
+FILEOS          VOS_NT_WINDOWS32
+FILETYPE        VFT_DRV
+FILESUBTYPE     VFT2_DRV_NETWORK
+BEGIN
+    BLOCK "StringFileInfo"
+    BEGIN
+        BLOCK "040904B0"
+        BEGIN
+            VALUE "Comments", "C:\\temp\r\n\001"
+            VALUE "FileVersion", "1.2.3.4"
+            VALUE "ProductName", "Say ""hi"""
+            VALUE "BuildCommit", "abc123"
+        END
+        BLOCK "040704B0"
+        BEGIN
+            VALUE "FileVersion", "1.2.3.4"
+            VALUE "ProductName", "Grüße"
+        END
+    END
+    BLOCK "VarFileInfo"
+    BEGIN
+        VALUE "Translation", 0x0409, 0x04B0, 0x0407, 0x04B0
+    END
+END
+
+1 ICON "icon.ico"
+
+2 ICON "other.ico"
+
+32512 ICON "icon.ico"
+
+1 RT_MANIFEST "app.manifest"
+`, string(b))
+
+	// Reading the script back gives the same version information.
+	got := &VersionInfo{}
+	assert.NoError(t, got.ParseRC(b))
+	got.Build()
+	got.Walk()
+	assert.Equal(t, vi.Buffer.Bytes(), got.Buffer.Bytes())
+	assert.Equal(t, []IconResource{
+		{Path: "icon.ico", ID: ResourceID{ID: 1}},
+		{Path: "other.ico", ID: ResourceID{ID: 2}},
+	}, got.Icons)
+	assert.Equal(t, "icon.ico", got.ApplicationIconPath)
+	assert.Equal(t, vi.ManifestPath, got.ManifestPath)
+
+	vi.ResPath = "legacy.res"
+	assert.EqualError(t, vi.WriteRC(file), "the resources of a .res file can not be written to a resource script")
+}
@@ -0,0 +1,311 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf16"
+
+	"github.com/akavel/rsrc/ico"
+)
+
+// *****************************************************************************
+// Win32 Resource Files
+// *****************************************************************************
+
+/*
+Resource file format
+https://learn.microsoft.com/en-us/windows/win32/menurc/resourceheader
+
+A .res file is a list of resources, each one a RESOURCEHEADER followed by its
+data. The file starts with an empty resource that marks it as a 32-bit file.
+*/
+
+// Memory flags of a RESOURCEHEADER. Windows ignores them, but resource
+// compilers still set them.
+const (
+	resMoveable    = 0x0010
+	resPure        = 0x0020
+	resDiscardable = 0x1000
+)
+
+// ConflictPolicy decides what happens when a resource from a .res file has
+// the same type and ID as one defined by the version info.
+type ConflictPolicy string
+
+const (
+	// ConflictReplace keeps the resource defined by the version info and
+	// drops every language of the one from the .res file, along with the
+	// images of a replaced icon group. It is the default.
+	ConflictReplace ConflictPolicy = "replace"
+	// ConflictError fails instead of choosing one of the resources.
+	ConflictError ConflictPolicy = "error"
+)
+
+// ReadRes reads the resources of a 32-bit Win32 .res file, like the ones
+// written by rc.exe, windres or WriteRes.
+func ReadRes(filename string) (Resources, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	res, err := parseRes(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return res, nil
+}
+
+// isRes reports whether the file starts like a 32-bit .res file.
+func isRes(filename string) bool {
+	f, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	marker := resMarker()
+	b := make([]byte, len(marker))
+	if _, err := io.ReadFull(f, b); err != nil {
+		return false
+	}
+	return bytes.Equal(b, marker)
+}
+
+// resMarker returns the empty resource that starts every 32-bit .res file.
+func resMarker() []byte {
+	var b bytes.Buffer
+	writeResHeader(&b, Resource{}, 0)
+	return b.Bytes()
+}
+
+// parseRes splits the data of a .res file into its resources.
+func parseRes(b []byte) (Resources, error) {
+	marker := resMarker()
+	if !bytes.HasPrefix(b, marker) {
+		return nil, errors.New("not a 32-bit .res file")
+	}
+
+	var res Resources
+	for off := len(marker); off < len(b); {
+		if off+8 > len(b) {
+			return nil, fmt.Errorf("resource header at %#x runs past the end of the file", off)
+		}
+		dataSize := binary.LittleEndian.Uint32(b[off:])
+		headerSize := binary.LittleEndian.Uint32(b[off+4:])
+		if headerSize < 8 || uint64(headerSize) > uint64(len(b)-off) {
+			return nil, fmt.Errorf("resource header at %#x has an invalid HeaderSize %d", off, headerSize)
+		}
+		header := b[off+8 : off+int(headerSize)]
+
+		var r Resource
+		var n int
+		var err error
+		if r.Type, n, err = readOrdOrName(header); err != nil {
+			return nil, fmt.Errorf("resource type at %#x: %w", off+8, err)
+		}
+		nameOff := n
+		if r.Name, n, err = readOrdOrName(header[nameOff:]); err != nil {
+			return nil, fmt.Errorf("resource name at %#x: %w", off+8+nameOff, err)
+		}
+
+		// DataVersion and MemoryFlags come before the language
+		langOff := align4(8+nameOff+n) - 8 + 6
+		if langOff+2 > len(header) {
+			return nil, fmt.Errorf("resource header at %#x is too short for LanguageId", off)
+		}
+		r.LangID = LangID(binary.LittleEndian.Uint16(header[langOff:]))
+
+		start := off + int(headerSize)
+		if uint64(dataSize) > uint64(len(b)-start) {
+			return nil, fmt.Errorf("%s/%s: %d bytes of data at %#x run past the end of the file",
+				r.Type.typeString(), r.Name, dataSize, start)
+		}
+		end := start + int(dataSize)
+		r.Data = b[start:end]
+		res = append(res, r)
+
+		off = align4(end)
+	}
+
+	return res, nil
+}
+
+// readOrdOrName is the inverse of resOrdOrName. It also returns the number
+// of bytes that were read.
+func readOrdOrName(b []byte) (ResourceID, int, error) {
+	if len(b) < 2 {
+		return ResourceID{}, 0, errors.New("header ends before the ID")
+	}
+	if binary.LittleEndian.Uint16(b) == 0xffff {
+		if len(b) < 4 {
+			return ResourceID{}, 0, errors.New("header ends before the ID")
+		}
+		return ResourceID{ID: binary.LittleEndian.Uint16(b[2:])}, 4, nil
+	}
+
+	var name []uint16
+	for i := 0; ; i += 2 {
+		if i+2 > len(b) {
+			return ResourceID{}, 0, errors.New("name is not NUL terminated before the end of the header")
+		}
+		c := binary.LittleEndian.Uint16(b[i:])
+		if c == 0 {
+			return ResourceID{Name: string(utf16.Decode(name))}, i + 2, nil
+		}
+		name = append(name, c)
+	}
+}
+
+// Merge adds the resources of other, like the ones read from a .res file,
+// to rs. A resource of other with the same type and ID as one in rs is
+// handled as policy says. The images of an icon group of other that rs
+// replaces are dropped too, unless another group of other shows them.
+func (rs Resources) Merge(other Resources, policy ConflictPolicy) (Resources, error) {
+	switch policy {
+	case "", ConflictReplace, ConflictError:
+	default:
+		return nil, fmt.Errorf("unknown conflict policy %q, expected replace or error", policy)
+	}
+
+	type typeName struct{ typ, name ResourceID }
+	defined := map[typeName]bool{}
+	for _, r := range rs {
+		defined[typeName{r.Type.key(), r.Name.key()}] = true
+	}
+	orphans := other.orphanIcons(func(group ResourceID) bool {
+		return defined[typeName{ResourceID{ID: rtGroupIcon}, group.key()}]
+	})
+
+	merged := append(Resources(nil), rs...)
+	for _, r := range other {
+		if defined[typeName{r.Type.key(), r.Name.key()}] {
+			if policy == ConflictError {
+				return nil, fmt.Errorf("resource %s/%s is defined twice", r.Type.typeString(), r.Name)
+			}
+			continue
+		}
+		if r.Type == (ResourceID{ID: rtIcon}) && r.Name.Name == "" && orphans[r.Name.ID] {
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged, nil
+}
+
+// orphanIcons returns the IDs of the RT_ICON images that only icon groups
+// replaced says are replaced refer to.
+func (rs Resources) orphanIcons(replaced func(group ResourceID) bool) map[uint16]bool {
+	orphans, shown := map[uint16]bool{}, map[uint16]bool{}
+	for _, r := range rs {
+		if r.Type != (ResourceID{ID: rtGroupIcon}) {
+			continue
+		}
+		for _, id := range groupIconIDs(r.Data) {
+			if replaced(r.Name) {
+				orphans[id] = true
+			} else {
+				shown[id] = true
+			}
+		}
+	}
+	for id := range shown {
+		delete(orphans, id)
+	}
+	return orphans
+}
+
+// groupIconIDs returns the IDs of the RT_ICON images an RT_GROUP_ICON lists,
+// as far as it can be read.
+func groupIconIDs(group []byte) []uint16 {
+	r := bytes.NewReader(group)
+	var dir ico.ICONDIR
+	if err := binary.Read(r, binary.LittleEndian, &dir); err != nil {
+		return nil
+	}
+	var ids []uint16
+	for i := 0; i < int(dir.Count); i++ {
+		var e gRPICONDIRENTRY
+		if err := binary.Read(r, binary.LittleEndian, &e); err != nil {
+			break
+		}
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+// WriteRes writes the resources as a 32-bit Win32 .res file, which can be
+// linked with MSVC, lld-link or MinGW windres.
+func (rs Resources) WriteRes(filename string) error {
+	return os.WriteFile(filename, rs.res(), 0644)
+}
+
+// res returns the resources in the .res file format.
+func (rs Resources) res() []byte {
+	var b bytes.Buffer
+
+	b.Write(resMarker())
+
+	for _, r := range rs {
+		r.Type, r.Name = r.Type.key(), r.Name.key()
+		writeResHeader(&b, r, resMemoryFlags(r.Type))
+		b.Write(r.Data)
+		b.Write(padBytes(align4(len(r.Data)) - len(r.Data)))
+	}
+
+	return b.Bytes()
+}
+
+// writeResHeader writes a RESOURCEHEADER for r.
+func writeResHeader(b *bytes.Buffer, r Resource, memoryFlags uint16) {
+	var h bytes.Buffer
+	h.Write(resOrdOrName(r.Type))
+	h.Write(resOrdOrName(r.Name))
+
+	// The fields that follow the names start on a 32-bit boundary
+	h.Write(padBytes(align4(8+h.Len()) - 8 - h.Len()))
+
+	binary.Write(&h, binary.LittleEndian, struct {
+		DataVersion     uint32
+		MemoryFlags     uint16
+		LanguageID      uint16
+		Version         uint32
+		Characteristics uint32
+	}{
+		MemoryFlags: memoryFlags,
+		LanguageID:  uint16(r.LangID),
+	})
+
+	binary.Write(b, binary.LittleEndian, uint32(len(r.Data)))
+	binary.Write(b, binary.LittleEndian, uint32(8+h.Len()))
+	b.Write(h.Bytes())
+}
+
+// resOrdOrName encodes an ID as 0xFFFF followed by the number, or a name as
+// a NUL terminated UTF-16 string.
+func resOrdOrName(id ResourceID) []byte {
+	if id.Name == "" {
+		return []byte{0xff, 0xff, byte(id.ID), byte(id.ID >> 8)}
+	}
+	b := make([]byte, 0, 2*len(id.Name)+2)
+	for _, c := range utf16.Encode([]rune(id.Name)) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return append(b, 0, 0)
+}
+
+// resMemoryFlags returns the flags rc.exe uses for each resource type.
+func resMemoryFlags(typ ResourceID) uint16 {
+	if typ.Name == "" {
+		switch uint32(typ.ID) {
+		case rtIcon:
+			return resMoveable | resDiscardable
+		case rtGroupIcon:
+			return resMoveable | resPure | resDiscardable
+		}
+	}
+	return resMoveable | resPure
+}
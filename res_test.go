@@ -0,0 +1,227 @@
+package goversioninfo
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteRes(t *testing.T) {
+	jsonBytes, err := os.ReadFile("testdata/json/cmd.json")
+	assert.NoError(t, err)
+
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON(jsonBytes))
+	vi.IconPath = "testdata/resource/icon.ico"
+	vi.ManifestPath = "testdata/resource/goversioninfo.exe.manifest"
+	vi.Build()
+	vi.Walk()
+
+	want, err := vi.Resources()
+	assert.NoError(t, err)
+
+	tmpdir, err := os.MkdirTemp("", "res")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+	file := filepath.Join(tmpdir, "resource.res")
+	assert.NoError(t, vi.WriteRes(file))
+
+	b, err := os.ReadFile(file)
+	assert.NoError(t, err)
+
+	// The file starts with the empty 32-bit marker resource.
+	assert.Equal(t, []byte{
+		0x00, 0x00, 0x00, 0x00, 0x20, 0x00, 0x00, 0x00,
+		0xff, 0xff, 0x00, 0x00, 0xff, 0xff, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}, b[:32])
+
+	// Every resource follows in order, with its header and data aligned.
+	off := 32
+	for _, r := range want {
+		if !assert.True(t, off+32 <= len(b), "file ends before %s/%s", r.Type, r.Name) {
+			return
+		}
+		assert.Zero(t, off%4, "header of %s/%s is not aligned", r.Type, r.Name)
+
+		dataSize := int(binary.LittleEndian.Uint32(b[off:]))
+		headerSize := int(binary.LittleEndian.Uint32(b[off+4:]))
+		assert.Equal(t, 32, headerSize)
+		assert.Equal(t, uint16(r.Type.ID), binary.LittleEndian.Uint16(b[off+10:]))
+		assert.Equal(t, r.Name.ID, binary.LittleEndian.Uint16(b[off+14:]))
+		assert.Equal(t, uint16(r.LangID), binary.LittleEndian.Uint16(b[off+22:]))
+		assert.Equal(t, r.Data, b[off+headerSize:off+headerSize+dataSize])
+
+		off = align4(off + headerSize + dataSize)
+	}
+	assert.Equal(t, len(b), off)
+}
+
+func TestWriteResNames(t *testing.T) {
+	res := Resources{
+		{Type: ResourceID{Name: "Config"}, Name: ResourceID{ID: 7}, LangID: LngGerman, Data: []byte("abcde")},
+	}
+
+	tmpdir, err := os.MkdirTemp("", "res")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+	file := filepath.Join(tmpdir, "resource.res")
+	assert.NoError(t, res.WriteRes(file))
+
+	b, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x05, 0x00, 0x00, 0x00, // DataSize
+		0x2c, 0x00, 0x00, 0x00, // HeaderSize
+		'C', 0, 'O', 0, 'N', 0, 'F', 0, 'I', 0, 'G', 0, 0, 0, // TYPE
+		0xff, 0xff, 0x07, 0x00, // NAME
+		0x00, 0x00, // Padding
+		0x00, 0x00, 0x00, 0x00, // DataVersion
+		0x30, 0x00, // MemoryFlags
+		0x07, 0x04, // LanguageId
+		0x00, 0x00, 0x00, 0x00, // Version
+		0x00, 0x00, 0x00, 0x00, // Characteristics
+		'a', 'b', 'c', 'd', 'e', 0x00, 0x00, 0x00,
+	}, b[32:])
+}
+
+func TestReadRes(t *testing.T) {
+	res, err := ReadRes("testdata/res/legacy.res")
+	if err != nil {
+		t.Fatal("Could not read the .res", err)
+	}
+
+	var ids []string
+	for _, r := range res {
+		ids = append(ids, r.Type.typeString()+"/"+r.Name.String())
+		assert.Equal(t, LngUSEnglish, r.LangID)
+	}
+	assert.Equal(t, []string{"RT_ICON/1", "RT_GROUP_ICON/1", "RT_DIALOG/1", "RT_VERSION/1", "RT_STRING/1"}, ids)
+
+	vi, err := DecodeVersionInfo(res[3].Data)
+	assert.NoError(t, err)
+	assert.Equal(t, "Legacy", vi.StringFileInfo.ProductName)
+
+	// Writing what was read must give back the same resources.
+	tmpdir, err := os.MkdirTemp("", "res")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+	file := filepath.Join(tmpdir, "copy.res")
+	assert.NoError(t, res.WriteRes(file))
+	got, err := ReadResources(file)
+	assert.NoError(t, err)
+	assert.Equal(t, res, got)
+}
+
+func TestReadResErrors(t *testing.T) {
+	marker := resMarker()
+	header := []byte{
+		0x10, 0x00, 0x00, 0x00, 0x20, 0x00, 0x00, 0x00,
+		0xff, 0xff, 0x0a, 0x00, 0xff, 0xff, 0x01, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x30, 0x00, 0x09, 0x04,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"empty", nil, "not a 32-bit .res file"},
+		{"16-bit", []byte{0xff, 0x0a, 0x00, 0xff, 0x01, 0x00}, "not a 32-bit .res file"},
+		{"short header", append(append([]byte{}, marker...), 1, 2, 3), "resource header at 0x20 runs past the end of the file"},
+		{"header size", append(append([]byte{}, marker...), header[:8]...), "resource header at 0x20 has an invalid HeaderSize 32"},
+		{"data size", append(append([]byte{}, marker...), header...), "RT_RCDATA/1: 16 bytes of data at 0x40 run past the end of the file"},
+		{"name", append(append([]byte{}, marker...), 0, 0, 0, 0, 12, 0, 0, 0, 'A', 0, 'B', 0), "resource type at 0x28: name is not NUL terminated before the end of the header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRes(tt.data)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestMergeRes(t *testing.T) {
+	legacy, err := ReadRes("testdata/res/legacy.res")
+	assert.NoError(t, err)
+
+	vi := &VersionInfo{}
+	vi.StringFileInfo.ProductName = "Go"
+	vi.IconPath = "testdata/resource/icon.ico"
+	vi.ResPath = "testdata/res/legacy.res"
+	vi.Build()
+	vi.Walk()
+
+	res, err := vi.Resources()
+	assert.NoError(t, err)
+
+	// The version info replaces the one of the .res file, everything else
+	// is kept.
+	var versions []Resource
+	kept := map[string][]byte{}
+	for _, r := range res {
+		switch r.Type {
+		case ResourceID{ID: rtVersion}:
+			versions = append(versions, r)
+		case ResourceID{ID: 5}, ResourceID{ID: 6}:
+			kept[r.Type.typeString()] = r.Data
+		}
+	}
+	if assert.Len(t, versions, 1) {
+		decoded, err := DecodeVersionInfo(versions[0].Data)
+		assert.NoError(t, err)
+		assert.Equal(t, "Go", decoded.StringFileInfo.ProductName)
+	}
+	assert.Equal(t, legacy[2].Data, kept["RT_DIALOG"])
+	assert.Equal(t, legacy[4].Data, kept["RT_STRING"])
+
+	// The icon group of the .res file still finds its image.
+	legacyIcon, err := os.ReadFile("testdata/res/legacy.ico")
+	assert.NoError(t, err)
+	er, err := newExecutableResources(res)
+	assert.NoError(t, err)
+	if assert.Len(t, er.Icons, 3) {
+		assert.Equal(t, ResourceID{ID: 1}, er.Icons[2].ID)
+		assert.Equal(t, legacyIcon, er.Icons[2].Data)
+	}
+
+	// An icon group that replaces the one of the .res file takes its image
+	// along, and its ID is free again.
+	vi.IconPath = ""
+	vi.Icons = []IconResource{{Path: "testdata/resource/icon.ico", ID: ResourceID{ID: 1}}}
+	res, err = vi.Resources()
+	assert.NoError(t, err)
+	var imageIDs []uint16
+	for _, r := range res {
+		if r.Type == (ResourceID{ID: rtIcon}) {
+			assert.NotEqual(t, legacy[0].Data, r.Data)
+			imageIDs = append(imageIDs, r.Name.ID)
+		}
+	}
+	icon, err := os.ReadFile("testdata/resource/icon.ico")
+	assert.NoError(t, err)
+	er, err = newExecutableResources(res)
+	assert.NoError(t, err)
+	if assert.Len(t, er.Icons, 1) {
+		assert.Equal(t, ResourceID{ID: 1}, er.Icons[0].ID)
+		assert.Equal(t, icon, er.Icons[0].Data)
+	}
+	assert.Len(t, imageIDs, int(binary.LittleEndian.Uint16(icon[4:])), "one for each image of icon.ico")
+	assert.Contains(t, imageIDs, uint16(1))
+
+	vi.ResConflict = ConflictError
+	_, err = vi.Resources()
+	assert.EqualError(t, err, "merging testdata/res/legacy.res: resource RT_GROUP_ICON/1 is defined twice")
+	vi.Icons = nil
+	_, err = vi.Resources()
+	assert.EqualError(t, err, "merging testdata/res/legacy.res: resource RT_VERSION/1 is defined twice")
+
+	vi.ResConflict = "keep"
+	_, err = vi.Resources()
+	assert.EqualError(t, err, `merging testdata/res/legacy.res: unknown conflict policy "keep", expected replace or error`)
+}
@@ -0,0 +1,508 @@
+package goversioninfo
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf16"
+)
+
+// *****************************************************************************
+// Resource Directory
+// *****************************************************************************
+
+/*
+Resource directory layout
+https://learn.microsoft.com/en-us/windows/win32/debug/pe-format#the-rsrc-section
+
+The tree is always three levels deep: type, then name or ID, then language.
+*/
+
+// ResourceID is the type or name of a resource. A resource is identified
+// either by a 16-bit number or by a string, so Name is used when it is set.
+type ResourceID struct {
+	ID   uint16
+	Name string
+}
+
+// String returns the name, or the number in decimal.
+func (r ResourceID) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return strconv.Itoa(int(r.ID))
+}
+
+// MarshalJSON writes the name as a string and the number as a number.
+func (r ResourceID) MarshalJSON() ([]byte, error) {
+	if r.Name != "" {
+		return json.Marshal(r.Name)
+	}
+	return json.Marshal(r.ID)
+}
+
+// UnmarshalJSON reads a number or a name. A string of the form "#101" is the
+// number, like in the resource APIs of Windows.
+func (r *ResourceID) UnmarshalJSON(p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+	if p[0] != '"' {
+		var u uint16
+		if err := json.Unmarshal(p, &u); err != nil {
+			return err
+		}
+		*r = ResourceID{ID: u}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(p, &s); err != nil {
+		return err
+	}
+	if strings.HasPrefix(s, "#") {
+		u, err := strconv.ParseUint(s[1:], 10, 16)
+		if err != nil {
+			return fmt.Errorf("resource ID %q is not a 16-bit number", s)
+		}
+		*r = ResourceID{ID: uint16(u)}
+		return nil
+	}
+	*r = ResourceID{Name: s}
+	return nil
+}
+
+// resourceTypeNames are the predefined resource types from winuser.h.
+var resourceTypeNames = map[uint16]string{
+	1:  "RT_CURSOR",
+	2:  "RT_BITMAP",
+	3:  "RT_ICON",
+	4:  "RT_MENU",
+	5:  "RT_DIALOG",
+	6:  "RT_STRING",
+	7:  "RT_FONTDIR",
+	8:  "RT_FONT",
+	9:  "RT_ACCELERATOR",
+	10: "RT_RCDATA",
+	11: "RT_MESSAGETABLE",
+	12: "RT_GROUP_CURSOR",
+	14: "RT_GROUP_ICON",
+	16: "RT_VERSION",
+	17: "RT_DLGINCLUDE",
+	19: "RT_PLUGPLAY",
+	20: "RT_VXD",
+	21: "RT_ANICURSOR",
+	22: "RT_ANIICON",
+	23: "RT_HTML",
+	24: "RT_MANIFEST",
+}
+
+// typeString returns the RT_ name of a predefined resource type.
+func (r ResourceID) typeString() string {
+	if name, ok := resourceTypeNames[r.ID]; ok && r.Name == "" {
+		return name
+	}
+	return r.String()
+}
+
+// key returns the form a name is stored and looked up in. Windows upper cases
+// string names before searching for them.
+func (r ResourceID) key() ResourceID {
+	if r.Name != "" {
+		return ResourceID{Name: strings.ToUpper(r.Name)}
+	}
+	return r
+}
+
+// less orders IDs the way a resource directory must be sorted: names first,
+// then numbers.
+func (r ResourceID) less(o ResourceID) bool {
+	if (r.Name != "") != (o.Name != "") {
+		return r.Name != ""
+	}
+	if r.Name != "" {
+		return r.Name < o.Name
+	}
+	return r.ID < o.ID
+}
+
+// Resource is a single leaf of a resource directory.
+type Resource struct {
+	Type   ResourceID
+	Name   ResourceID
+	LangID LangID
+	Data   []byte
+}
+
+// Resources is a list of resources that is written as one resource directory.
+type Resources []Resource
+
+// add appends a resource with a numeric type and ID in the default language.
+func (rs *Resources) add(typ uint32, id uint16, data []byte) {
+	*rs = append(*rs, Resource{
+		Type:   ResourceID{ID: uint16(typ)},
+		Name:   ResourceID{ID: id},
+		LangID: LngUSEnglish,
+		Data:   data,
+	})
+}
+
+// checkIDs returns an error for the first resource with the type, name and
+// language of an earlier one.
+func (rs Resources) checkIDs() error {
+	type leaf struct {
+		typ, name ResourceID
+		lang      LangID
+	}
+	seen := map[leaf]bool{}
+	for _, r := range rs {
+		l := leaf{r.Type.key(), r.Name.key(), r.LangID}
+		if seen[l] {
+			return fmt.Errorf("%s %s language %04x is defined twice", r.Type.typeString(), r.Name, uint16(r.LangID))
+		}
+		seen[l] = true
+	}
+	return nil
+}
+
+// Print writes a table that lists every resource.
+func (rs Resources) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tNAME\tLANG\tSIZE\t")
+	for _, r := range rs {
+		fmt.Fprintf(tw, "%s\t%s\t%04x\t%d\t%s\n", r.Type.typeString(), r.Name, uint16(r.LangID), len(r.Data), r.describe())
+	}
+	return tw.Flush()
+}
+
+// describe returns a short summary of the well known resource types.
+func (r Resource) describe() string {
+	if r.Type.Name != "" {
+		return ""
+	}
+	switch uint32(r.Type.ID) {
+	case rtVersion:
+		vi, err := DecodeVersionInfo(r.Data)
+		if err != nil {
+			return err.Error()
+		}
+		return fmt.Sprintf("FileVersion %s, ProductVersion %s",
+			vi.FixedFileInfo.FileVersion.GetVersionString(), vi.FixedFileInfo.ProductVersion.GetVersionString())
+	case rtGroupIcon:
+		if len(r.Data) >= 6 {
+			return fmt.Sprintf("%d images", binary.LittleEndian.Uint16(r.Data[4:]))
+		}
+	}
+	return ""
+}
+
+// numbered tells if every resource has a numeric type and ID and is in U.S.
+// English, which is all coff.AddResource can write.
+func (rs Resources) numbered() bool {
+	for _, r := range rs {
+		if r.Type.Name != "" || r.Name.Name != "" || r.LangID != LngUSEnglish {
+			return false
+		}
+	}
+	return true
+}
+
+// section lays the resources out as the contents of a .rsrc section loaded
+// at rva. It also returns the offsets of the fields that hold an RVA, which
+// need a relocation in an object file.
+func (rs Resources) section(rva uint32) ([]byte, []uint32, error) {
+	type langNode struct {
+		lang LangID
+		data []byte
+	}
+	type nameNode struct {
+		id    ResourceID
+		langs []langNode
+	}
+	type typeNode struct {
+		id    ResourceID
+		names []*nameNode
+	}
+
+	// Group the leaves into the type, name and language levels
+	var types []*typeNode
+	for _, r := range rs {
+		typeKey, nameKey := r.Type.key(), r.Name.key()
+		var t *typeNode
+		for _, tn := range types {
+			if tn.id == typeKey {
+				t = tn
+			}
+		}
+		if t == nil {
+			t = &typeNode{id: typeKey}
+			types = append(types, t)
+		}
+		var n *nameNode
+		for _, nn := range t.names {
+			if nn.id == nameKey {
+				n = nn
+			}
+		}
+		if n == nil {
+			n = &nameNode{id: nameKey}
+			t.names = append(t.names, n)
+		}
+		for _, l := range n.langs {
+			if l.lang == r.LangID {
+				return nil, nil, fmt.Errorf("duplicate resource %s/%s language %04x", r.Type.typeString(), r.Name, uint16(r.LangID))
+			}
+		}
+		n.langs = append(n.langs, langNode{lang: r.LangID, data: r.Data})
+	}
+
+	sort.SliceStable(types, func(i, j int) bool { return types[i].id.less(types[j].id) })
+	for _, t := range types {
+		sort.SliceStable(t.names, func(i, j int) bool { return t.names[i].id.less(t.names[j].id) })
+		for _, n := range t.names {
+			sort.SliceStable(n.langs, func(i, j int) bool { return n.langs[i].lang < n.langs[j].lang })
+		}
+	}
+
+	// Directories come first, then the data entries, the names and the data
+	dirSize := func(entries int) int { return 16 + 8*entries }
+	off := dirSize(len(types))
+	typeOff := make([]int, len(types))
+	for i, t := range types {
+		typeOff[i] = off
+		off += dirSize(len(t.names))
+	}
+	nameOff := make([][]int, len(types))
+	leaves := 0
+	for i, t := range types {
+		nameOff[i] = make([]int, len(t.names))
+		for j, n := range t.names {
+			nameOff[i][j] = off
+			off += dirSize(len(n.langs))
+			leaves += len(n.langs)
+		}
+	}
+	entryOff := off
+	off += 16 * leaves
+
+	stringOff := map[string]int{}
+	var names []string
+	addName := func(id ResourceID) {
+		if _, ok := stringOff[id.Name]; id.Name == "" || ok {
+			return
+		}
+		stringOff[id.Name] = off
+		names = append(names, id.Name)
+		off += 2 + 2*len(utf16.Encode([]rune(id.Name)))
+	}
+	for _, t := range types {
+		addName(t.id)
+		for _, n := range t.names {
+			addName(n.id)
+		}
+	}
+
+	off = align8(off)
+	dataOff := off
+	for _, t := range types {
+		for _, n := range t.names {
+			for _, l := range n.langs {
+				off += align8(len(l.data))
+			}
+		}
+	}
+
+	b := make([]byte, off)
+	le := binary.LittleEndian
+	writeDir := func(at int, ids []ResourceID, offsets []uint32) {
+		named := 0
+		for _, id := range ids {
+			if id.Name != "" {
+				named++
+			}
+		}
+		le.PutUint16(b[at+12:], uint16(named))
+		le.PutUint16(b[at+14:], uint16(len(ids)-named))
+		for i, id := range ids {
+			nameOrID := uint32(id.ID)
+			if id.Name != "" {
+				nameOrID = 0x80000000 | uint32(stringOff[id.Name])
+			}
+			le.PutUint32(b[at+16+8*i:], nameOrID)
+			le.PutUint32(b[at+20+8*i:], offsets[i])
+		}
+	}
+
+	var ids []ResourceID
+	var offsets []uint32
+	for i, t := range types {
+		ids = append(ids, t.id)
+		offsets = append(offsets, 0x80000000|uint32(typeOff[i]))
+	}
+	writeDir(0, ids, offsets)
+
+	var relocs []uint32
+	entry, data := entryOff, dataOff
+	for i, t := range types {
+		ids, offsets = ids[:0], offsets[:0]
+		for j, n := range t.names {
+			ids = append(ids, n.id)
+			offsets = append(offsets, 0x80000000|uint32(nameOff[i][j]))
+		}
+		writeDir(typeOff[i], ids, offsets)
+
+		for j, n := range t.names {
+			ids, offsets = ids[:0], offsets[:0]
+			for _, l := range n.langs {
+				ids = append(ids, ResourceID{ID: uint16(l.lang)})
+				offsets = append(offsets, uint32(entry))
+
+				// IMAGE_RESOURCE_DATA_ENTRY
+				le.PutUint32(b[entry:], rva+uint32(data))
+				le.PutUint32(b[entry+4:], uint32(len(l.data)))
+				relocs = append(relocs, uint32(entry))
+				copy(b[data:], l.data)
+
+				entry += 16
+				data += align8(len(l.data))
+			}
+			writeDir(nameOff[i][j], ids, offsets)
+		}
+	}
+
+	for _, name := range names {
+		u16 := utf16.Encode([]rune(name))
+		at := stringOff[name]
+		le.PutUint16(b[at:], uint16(len(u16)))
+		for i, c := range u16 {
+			le.PutUint16(b[at+2+2*i:], c)
+		}
+	}
+
+	return b, relocs, nil
+}
+
+// resourceDataFunc returns size bytes stored at the given address. The address
+// is an RVA in an executable and a section offset in an object file. entry is
+// the offset of the IMAGE_RESOURCE_DATA_ENTRY that holds it.
+type resourceDataFunc func(entry, addr, size uint32) ([]byte, error)
+
+// parseResourceDirectory reads every resource of the directory held by dir.
+func parseResourceDirectory(dir []byte, data resourceDataFunc) (Resources, error) {
+	p := resourceParser{dir: dir, data: data, visited: map[uint32]bool{}}
+	var res Resources
+	err := p.walk(0, 0, Resource{}, &res)
+	return res, err
+}
+
+type resourceParser struct {
+	dir  []byte
+	data resourceDataFunc
+
+	// visited holds the offsets of the directories read so far. Each one is
+	// only read once, so directories that point at the same subdirectory
+	// many times can not make the walk blow up.
+	visited map[uint32]bool
+}
+
+func (p *resourceParser) uint32At(off uint32, what string) (uint32, error) {
+	if uint64(off)+4 > uint64(len(p.dir)) {
+		return 0, fmt.Errorf("resource directory: %s at %#x is outside the %d byte directory", what, off, len(p.dir))
+	}
+	return binary.LittleEndian.Uint32(p.dir[off:]), nil
+}
+
+func (p *resourceParser) walk(off uint32, depth int, leaf Resource, res *Resources) error {
+	if p.visited[off] {
+		return fmt.Errorf("resource directory: directory at %#x is referenced twice", off)
+	}
+	p.visited[off] = true
+
+	counts, err := p.uint32At(off+12, "entry count")
+	if err != nil {
+		return err
+	}
+	total := (counts & 0xffff) + (counts >> 16)
+
+	for i := uint32(0); i < total; i++ {
+		entry := off + 16 + i*8
+		nameOrID, err := p.uint32At(entry, "entry name")
+		if err != nil {
+			return err
+		}
+		offsetToData, err := p.uint32At(entry+4, "entry offset")
+		if err != nil {
+			return err
+		}
+
+		id := ResourceID{ID: uint16(nameOrID)}
+		if nameOrID&0x80000000 != 0 {
+			if id.Name, err = p.name(nameOrID & 0x7fffffff); err != nil {
+				return err
+			}
+			id.ID = 0
+		}
+
+		switch depth {
+		case 0:
+			leaf.Type = id
+		case 1:
+			leaf.Name = id
+		case 2:
+			leaf.LangID = LangID(nameOrID)
+		}
+
+		if offsetToData&0x80000000 != 0 {
+			if depth >= 2 {
+				return fmt.Errorf("resource directory: %s/%s is nested more than 3 levels deep", leaf.Type, leaf.Name)
+			}
+			if err := p.walk(offsetToData&0x7fffffff, depth+1, leaf, res); err != nil {
+				return err
+			}
+			continue
+		}
+		if depth != 2 {
+			return fmt.Errorf("resource directory: data entry at %#x is only %d levels deep", offsetToData, depth+1)
+		}
+
+		// IMAGE_RESOURCE_DATA_ENTRY
+		addr, err := p.uint32At(offsetToData, "data entry")
+		if err != nil {
+			return err
+		}
+		size, err := p.uint32At(offsetToData+4, "data entry size")
+		if err != nil {
+			return err
+		}
+		if leaf.Data, err = p.data(offsetToData, addr, size); err != nil {
+			return fmt.Errorf("resource %s/%s/%04x: %w", leaf.Type, leaf.Name, leaf.LangID, err)
+		}
+		*res = append(*res, leaf)
+	}
+
+	return nil
+}
+
+// name reads an IMAGE_RESOURCE_DIR_STRING_U.
+func (p *resourceParser) name(off uint32) (string, error) {
+	if uint64(off)+2 > uint64(len(p.dir)) {
+		return "", fmt.Errorf("resource directory: name at %#x is outside the %d byte directory", off, len(p.dir))
+	}
+	n := uint32(binary.LittleEndian.Uint16(p.dir[off:]))
+	if uint64(off)+2+uint64(n)*2 > uint64(len(p.dir)) {
+		return "", fmt.Errorf("resource directory: name at %#x runs past the end of the directory", off)
+	}
+	u16 := make([]uint16, n)
+	for i := range u16 {
+		u16[i] = binary.LittleEndian.Uint16(p.dir[off+2+uint32(i)*2:])
+	}
+	return string(utf16.Decode(u16)), nil
+}
+
+func align8(i int) int {
+	return (i + 7) &^ 7
+}
@@ -0,0 +1,184 @@
+package goversioninfo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// *****************************************************************************
+// Semantic Versions
+// *****************************************************************************
+
+// SemVer is a semantic version like v2.3.1-rc.2+sha.abc, see https://semver.org.
+type SemVer struct {
+	Major int
+	Minor int
+	Patch int
+
+	// Prerelease is what follows the -, like rc.2.
+	Prerelease string
+
+	// Metadata is the build metadata that follows the +, like sha.abc.
+	Metadata string
+}
+
+// semVerPattern is the regular expression of semver.org with an optional v.
+var semVerPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// ParseSemVer parses a semantic version with an optional leading v.
+func ParseSemVer(s string) (SemVer, error) {
+	m := semVerPattern.FindStringSubmatch(s)
+	if m == nil {
+		return SemVer{}, fmt.Errorf("%q is not a semantic version like 1.2.3 or v1.2.3-rc.1+abc", s)
+	}
+
+	var nums [3]int
+	for i := range nums {
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return SemVer{}, fmt.Errorf("%s: %s", m[i+1], err)
+		}
+		nums[i] = n
+	}
+	return SemVer{Major: nums[0], Minor: nums[1], Patch: nums[2], Prerelease: m[4], Metadata: m[5]}, nil
+}
+
+// String returns the version without the v, like 2.3.1-rc.2+sha.abc.
+func (sv SemVer) String() string {
+	s := fmt.Sprintf("%d.%d.%d", sv.Major, sv.Minor, sv.Patch)
+	if sv.Prerelease != "" {
+		s += "-" + sv.Prerelease
+	}
+	if sv.Metadata != "" {
+		s += "+" + sv.Metadata
+	}
+	return s
+}
+
+// PrereleaseScheme tells SetSemVer how the prerelease becomes the Build
+// number of the file version.
+type PrereleaseScheme string
+
+// Schemes for the Build number.
+const (
+	// PrereleaseNone leaves Build at zero.
+	PrereleaseNone PrereleaseScheme = ""
+
+	// PrereleaseNumber uses the last number of the prerelease, 2 for rc.2.
+	PrereleaseNumber PrereleaseScheme = "number"
+
+	// PrereleaseStage counts alpha.N as 1000+N, beta.N as 2000+N and rc.N as
+	// 3000+N, so prereleases of a version sort in order.
+	PrereleaseStage PrereleaseScheme = "stage"
+)
+
+// prereleaseStages are the stages of PrereleaseStage.
+var prereleaseStages = map[string]int{"alpha": 1, "beta": 2, "rc": 3}
+
+// build returns the Build number of the prerelease.
+func (scheme PrereleaseScheme) build(prerelease string) (int, error) {
+	if scheme == PrereleaseNone || prerelease == "" {
+		return 0, nil
+	}
+
+	ids := strings.Split(prerelease, ".")
+	n, hasNumber := 0, false
+	for i := len(ids) - 1; i >= 0 && !hasNumber; i-- {
+		if v, err := strconv.Atoi(ids[i]); err == nil {
+			n, hasNumber = v, true
+		}
+	}
+
+	switch scheme {
+	case PrereleaseNumber:
+		if !hasNumber {
+			return 0, fmt.Errorf("prerelease %s has no number for the Build", prerelease)
+		}
+		return n, nil
+	case PrereleaseStage:
+		stage, ok := prereleaseStages[strings.ToLower(ids[0])]
+		if !ok {
+			return 0, fmt.Errorf("prerelease %s does not start with alpha, beta or rc", prerelease)
+		}
+		if n >= 1000 {
+			return 0, fmt.Errorf("prerelease %s has a number above 999", prerelease)
+		}
+		return stage*1000 + n, nil
+	}
+	return 0, fmt.Errorf("unknown prerelease scheme %q, expected number or stage", scheme)
+}
+
+// SetSemVer sets the file and product versions to the one of sv, with the
+// Build number of the prerelease scheme, and keeps version, the original
+// string, as StringFileInfo.ProductVersion. A prerelease sets
+// VS_FF_PRERELEASE. The build metadata goes to metadataString, SpecialBuild
+// or PrivateBuild, and sets the flag of the string.
+func (vi *VersionInfo) SetSemVer(version string, scheme PrereleaseScheme, metadataString string) error {
+	sv, err := ParseSemVer(version)
+	if err != nil {
+		return err
+	}
+	build, err := scheme.build(sv.Prerelease)
+	if err != nil {
+		return err
+	}
+	var metadata *string
+	switch metadataString {
+	case "", "SpecialBuild":
+		metadata, metadataString = &vi.StringFileInfo.SpecialBuild, "SpecialBuild"
+	case "PrivateBuild":
+		metadata = &vi.StringFileInfo.PrivateBuild
+	default:
+		return fmt.Errorf("build metadata can not go to %q, expected SpecialBuild or PrivateBuild", metadataString)
+	}
+
+	v := FileVersion{Major: sv.Major, Minor: sv.Minor, Patch: sv.Patch, Build: build}
+	vi.FixedFileInfo.FileVersion = v
+	vi.FixedFileInfo.ProductVersion = v
+	vi.StringFileInfo.FileVersion = v.GetVersionString()
+	vi.StringFileInfo.ProductVersion = version
+
+	if sv.Prerelease != "" {
+		vi.FixedFileInfo.setFileFlag("VS_FF_PRERELEASE")
+	}
+	if sv.Metadata != "" {
+		*metadata = sv.Metadata
+		vi.FixedFileInfo.setFileFlag("VS_FF_" + strings.ToUpper(metadataString))
+	}
+	return nil
+}
+
+// versionPattern matches the start of a version that is not semantic, with
+// three or four parts like 6.3.9600.16384 (winblue_rtm).
+var versionPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// parseVersionString parses a version string for NewFileVersion and the
+// StringFileInfo versions. Semantic versions like v1.2.3-rc.1 have no Build,
+// which the returned bool tells. Other strings only need to start with a
+// version, like 6.3.9600.16384 (winblue_rtm).
+func parseVersionString(s string) (FileVersion, bool, error) {
+	if sv, err := ParseSemVer(s); err == nil {
+		return FileVersion{Major: sv.Major, Minor: sv.Minor, Patch: sv.Patch}, true, nil
+	}
+
+	comps := versionPattern.FindStringSubmatch(s)
+	if comps == nil {
+		return FileVersion{}, false, fmt.Errorf("version expected to start from x.y.z")
+	}
+	var nums [4]int
+	for i, c := range comps[1:] {
+		if c == "" {
+			continue
+		}
+		n, err := strconv.Atoi(c)
+		if err != nil {
+			return FileVersion{}, false, fmt.Errorf("%s: %s", c, err)
+		}
+		nums[i] = n
+	}
+	return FileVersion{Major: nums[0], Minor: nums[1], Patch: nums[2], Build: nums[3]}, false, nil
+}
@@ -0,0 +1,144 @@
+package goversioninfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSemVer(t *testing.T) {
+	tests := []struct {
+		in  string
+		out SemVer
+		err string
+	}{
+		{"1.2.3", SemVer{Major: 1, Minor: 2, Patch: 3}, ""},
+		{"v2.3.1-rc.2+sha.abc", SemVer{2, 3, 1, "rc.2", "sha.abc"}, ""},
+		{"v0.0.1-alpha", SemVer{Patch: 1, Prerelease: "alpha"}, ""},
+		{"10.20.30+build.5-x", SemVer{10, 20, 30, "", "build.5-x"}, ""},
+		{"1.0.0-x.7.z.92", SemVer{1, 0, 0, "x.7.z.92", ""}, ""},
+
+		{"1.2", SemVer{}, `"1.2" is not a semantic version like 1.2.3 or v1.2.3-rc.1+abc`},
+		{"01.2.3", SemVer{}, `"01.2.3" is not a semantic version like 1.2.3 or v1.2.3-rc.1+abc`},
+		{"1.2.3.4", SemVer{}, `"1.2.3.4" is not a semantic version like 1.2.3 or v1.2.3-rc.1+abc`},
+		{"1.2.3-rc.01", SemVer{}, `"1.2.3-rc.01" is not a semantic version like 1.2.3 or v1.2.3-rc.1+abc`},
+		{"1.2.3+", SemVer{}, `"1.2.3+" is not a semantic version like 1.2.3 or v1.2.3-rc.1+abc`},
+		{"V1.2.3", SemVer{}, `"V1.2.3" is not a semantic version like 1.2.3 or v1.2.3-rc.1+abc`},
+	}
+	for _, tt := range tests {
+		got, err := ParseSemVer(tt.in)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err)
+			continue
+		}
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.out, got, tt.in)
+		assert.Equal(t, tt.in[len(tt.in)-len(got.String()):], got.String())
+	}
+}
+
+func TestPrereleaseScheme(t *testing.T) {
+	tests := []struct {
+		scheme     PrereleaseScheme
+		prerelease string
+		build      int
+		err        string
+	}{
+		{PrereleaseNone, "rc.2", 0, ""},
+		{PrereleaseNumber, "", 0, ""},
+		{PrereleaseNumber, "rc.2", 2, ""},
+		{PrereleaseNumber, "beta.11.hotfix", 11, ""},
+		{PrereleaseNumber, "rc", 0, "prerelease rc has no number for the Build"},
+		{PrereleaseStage, "alpha", 1000, ""},
+		{PrereleaseStage, "beta.3", 2003, ""},
+		{PrereleaseStage, "RC.12", 3012, ""},
+		{PrereleaseStage, "preview.1", 0, "prerelease preview.1 does not start with alpha, beta or rc"},
+		{PrereleaseStage, "rc.1000", 0, "prerelease rc.1000 has a number above 999"},
+		{"date", "rc.1", 0, `unknown prerelease scheme "date", expected number or stage`},
+	}
+	for _, tt := range tests {
+		build, err := tt.scheme.build(tt.prerelease)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tt.build, build, "%s %s", tt.scheme, tt.prerelease)
+	}
+}
+
+func TestSetSemVer(t *testing.T) {
+	jsonBytes, err := os.ReadFile("testdata/json/cmd.json")
+	assert.NoError(t, err)
+
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON(jsonBytes))
+	assert.NoError(t, vi.SetSemVer("v2.3.1-rc.2+sha.abc", PrereleaseStage, ""))
+	assert.Equal(t, FileVersion{2, 3, 1, 3002}, vi.FixedFileInfo.FileVersion)
+	assert.Equal(t, FileVersion{2, 3, 1, 3002}, vi.FixedFileInfo.ProductVersion)
+	assert.Equal(t, "2.3.1.3002", vi.StringFileInfo.FileVersion)
+	assert.Equal(t, "v2.3.1-rc.2+sha.abc", vi.StringFileInfo.ProductVersion)
+	assert.Equal(t, "sha.abc", vi.StringFileInfo.SpecialBuild)
+	assert.Equal(t, "00|VS_FF_PRERELEASE|VS_FF_SPECIALBUILD", vi.FixedFileInfo.FileFlags)
+	assert.Empty(t, vi.Validate())
+
+	vi = &VersionInfo{}
+	vi.FixedFileInfo.FileFlagsMask = "VS_FFI_FILEFLAGSMASK"
+	assert.NoError(t, vi.SetSemVer("1.4.0+ci.77", PrereleaseNumber, "PrivateBuild"))
+	assert.Equal(t, FileVersion{1, 4, 0, 0}, vi.FixedFileInfo.FileVersion)
+	assert.Equal(t, "ci.77", vi.StringFileInfo.PrivateBuild)
+	assert.Equal(t, "", vi.StringFileInfo.SpecialBuild)
+	assert.Equal(t, "VS_FF_PRIVATEBUILD", vi.FixedFileInfo.FileFlags)
+	assert.Empty(t, vi.Validate())
+
+	assert.EqualError(t, vi.SetSemVer("1.4", PrereleaseNone, ""), `"1.4" is not a semantic version like 1.2.3 or v1.2.3-rc.1+abc`)
+	assert.EqualError(t, vi.SetSemVer("1.4.0-rc", PrereleaseNumber, ""), "prerelease rc has no number for the Build")
+	assert.EqualError(t, vi.SetSemVer("1.4.0", PrereleaseNone, "Comments"), `build metadata can not go to "Comments", expected SpecialBuild or PrivateBuild`)
+}
+
+func TestSemVerVersionStrings(t *testing.T) {
+	// Semantic versions have no Build to compare.
+	vi := &VersionInfo{}
+	vi.FixedFileInfo.FileVersion = FileVersion{1, 2, 3, 9}
+	vi.StringFileInfo.FileVersion = "v1.2.3-beta.9"
+	assert.Empty(t, vi.Validate())
+
+	vi.StringFileInfo.FileVersion = "v1.2.4"
+	assert.Equal(t, []Issue{{SeverityWarning, "StringFileInfo.FileVersion",
+		"v1.2.4 and the FixedFileInfo version 1.2.3.9 do not match"}}, vi.Validate())
+
+	// They fill an empty FixedFileInfo version.
+	vi = &VersionInfo{}
+	vi.StringFileInfo.ProductVersion = "v4.5.6+abc"
+	assert.NoError(t, vi.Build())
+	assert.Equal(t, FileVersion{4, 5, 6, 0}, vi.FixedFileInfo.ProductVersion)
+}
+
+func TestRunCLISemVer(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "semver")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	cfg := NewCLIConfig()
+	cfg.ConfigFile = "testdata/json/cmd.json"
+	cfg.OutputFile = filepath.Join(tmpdir, "resource.syso")
+	cfg.SemVer = "v2.3.1-rc.2+sha.abc"
+	cfg.SemVerPrerelease = "number"
+	cfg.Strict = true
+	cfg.WarningsAsErrors = true
+	assert.NoError(t, RunCLI(cfg))
+
+	res, err := ReadResources(cfg.OutputFile)
+	assert.NoError(t, err)
+	decoded, err := DecodeVersionInfo(res[0].Data)
+	assert.NoError(t, err)
+	assert.Equal(t, FileVersion{2, 3, 1, 2}, decoded.FixedFileInfo.FileVersion)
+	assert.Equal(t, "v2.3.1-rc.2+sha.abc", decoded.StringFileInfo.ProductVersion)
+	assert.Equal(t, "sha.abc", decoded.StringFileInfo.SpecialBuild)
+	assert.Equal(t, "22", decoded.FixedFileInfo.FileFlags)
+
+	cfg.SemVer = "2.3"
+	assert.EqualError(t, RunCLI(cfg), `invalid -semver: "2.3" is not a semantic version like 1.2.3 or v1.2.3-rc.1+abc`)
+}
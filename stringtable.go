@@ -0,0 +1,123 @@
+package goversioninfo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf16"
+)
+
+// *****************************************************************************
+// String Tables
+// *****************************************************************************
+
+/*
+STRINGTABLE resource
+https://learn.microsoft.com/en-us/windows/win32/menurc/stringtable-resource
+
+RT_STRING resources hold 16 strings each. The string with ID n is string n%16
+of the block with ID n/16+1, and every string is a count of UTF-16 code units
+followed by the text, without a terminating zero. Missing strings have a count
+of 0, so LoadString can not tell them from empty ones.
+*/
+
+const rtString = 6
+
+// StringResource holds the strings of one language that a program loads with
+// LoadString, not to be confused with the StringTables of the version info.
+type StringResource struct {
+	// LangID is the language of the strings, U.S. English when it is not
+	// set.
+	LangID *LangID `json:",omitempty"`
+
+	// Strings maps the IDs to the strings. In JSON the IDs are the keys of
+	// an object, like {"101": "Hello"}.
+	Strings map[uint16]string
+}
+
+// lang returns the language the strings are stored in.
+func (sr StringResource) lang() LangID {
+	if sr.LangID != nil {
+		return *sr.LangID
+	}
+	return LngUSEnglish
+}
+
+// stringBlock is one RT_STRING resource.
+type stringBlock struct {
+	id   uint16
+	data []byte
+}
+
+// stringBlocks packs the strings into their blocks, in the order of the IDs.
+func stringBlocks(strs map[uint16]string) ([]stringBlock, error) {
+	ids := make([]int, 0, len(strs))
+	for id := range strs {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+
+	var blocks []stringBlock
+	for len(ids) > 0 {
+		first := ids[0] &^ 0xf
+		var b []byte
+		for n := first; n < first+16; n++ {
+			s := ""
+			if len(ids) > 0 && ids[0] == n {
+				s = strs[uint16(n)]
+				ids = ids[1:]
+			}
+			size := len(utf16.Encode([]rune(s)))
+			if size > 0xffff {
+				return nil, fmt.Errorf("string %d is longer than 65535 UTF-16 code units", n)
+			}
+			b = binary.LittleEndian.AppendUint16(b, uint16(size))
+			b = append(b, padString(s, 0)...)
+		}
+		blocks = append(blocks, stringBlock{uint16(first/16 + 1), b})
+	}
+	return blocks, nil
+}
+
+// decodeStringBlock adds the strings of the RT_STRING resource with the
+// block ID id to strs. Empty strings are left out.
+func decodeStringBlock(strs map[uint16]string, id uint16, data []byte) error {
+	if id == 0 {
+		return errors.New("block ID 0 holds no strings")
+	}
+	for n := 0; n < 16; n++ {
+		if len(data) < 2 {
+			return fmt.Errorf("string %d runs past the end of the block", int(id-1)*16+n)
+		}
+		size := int(binary.LittleEndian.Uint16(data)) * 2
+		data = data[2:]
+		if len(data) < size {
+			return fmt.Errorf("string %d runs past the end of the block", int(id-1)*16+n)
+		}
+		if size > 0 {
+			u16 := make([]uint16, size/2)
+			for i := range u16 {
+				u16[i] = binary.LittleEndian.Uint16(data[2*i:])
+			}
+			strs[uint16(int(id-1)*16+n)] = string(utf16.Decode(u16))
+		}
+		data = data[size:]
+	}
+	return nil
+}
+
+// addStringResources packs the strings of every language into RT_STRING
+// resources.
+func addStringResources(res *Resources, tables []StringResource) error {
+	for _, st := range tables {
+		blocks, err := stringBlocks(st.Strings)
+		if err != nil {
+			return err
+		}
+		for _, b := range blocks {
+			*res = append(*res, Resource{Type: ResourceID{ID: rtString}, Name: ResourceID{ID: b.id}, LangID: st.lang(), Data: b.data})
+		}
+	}
+	return nil
+}
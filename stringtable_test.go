@@ -0,0 +1,114 @@
+package goversioninfo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringBlocks(t *testing.T) {
+	blocks, err := stringBlocks(map[uint16]string{1: "A", 15: "", 17: "Bé", 65535: "Z"})
+	assert.NoError(t, err)
+	if assert.Len(t, blocks, 3) {
+		assert.Equal(t, uint16(1), blocks[0].id)
+		assert.Equal(t, []byte{0, 0, 1, 0, 'A', 0}, blocks[0].data[:6])
+		assert.Len(t, blocks[0].data, 16*2+2)
+
+		assert.Equal(t, uint16(2), blocks[1].id)
+		assert.Equal(t, []byte{0, 0, 2, 0, 'B', 0, 0xe9, 0}, blocks[1].data[:8])
+
+		assert.Equal(t, uint16(4096), blocks[2].id)
+	}
+
+	strs := map[uint16]string{}
+	for _, b := range blocks {
+		assert.NoError(t, decodeStringBlock(strs, b.id, b.data))
+	}
+	assert.Equal(t, map[uint16]string{1: "A", 17: "Bé", 65535: "Z"}, strs)
+
+	assert.EqualError(t, decodeStringBlock(strs, 1, []byte{1, 0}), "string 0 runs past the end of the block")
+	assert.EqualError(t, decodeStringBlock(strs, 0, nil), "block ID 0 holds no strings")
+
+	_, err = stringBlocks(map[uint16]string{3: strings.Repeat("x", 0x10000)})
+	assert.EqualError(t, err, "string 3 is longer than 65535 UTF-16 code units")
+}
+
+func TestStringResources(t *testing.T) {
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON([]byte(`{"StringResources": [
+		{"Strings": {"101": "Hello", "102": "Goodbye"}},
+		{"LangID": "0407", "Strings": {"101": "Hallo"}}
+	]}`)))
+	german := LngGerman
+	assert.Equal(t, []StringResource{
+		{Strings: map[uint16]string{101: "Hello", 102: "Goodbye"}},
+		{LangID: &german, Strings: map[uint16]string{101: "Hallo"}},
+	}, vi.StringResources)
+	assert.Empty(t, vi.Validate())
+
+	assert.NoError(t, vi.Build())
+	vi.Walk()
+	res, err := vi.Resources()
+	assert.NoError(t, err)
+	var blocks []string
+	for _, r := range res {
+		if r.Type == (ResourceID{ID: rtString}) {
+			blocks = append(blocks, fmt.Sprintf("%s %04x", r.Name, uint16(r.LangID)))
+		}
+	}
+	assert.Equal(t, []string{"7 0409", "7 0407"}, blocks)
+
+	er, err := newExecutableResources(res)
+	assert.NoError(t, err)
+	assert.Equal(t, vi.StringResources, er.StringResources)
+
+	// Languages listed twice and strings that take the block of a resource
+	vi.StringResources = append(vi.StringResources, StringResource{Strings: map[uint16]string{103: ""}})
+	vi.FileResources = []FileResource{{Type: ResourceID{Name: "RT_STRING"}, ID: ResourceID{ID: 7}, Path: "testdata/resource/icon.ico"}}
+	assert.Equal(t, []Issue{
+		{SeverityError, "Resources[0].ID", "RT_STRING 7 is already used by StringResources[2]"},
+		{SeverityError, "StringResources[2].LangID", "0409 is already used by StringResources[0]"},
+		{SeverityWarning, "StringResources[2].Strings.103", "is empty, LoadString can not tell it from a missing string"},
+	}, vi.Validate())
+
+	vi.FileResources = nil
+	_, err = vi.Resources()
+	assert.EqualError(t, err, "RT_STRING 7 language 0409 is defined twice")
+}
+
+func TestStringResourcesRC(t *testing.T) {
+	german := LngGerman
+	vi := &VersionInfo{StringResources: []StringResource{
+		{Strings: map[uint16]string{102: "Goodbye", 101: `Say "hi"`}},
+		{LangID: &german, Strings: map[uint16]string{101: "Hallo"}},
+	}}
+	b, err := vi.rc()
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "\nSTRINGTABLE\nBEGIN\n    101, \"Say \"\"hi\"\"\"\n    102, \"Goodbye\"\nEND\n"+
+		"\nSTRINGTABLE\nLANGUAGE 0x07, 0x01\nBEGIN\n    101, \"Hallo\"\nEND\n")
+
+	parsed := &VersionInfo{}
+	assert.NoError(t, parsed.ParseRC(b))
+	assert.Equal(t, vi.StringResources, parsed.StringResources)
+
+	// Tables of the same language are joined, like rc.exe does
+	parsed = &VersionInfo{}
+	assert.NoError(t, parsed.ParseRC([]byte("1 VERSIONINFO\nBEGIN\nEND\n"+
+		"LANGUAGE LANG_ENGLISH, SUBLANG_ENGLISH_US\nSTRINGTABLE DISCARDABLE\n{\n 1 \"one\"\n}\n"+
+		"#define IDS_TWO 2\nSTRINGTABLE\nBEGIN\n IDS_TWO, \"two\"\nEND\n")))
+	assert.Equal(t, []StringResource{{Strings: map[uint16]string{1: "one", 2: "two"}}}, parsed.StringResources)
+
+	tests := []struct {
+		script string
+		msg    string
+	}{
+		{"STRINGTABLE\nBEGIN\n1, \"a\"\n1, \"b\"\nEND", "line 4: string 1 is defined twice"},
+		{"STRINGTABLE\nBEGIN\n1, 2\nEND", "line 3: expected a string, found 2"},
+		{"STRINGTABLE\nBEGIN\n70000, \"a\"\nEND", "line 3: string ID 70000 does not fit in 16 bits"},
+	}
+	for _, tt := range tests {
+		assert.EqualError(t, (&VersionInfo{}).ParseRC([]byte(tt.script)), tt.msg)
+	}
+}
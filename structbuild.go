@@ -1,6 +1,7 @@
 package goversioninfo
 
 import (
+	"fmt"
 	"reflect"
 )
 
@@ -53,15 +54,14 @@ type VSFixedFileInfo struct {
 	DwFileDateLS       uint32
 }
 
-// VSStringFileInfo holds multiple collections of keys and values,
-// only allows for 1 collection in this package.
+// VSStringFileInfo holds one collection of keys and values per translation.
 type VSStringFileInfo struct {
 	WLength      uint16
 	WValueLength uint16
 	WType        uint16
 	SzKey        []byte
 	Padding      []byte
-	Children     VSStringTable
+	Children     []VSStringTable
 }
 
 // VSStringTable holds a collection of string keys and values.
@@ -84,7 +84,7 @@ type VSString struct {
 	Value        []byte
 }
 
-// VSVarFileInfo holds the translation collection of 1.
+// VSVarFileInfo holds the translation collection.
 type VSVarFileInfo struct {
 	WLength      uint16
 	WValueLength uint16
@@ -94,20 +94,29 @@ type VSVarFileInfo struct {
 	Value        VSVar
 }
 
-// VSVar holds the translation key.
+// VSVar holds the translation key and one value per language and code page pair.
 type VSVar struct {
 	WLength      uint16
 	WValueLength uint16
 	WType        uint16
 	SzKey        []byte
 	Padding      []byte
-	Value        uint32
+	Value        []uint32
 }
 
-func buildString(i int, v reflect.Value) (VSString, bool) {
-	sValue := string(v.Field(i).Interface().(string))
-	sName := v.Type().Field(i).Name
+// maxLength is the largest structure WLength can describe.
+const maxLength = 0xffff
 
+// length checks that a structure of n bytes fits in WLength. path names the
+// structure like the decoder does, as in VS_VERSION_INFO/StringFileInfo.
+func length(path string, n int) (uint16, error) {
+	if n > maxLength {
+		return 0, fmt.Errorf("%s is %d bytes long, more than the %d a version info structure can hold", path, n, maxLength)
+	}
+	return uint16(n), nil
+}
+
+func buildString(path, sName, sValue string) (VSString, bool, error) {
 	ss := VSString{}
 
 	// If the value is set
@@ -135,20 +144,23 @@ func buildString(i int, v reflect.Value) (VSString, bool) {
 		// Create value
 		ss.Value = padString(sValue, zeros)
 
-		// Length of text in words (2 bytes) plus zero terminate word
-		ss.WValueLength = uint16(len(padString(sValue, 0))/2) + 1
-
 		// Length of structure
-		//ss.WLength = 6 + uint16(soFar) + (ss.WValueLength * 2)
-		ss.WLength = uint16(6 + soFar + len(ss.Value))
+		var err error
+		if ss.WLength, err = length(path+"/"+sName, 6+soFar+len(ss.Value)); err != nil {
+			return ss, false, err
+		}
+
+		// Length of text in words (2 bytes) plus zero terminate word, which
+		// is shorter than the structure
+		ss.WValueLength = uint16(len(padString(sValue, 0))/2) + 1
 
-		return ss, true
+		return ss, true, nil
 	}
 
-	return ss, false
+	return ss, false, nil
 }
 
-func buildStringTable(vi *VersionInfo) VSStringTable {
+func buildStringTable(path string, t Translation, sfi StringFileInfo) (VSStringTable, error) {
 	st := VSStringTable{}
 
 	// Always set to 0
@@ -158,7 +170,8 @@ func buildStringTable(vi *VersionInfo) VSStringTable {
 	st.WType = 0x01
 
 	// Language identifier and Code page
-	st.SzKey = padString(vi.VarFileInfo.Translation.getTranslationString(), 0)
+	path += "/" + t.getTranslationString()
+	st.SzKey = padString(t.getTranslationString(), 0)
 
 	// Align to 32-bit boundary
 	soFar := 2
@@ -168,22 +181,36 @@ func buildStringTable(vi *VersionInfo) VSStringTable {
 	st.Padding = padBytes(soFar)
 	soFar += len(st.SzKey)
 
-	// Loop through the struct fields
-	v := reflect.ValueOf(vi.StringFileInfo)
+	// Standard string fields come first, custom keys follow them
+	var pairs []StringPair
+	v := reflect.ValueOf(sfi)
 	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).Kind() == reflect.String {
+			pairs = append(pairs, StringPair{v.Type().Field(i).Name, v.Field(i).String()})
+		}
+	}
+	pairs = append(pairs, sfi.Custom...)
+
+	n := 6 + soFar
+	for _, pair := range pairs {
+		r, ok, err := buildString(path, pair.Key, pair.Value)
+		if err != nil {
+			return st, err
+		}
 		// If the struct is valid
-		if r, ok := buildString(i, v); ok {
+		if ok {
 			st.Children = append(st.Children, r)
-			st.WLength += r.WLength
+			n += int(r.WLength)
 		}
 	}
 
-	st.WLength += 6 + uint16(soFar)
-
-	return st
+	var err error
+	st.WLength, err = length(path, n)
+	return st, err
 }
 
-func buildStringFileInfo(vi *VersionInfo) VSStringFileInfo {
+func buildStringFileInfo(vi *VersionInfo) (VSStringFileInfo, error) {
+	const path = "VS_VERSION_INFO/StringFileInfo"
 	sf := VSStringFileInfo{}
 
 	// Always set to 0
@@ -202,16 +229,25 @@ func buildStringFileInfo(vi *VersionInfo) VSStringFileInfo {
 	sf.Padding = padBytes(soFar)
 	soFar += len(sf.SzKey)
 
-	// Allows for more than one string table
-	st := buildStringTable(vi)
-	sf.Children = st
+	// The default table comes first, followed by the localized ones
+	tables := append([]StringTable{{vi.VarFileInfo.Translation.first(), vi.StringFileInfo}}, vi.StringTables...)
 
-	sf.WLength = 6 + uint16(soFar) + st.WLength
+	n := 6 + soFar
+	for _, t := range tables {
+		st, err := buildStringTable(path, t.Translation, t.StringFileInfo)
+		if err != nil {
+			return sf, err
+		}
+		sf.Children = append(sf.Children, st)
+		n += int(st.WLength)
+	}
 
-	return sf
+	var err error
+	sf.WLength, err = length(path, n)
+	return sf, err
 }
 
-func buildVar(vfi VarFileInfo) VSVar {
+func buildVar(vfi VarFileInfo) (VSVar, error) {
 	vs := VSVar{}
 
 	// 0 for binary, 1 for text
@@ -228,19 +264,28 @@ func buildVar(vfi VarFileInfo) VSVar {
 	vs.Padding = padBytes(soFar)
 	soFar += len(vs.SzKey)
 
-	// Create value
-	vs.Value = str2Uint32(vfi.Translation.getTranslation())
-
-	// Length of text in bytes
-	vs.WValueLength = 4
+	// Create value, always holding at least one pair
+	translations := vfi.Translation
+	if len(translations) == 0 {
+		translations = Translations{Translation{}}
+	}
+	for _, t := range translations {
+		vs.Value = append(vs.Value, str2Uint32(t.getTranslation()))
+	}
 
 	// Length of structure
-	vs.WLength = 6 + vs.WValueLength + uint16(soFar)
+	var err error
+	if vs.WLength, err = length("VS_VERSION_INFO/VarFileInfo/Translation", 6+4*len(vs.Value)+soFar); err != nil {
+		return vs, err
+	}
+
+	// Length of value in bytes
+	vs.WValueLength = uint16(4 * len(vs.Value))
 
-	return vs
+	return vs, nil
 }
 
-func buildVarFileInfo(vfi VarFileInfo) VSVarFileInfo {
+func buildVarFileInfo(vfi VarFileInfo) (VSVarFileInfo, error) {
 	vf := VSVarFileInfo{}
 
 	// Always set to 0
@@ -259,15 +304,17 @@ func buildVarFileInfo(vfi VarFileInfo) VSVarFileInfo {
 	vf.Padding = padBytes(soFar)
 	soFar += len(vf.SzKey)
 
-	// TODO Allow for more than one var table
-	st := buildVar(vfi)
+	st, err := buildVar(vfi)
+	if err != nil {
+		return vf, err
+	}
 	vf.Value = st
-	vf.WLength = 6 + st.WLength + uint16(soFar)
+	vf.WLength, err = length("VS_VERSION_INFO/VarFileInfo", 6+int(st.WLength)+soFar)
 
-	return vf
+	return vf, err
 }
 
-func buildFixedFileInfo(vi *VersionInfo) VSFixedFileInfo {
+func buildFixedFileInfo(vi *VersionInfo) (VSFixedFileInfo, error) {
 	ff := VSFixedFileInfo{}
 	ff.DwSignature = 0xFEEF04BD
 	ff.DwStrucVersion = 0x00010000
@@ -275,24 +322,36 @@ func buildFixedFileInfo(vi *VersionInfo) VSFixedFileInfo {
 	ff.DwFileVersionLS = str2Uint32(vi.FixedFileInfo.FileVersion.getVersionLowString())
 	ff.DwProductVersionMS = str2Uint32(vi.FixedFileInfo.ProductVersion.getVersionHighString())
 	ff.DwProductVersionLS = str2Uint32(vi.FixedFileInfo.ProductVersion.getVersionLowString())
-	ff.DwFileFlagsMask = str2Uint32(vi.FixedFileInfo.FileFlagsMask)
-	ff.DwFileFlags = str2Uint32(vi.FixedFileInfo.FileFlags)
-	ff.DwFileOS = str2Uint32(vi.FixedFileInfo.FileOS)
-	ff.DwFileType = str2Uint32(vi.FixedFileInfo.FileType)
-	ff.DwFileSubtype = str2Uint32(vi.FixedFileInfo.FileSubType)
-
-	// According to the spec, these should be zero...ugh
-	/*if vi.Timestamp {
-		now := syscall.NsecToFiletime(time.Now().UnixNano())
-		ff.DwFileDateMS = now.HighDateTime
-		ff.DwFileDateLS = now.LowDateTime
-	}*/
-
-	return ff
+
+	// The flags and types may be hex numbers or winver.h names
+	values, _ := vi.FixedFileInfo.values()
+	ff.DwFileFlagsMask = values.FileFlagsMask
+	ff.DwFileFlags = values.FileFlags
+	ff.DwFileOS = values.FileOS
+	ff.DwFileType = values.FileType
+	ff.DwFileSubtype = values.FileSubType
+
+	// The date is opt-in, most files leave it zero
+	date, err := vi.fileDate()
+	if err != nil {
+		return ff, err
+	}
+	ff.DwFileDateMS = uint32(date >> 32)
+	ff.DwFileDateLS = uint32(date)
+
+	return ff, nil
 }
 
-// Build fills the structs with data from the config file
-func (v *VersionInfo) Build() {
+// Build fills the structs with data from the config file. Whatever the mode,
+// it fails when the strings do not fit in the 64 KiB a version info can hold
+// or the file date can not be read. The issues Validate finds are only
+// checked with Strict or WarningsAsErrors set, and returned as a
+// *ValidationError; otherwise Build carries on with zero values.
+func (v *VersionInfo) Build() error {
+	if err := v.check(); err != nil {
+		return err
+	}
+
 	v.fillVersions()
 
 	vi := VSVersionInfo{}
@@ -311,7 +370,10 @@ func (v *VersionInfo) Build() {
 	vi.Padding1 = padBytes(soFar)
 	soFar += len(vi.SzKey)
 
-	vi.Value = buildFixedFileInfo(v)
+	var err error
+	if vi.Value, err = buildFixedFileInfo(v); err != nil {
+		return err
+	}
 
 	// Length of VSFixedFileInfo (always the same)
 	vi.WValueLength = 0x34
@@ -320,13 +382,22 @@ func (v *VersionInfo) Build() {
 	vi.Padding2 = []byte{}
 
 	// Build strings
-	vi.Children = buildStringFileInfo(v)
+	if vi.Children, err = buildStringFileInfo(v); err != nil {
+		return err
+	}
 
 	// Build translation
-	vi.Children2 = buildVarFileInfo(v.VarFileInfo)
+	if vi.Children2, err = buildVarFileInfo(v.VarFileInfo); err != nil {
+		return err
+	}
 
 	// Calculate the total size
-	vi.WLength += 6 + uint16(soFar) + vi.WValueLength + vi.Children.WLength + vi.Children2.WLength
+	n := 6 + soFar + int(vi.WValueLength) + int(vi.Children.WLength) + int(vi.Children2.WLength)
+	if vi.WLength, err = length("VS_VERSION_INFO", n); err != nil {
+		return err
+	}
 
 	v.Structure = vi
+
+	return nil
 }
@@ -0,0 +1,103 @@
+package goversioninfo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildLengthOverflow(t *testing.T) {
+	vi := &VersionInfo{}
+	vi.StringFileInfo.Comments = strings.Repeat("x", 32600)
+	assert.NoError(t, vi.Build())
+	vi.Walk()
+	assert.Equal(t, vi.Buffer.Len(), int(vi.Structure.WLength))
+
+	// A string can be too long on its own.
+	vi.StringFileInfo.Comments = strings.Repeat("x", 32767)
+	assert.EqualError(t, vi.Build(), "VS_VERSION_INFO/StringFileInfo/00000000/Comments is 65560 bytes long, more than the 65535 a version info structure can hold")
+
+	// Strings that fit on their own can overflow their table.
+	vi = &VersionInfo{}
+	vi.VarFileInfo.Translation = Translations{{LngUSEnglish, CsUnicode}}
+	for i := 0; i < 4; i++ {
+		vi.StringFileInfo.Custom.Set(fmt.Sprintf("Part%d", i), strings.Repeat("x", 8200))
+	}
+	assert.EqualError(t, vi.Build(), "VS_VERSION_INFO/StringFileInfo/040904B0 is 65720 bytes long, more than the 65535 a version info structure can hold")
+
+	// Tables that fit on their own can overflow the StringFileInfo.
+	vi = &VersionInfo{}
+	vi.StringFileInfo.Comments = strings.Repeat("x", 20000)
+	vi.StringTables = []StringTable{
+		{Translation{LngGerman, CsUnicode}, StringFileInfo{Comments: strings.Repeat("y", 20000)}},
+	}
+	assert.EqualError(t, vi.Build(), "VS_VERSION_INFO/StringFileInfo is 80140 bytes long, more than the 65535 a version info structure can hold")
+
+	// Everything together can overflow the version info.
+	vi = &VersionInfo{}
+	vi.StringFileInfo.Comments = strings.Repeat("x", 32700)
+	assert.EqualError(t, vi.Build(), "VS_VERSION_INFO is 65648 bytes long, more than the 65535 a version info structure can hold")
+
+	// Too many translations overflow the Translation value.
+	vi = &VersionInfo{}
+	vi.VarFileInfo.Translation = make(Translations, 16384)
+	assert.EqualError(t, vi.Build(), "VS_VERSION_INFO/VarFileInfo/Translation is 65568 bytes long, more than the 65535 a version info structure can hold")
+}
+
+func FuzzBuild(f *testing.F) {
+	f.Add("Comment", "Key", "Value", uint16(1))
+	f.Add("", "BuildCommit", "abc1234", uint16(30))
+	f.Add("Größe 😀", "Ключ", "価値", uint16(40))
+	f.Add(strings.Repeat("long", 100), "K", strings.Repeat("v", 1100), uint16(63))
+
+	f.Fuzz(func(t *testing.T, comments, key, value string, n uint16) {
+		if strings.ContainsRune(comments+key+value, 0) || key == "" {
+			t.Skip("NUL ends strings and an empty key is not a key")
+		}
+
+		vi := &VersionInfo{}
+		vi.VarFileInfo.Translation = Translations{{LngUSEnglish, CsUnicode}}
+		vi.StringFileInfo.Comments = comments
+		for i := 0; i < int(n%64); i++ {
+			vi.StringFileInfo.Custom.Set(fmt.Sprintf("%s%d", key, i), value)
+		}
+
+		// The strings alone tell if the version info can fit.
+		size := 2 * len(utf16.Encode([]rune(comments)))
+		for _, pair := range vi.StringFileInfo.Custom {
+			size += 2 * (len(utf16.Encode([]rune(pair.Key))) + len(utf16.Encode([]rune(pair.Value))))
+		}
+
+		if err := vi.Build(); err != nil {
+			assert.Contains(t, err.Error(), "more than the 65535 a version info structure can hold")
+			assert.Greater(t, size, maxLength-1024, "Build failed on %d bytes of strings", size)
+			return
+		}
+		assert.LessOrEqual(t, size, maxLength)
+		vi.Walk()
+
+		// Every structure lies within its parent and the root is the data.
+		b := vi.Buffer.Bytes()
+		assert.Equal(t, len(b), int(binary.LittleEndian.Uint16(b)))
+
+		decoded, err := DecodeVersionInfo(b)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, string([]rune(comments)), decoded.StringFileInfo.Comments)
+		if value == "" {
+			assert.Empty(t, decoded.StringFileInfo.Custom)
+			return
+		}
+		if assert.Len(t, decoded.StringFileInfo.Custom, len(vi.StringFileInfo.Custom)) {
+			for i, pair := range decoded.StringFileInfo.Custom {
+				assert.Equal(t, string([]rune(vi.StringFileInfo.Custom[i].Key)), pair.Key)
+				assert.Equal(t, string([]rune(value)), pair.Value)
+			}
+		}
+	})
+}
@@ -0,0 +1,329 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf16"
+)
+
+// *****************************************************************************
+// Structure Decoding
+// *****************************************************************************
+
+// fixedFileInfoSignature is the magic number that starts a VS_FIXEDFILEINFO.
+const fixedFileInfoSignature = 0xFEEF04BD
+
+// DecodeError describes malformed VS_VERSIONINFO data.
+type DecodeError struct {
+	// Offset is the position of the problem from the start of the data.
+	Offset int
+	// Path names the block being decoded, like VS_VERSION_INFO/StringFileInfo.
+	Path string
+	Msg  string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s at offset %#x: %s", e.Path, e.Offset, e.Msg)
+}
+
+// versionBlock is the header shared by every version information structure.
+type versionBlock struct {
+	path        string
+	offset      int // start of the block
+	end         int // offset just past WLength
+	valueLength int // raw WValueLength
+	typ         uint16
+	key         string
+	value       int // start of the value, aligned to 32 bits
+}
+
+type versionDecoder struct {
+	data []byte
+}
+
+// DecodeVersionInfo parses raw VS_VERSIONINFO bytes, like the data of an
+// RT_VERSION resource, back into a VersionInfo. The first string table that
+// matches the first translation fills StringFileInfo and the other ones are
+// returned in StringTables.
+func DecodeVersionInfo(data []byte) (*VersionInfo, error) {
+	d := &versionDecoder{data: data}
+
+	root, err := d.block(0, len(data), "")
+	if err != nil {
+		return nil, err
+	}
+	if root.key != "VS_VERSION_INFO" {
+		return nil, d.errorf(6, root.path, "key is %q, expected VS_VERSION_INFO", root.key)
+	}
+
+	vi := &VersionInfo{}
+	childStart := root.value
+	if root.valueLength != 0 {
+		size := binary.Size(VSFixedFileInfo{})
+		if root.valueLength != size {
+			return nil, d.errorf(root.offset+2, root.path,
+				"WValueLength is %d, expected %d for VS_FIXEDFILEINFO", root.valueLength, size)
+		}
+		if root.value+size > root.end {
+			return nil, d.errorf(root.value, root.path, "VS_FIXEDFILEINFO runs past the end of the block at %#x", root.end)
+		}
+		var ff VSFixedFileInfo
+		if err := binary.Read(bytes.NewReader(d.data[root.value:]), binary.LittleEndian, &ff); err != nil {
+			return nil, d.errorf(root.value, root.path, "%v", err)
+		}
+		if ff.DwSignature != fixedFileInfoSignature {
+			return nil, d.errorf(root.value, root.path+"/VS_FIXEDFILEINFO",
+				"signature is %#08x, expected %#08x", ff.DwSignature, fixedFileInfoSignature)
+		}
+		vi.FixedFileInfo = decodeFixedFileInfo(ff)
+		childStart = root.value + size
+	}
+
+	var tables []StringTable
+	err = d.children(root, childStart, func(c versionBlock) error {
+		switch c.key {
+		case "StringFileInfo":
+			return d.stringFileInfo(c, &tables)
+		case "VarFileInfo":
+			return d.varFileInfo(c, &vi.VarFileInfo)
+		}
+		// Unknown blocks are valid, they are just not part of a VersionInfo.
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vi.setStringTables(tables)
+
+	return vi, nil
+}
+
+func (d *versionDecoder) errorf(offset int, path, format string, args ...interface{}) error {
+	return &DecodeError{Offset: offset, Path: path, Msg: fmt.Sprintf(format, args...)}
+}
+
+// block reads the header of the structure at offset, which must not extend
+// past limit.
+func (d *versionDecoder) block(offset, limit int, parent string) (versionBlock, error) {
+	b := versionBlock{path: parent, offset: offset}
+	if b.path == "" {
+		b.path = "VS_VERSION_INFO"
+	}
+
+	if offset+6 > limit {
+		return b, d.errorf(offset, b.path, "block header runs past the end of the parent block at %#x", limit)
+	}
+	length := int(binary.LittleEndian.Uint16(d.data[offset:]))
+	if length < 6 {
+		return b, d.errorf(offset, b.path, "WLength is %d, smaller than the 6 byte header", length)
+	}
+	b.end = offset + length
+	if b.end > limit {
+		return b, d.errorf(offset, b.path, "WLength %d runs past the end of the parent block at %#x", length, limit)
+	}
+	b.valueLength = int(binary.LittleEndian.Uint16(d.data[offset+2:]))
+	b.typ = binary.LittleEndian.Uint16(d.data[offset+4:])
+
+	// The key is a NUL terminated UTF-16 string
+	var key []uint16
+	k := offset + 6
+	for {
+		if k+2 > b.end {
+			return b, d.errorf(offset+6, b.path, "key is not NUL terminated before the end of the block at %#x", b.end)
+		}
+		c := binary.LittleEndian.Uint16(d.data[k:])
+		k += 2
+		if c == 0 {
+			break
+		}
+		key = append(key, c)
+	}
+	b.key = string(utf16.Decode(key))
+	if parent != "" {
+		b.path = parent + "/" + b.key
+	}
+
+	// The value starts on a 32-bit boundary
+	b.value = align4(k)
+	if b.value > b.end {
+		if b.valueLength != 0 {
+			return b, d.errorf(k, b.path, "padding after the key runs past the end of the block at %#x", b.end)
+		}
+		b.value = b.end
+	}
+
+	return b, nil
+}
+
+// children calls fn for each structure nested in b, starting at start.
+func (d *versionDecoder) children(b versionBlock, start int, fn func(versionBlock) error) error {
+	for off := align4(start); off < b.end; {
+		// Trailing padding is not a child
+		if isZero(d.data[off:b.end]) {
+			return nil
+		}
+		c, err := d.block(off, b.end, b.path)
+		if err != nil {
+			return err
+		}
+		if err := fn(c); err != nil {
+			return err
+		}
+		off = align4(c.end)
+	}
+	return nil
+}
+
+func (d *versionDecoder) stringFileInfo(b versionBlock, tables *[]StringTable) error {
+	return d.children(b, b.value, func(c versionBlock) error {
+		t, err := parseTranslationString(c.key)
+		if err != nil {
+			return d.errorf(c.offset+6, c.path, "string table key: %v", err)
+		}
+		st := StringTable{Translation: t}
+		err = d.children(c, c.value, func(s versionBlock) error {
+			value, err := d.text(s)
+			if err != nil {
+				return err
+			}
+			st.StringFileInfo.Set(s.key, value)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		*tables = append(*tables, st)
+		return nil
+	})
+}
+
+// text returns the string value of b. Some resource compilers count the
+// length in bytes instead of words, so both are accepted.
+func (d *versionDecoder) text(b versionBlock) (string, error) {
+	n := b.valueLength * 2
+	if b.typ == 0 || b.value+n > b.end {
+		n = b.valueLength
+	}
+	if b.value+n > b.end {
+		return "", d.errorf(b.offset+2, b.path, "WValueLength %d runs past the end of the block at %#x", b.valueLength, b.end)
+	}
+
+	raw := d.data[b.value : b.value+n]
+	u16 := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		c := binary.LittleEndian.Uint16(raw[i:])
+		if c == 0 {
+			break
+		}
+		u16 = append(u16, c)
+	}
+	return string(utf16.Decode(u16)), nil
+}
+
+func (d *versionDecoder) varFileInfo(b versionBlock, vfi *VarFileInfo) error {
+	return d.children(b, b.value, func(c versionBlock) error {
+		if c.key != "Translation" {
+			return nil
+		}
+		if c.valueLength%4 != 0 {
+			return d.errorf(c.offset+2, c.path, "WValueLength %d is not a multiple of 4", c.valueLength)
+		}
+		if c.value+c.valueLength > c.end {
+			return d.errorf(c.offset+2, c.path, "WValueLength %d runs past the end of the block at %#x", c.valueLength, c.end)
+		}
+		for off := c.value; off < c.value+c.valueLength; off += 4 {
+			vfi.Translation = append(vfi.Translation, Translation{
+				LangID:    LangID(binary.LittleEndian.Uint16(d.data[off:])),
+				CharsetID: CharsetID(binary.LittleEndian.Uint16(d.data[off+2:])),
+			})
+		}
+		return nil
+	})
+}
+
+// setStringTables moves the table keyed by the first translation into
+// StringFileInfo. When no table matches, the first table is used and its
+// translation becomes the first one.
+func (vi *VersionInfo) setStringTables(tables []StringTable) {
+	if len(tables) == 0 {
+		return
+	}
+
+	primary := 0
+	first := vi.VarFileInfo.Translation.first()
+	for i, t := range tables {
+		if t.Translation == first {
+			primary = i
+			break
+		}
+	}
+	if len(vi.VarFileInfo.Translation) == 0 || tables[primary].Translation != first {
+		vi.VarFileInfo.Translation = append(Translations{tables[primary].Translation}, vi.VarFileInfo.Translation...)
+	}
+
+	vi.StringFileInfo = tables[primary].StringFileInfo
+	for i, t := range tables {
+		if i != primary {
+			vi.StringTables = append(vi.StringTables, t)
+		}
+	}
+}
+
+func decodeFixedFileInfo(ff VSFixedFileInfo) FixedFileInfo {
+	var date string
+	if ft := uint64(ff.DwFileDateMS)<<32 | uint64(ff.DwFileDateLS); ft != 0 {
+		date = fromFileTime(ft).Format(time.RFC3339Nano)
+	}
+	return FixedFileInfo{
+		FileVersion:    decodeFileVersion(ff.DwFileVersionMS, ff.DwFileVersionLS),
+		ProductVersion: decodeFileVersion(ff.DwProductVersionMS, ff.DwProductVersionLS),
+		FileFlagsMask:  fmt.Sprintf("%02x", ff.DwFileFlagsMask),
+		FileFlags:      fmt.Sprintf("%02x", ff.DwFileFlags),
+		FileOS:         fmt.Sprintf("%02x", ff.DwFileOS),
+		FileType:       fmt.Sprintf("%02x", ff.DwFileType),
+		FileSubType:    fmt.Sprintf("%02x", ff.DwFileSubtype),
+		FileDate:       date,
+	}
+}
+
+func decodeFileVersion(ms, ls uint32) FileVersion {
+	return FileVersion{
+		Major: int(ms >> 16),
+		Minor: int(ms & 0xffff),
+		Patch: int(ls >> 16),
+		Build: int(ls & 0xffff),
+	}
+}
+
+// parseTranslationString is the inverse of getTranslationString.
+func parseTranslationString(s string) (Translation, error) {
+	if len(s) != 8 {
+		return Translation{}, fmt.Errorf("%q is not 8 hex digits", s)
+	}
+	lang, err := strconv.ParseUint(s[:4], 16, 16)
+	if err != nil {
+		return Translation{}, fmt.Errorf("%q is not 8 hex digits", s)
+	}
+	cs, err := strconv.ParseUint(s[4:], 16, 16)
+	if err != nil {
+		return Translation{}, fmt.Errorf("%q is not 8 hex digits", s)
+	}
+	return Translation{LangID: LangID(lang), CharsetID: CharsetID(cs)}, nil
+}
+
+func align4(i int) int {
+	return (i + 3) &^ 3
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeVersionInfo(t *testing.T) {
+	for _, filename := range []string{"cmd", "explorer", "control", "simple"} {
+		t.Run(filename, func(t *testing.T) {
+			path, _ := filepath.Abs("./testdata/hex/" + filename + ".hex")
+			data, err := os.ReadFile(path)
+			assert.NoError(t, err)
+
+			vi, err := DecodeVersionInfo(data)
+			if err != nil {
+				t.Fatal("Could not decode "+filename+".hex", err)
+			}
+
+			// Building the decoded info must give back the very same bytes.
+			vi.Build()
+			vi.Walk()
+			assert.Equal(t, data, vi.Buffer.Bytes())
+		})
+	}
+}
+
+func TestDecodeVersionInfoFields(t *testing.T) {
+	data, err := os.ReadFile("testdata/hex/cmd.hex")
+	assert.NoError(t, err)
+
+	vi, err := DecodeVersionInfo(data)
+	assert.NoError(t, err)
+
+	assert.Equal(t, FileVersion{6, 3, 9600, 16384}, vi.FixedFileInfo.FileVersion)
+	assert.Equal(t, "3f", vi.FixedFileInfo.FileFlagsMask)
+	assert.Equal(t, "40004", vi.FixedFileInfo.FileOS)
+	assert.Equal(t, "01", vi.FixedFileInfo.FileType)
+	assert.Equal(t, "Microsoft Corporation", vi.StringFileInfo.CompanyName)
+	assert.Equal(t, "© Microsoft Corporation. All rights reserved.", vi.StringFileInfo.LegalCopyright)
+	assert.Equal(t, Translations{{LangID: LngUSEnglish, CharsetID: CsUnicode}}, vi.VarFileInfo.Translation)
+}
+
+func TestDecodeVersionInfoTables(t *testing.T) {
+	vi := &VersionInfo{}
+	vi.FixedFileInfo.FileVersion = FileVersion{1, 2, 3, 4}
+	vi.FixedFileInfo.FileFlagsMask = "3f"
+	vi.FixedFileInfo.FileFlags = "00"
+	vi.FixedFileInfo.FileOS = "40004"
+	vi.FixedFileInfo.FileType = "01"
+	vi.FixedFileInfo.FileSubType = "00"
+	vi.StringFileInfo.ProductName = "Product"
+	vi.StringFileInfo.Custom = CustomStrings{{"BuildCommit", "abc1234"}}
+	vi.VarFileInfo.Translation = Translations{
+		{LangID: LngUSEnglish, CharsetID: CsUnicode},
+		{LangID: LngGerman, CharsetID: CsUnicode},
+	}
+	vi.StringTables = []StringTable{{
+		Translation:    Translation{LangID: LngGerman, CharsetID: CsUnicode},
+		StringFileInfo: StringFileInfo{ProductName: "Produkt"},
+	}}
+	vi.Build()
+	vi.Walk()
+
+	got, err := DecodeVersionInfo(vi.Buffer.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, vi.FixedFileInfo, got.FixedFileInfo)
+	assert.Equal(t, vi.StringFileInfo, got.StringFileInfo)
+	assert.Equal(t, vi.VarFileInfo, got.VarFileInfo)
+	assert.Equal(t, vi.StringTables, got.StringTables)
+}
+
+func TestDecodeVersionInfoErrors(t *testing.T) {
+	data, err := os.ReadFile("testdata/hex/simple.hex")
+	assert.NoError(t, err)
+
+	corrupt := func(offset int, v uint16) []byte {
+		b := append([]byte(nil), data...)
+		binary.LittleEndian.PutUint16(b[offset:], v)
+		return b
+	}
+
+	cases := []struct {
+		name   string
+		data   []byte
+		offset int
+		msg    string
+	}{
+		{"empty", nil, 0, "block header runs past"},
+		{"truncated", data[:len(data)-8], 0, "WLength 312 runs past"},
+		{"short length", corrupt(0, 4), 0, "smaller than the 6 byte header"},
+		{"bad key", bytes.Replace(data, padString("VS_VERSION", 0), padString("VS_VERSIOM", 0), 1), 6, "expected VS_VERSION_INFO"},
+		{"fixed file info size", corrupt(2, 0x30), 2, "WValueLength is 48"},
+		{"signature", corrupt(0x28, 0), 0x28, "signature"},
+		{"string file info overflow", corrupt(0x5c, 0x200), 0x5c, "WLength 512 runs past the end of the parent block at 0x138"},
+		{"string table overflow", corrupt(0x80, 0x200), 0x80, "WLength 512 runs past the end of the parent block at 0xf4"},
+		{"string value overflow", corrupt(0x9a, 0x40), 0x9a, "WValueLength 64 runs past"},
+		{"translation length", corrupt(0x116, 3), 0x116, "not a multiple of 4"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := DecodeVersionInfo(c.data)
+			var de *DecodeError
+			if !errors.As(err, &de) {
+				t.Fatalf("expected a *DecodeError, got %v", err)
+			}
+			assert.Equal(t, c.offset, de.Offset, err.Error())
+			assert.Contains(t, de.Msg, c.msg)
+		})
+	}
+}
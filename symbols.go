@@ -0,0 +1,314 @@
+package goversioninfo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// *****************************************************************************
+// Symbolic Constants
+// *****************************************************************************
+
+/*
+VS_FIXEDFILEINFO constants
+https://learn.microsoft.com/en-us/windows/win32/api/verrsrc/ns-verrsrc-vs_fixedfileinfo
+
+The names are the ones winver.h defines for resource scripts.
+*/
+
+// symbol is a named constant.
+type symbol struct {
+	name  string
+	value uint32
+}
+
+// fileFlagSymbols are the bits of FileFlags.
+var fileFlagSymbols = []symbol{
+	{"VS_FF_DEBUG", 0x01},
+	{"VS_FF_PRERELEASE", 0x02},
+	{"VS_FF_PATCHED", 0x04},
+	{"VS_FF_PRIVATEBUILD", 0x08},
+	{"VS_FF_INFOINFERRED", 0x10},
+	{"VS_FF_SPECIALBUILD", 0x20},
+}
+
+// fileFlagsMaskAll covers every FileFlags bit.
+const fileFlagsMaskAll = 0x3f
+
+// fileOSSymbols are the values of FileOS. The combined names come first so
+// they are preferred over joining a base and a windowing system.
+var fileOSSymbols = []symbol{
+	{"VOS_DOS_WINDOWS16", 0x00010001},
+	{"VOS_DOS_WINDOWS32", 0x00010004},
+	{"VOS_OS216_PM16", 0x00020002},
+	{"VOS_OS232_PM32", 0x00030003},
+	{"VOS_NT_WINDOWS32", 0x00040004},
+	{"VOS_UNKNOWN", 0x00000000},
+	{"VOS_DOS", 0x00010000},
+	{"VOS_OS216", 0x00020000},
+	{"VOS_OS232", 0x00030000},
+	{"VOS_NT", 0x00040000},
+	{"VOS_WINCE", 0x00050000},
+	{"VOS__BASE", 0x00000000},
+	{"VOS__WINDOWS16", 0x00000001},
+	{"VOS__PM16", 0x00000002},
+	{"VOS__PM32", 0x00000003},
+	{"VOS__WINDOWS32", 0x00000004},
+}
+
+// fileTypeSymbols are the values of FileType.
+var fileTypeSymbols = []symbol{
+	{"VFT_UNKNOWN", 0x00},
+	{"VFT_APP", 0x01},
+	{"VFT_DLL", 0x02},
+	{"VFT_DRV", 0x03},
+	{"VFT_FONT", 0x04},
+	{"VFT_VXD", 0x05},
+	{"VFT_STATIC_LIB", 0x07},
+}
+
+// Values of FileType that give FileSubType a meaning.
+const (
+	vftDrv  = 0x03
+	vftFont = 0x04
+	vftVxd  = 0x05
+)
+
+// driverSubTypeSymbols are the values of FileSubType for VFT_DRV.
+var driverSubTypeSymbols = []symbol{
+	{"VFT2_UNKNOWN", 0x00},
+	{"VFT2_DRV_PRINTER", 0x01},
+	{"VFT2_DRV_KEYBOARD", 0x02},
+	{"VFT2_DRV_LANGUAGE", 0x03},
+	{"VFT2_DRV_DISPLAY", 0x04},
+	{"VFT2_DRV_MOUSE", 0x05},
+	{"VFT2_DRV_NETWORK", 0x06},
+	{"VFT2_DRV_SYSTEM", 0x07},
+	{"VFT2_DRV_INSTALLABLE", 0x08},
+	{"VFT2_DRV_SOUND", 0x09},
+	{"VFT2_DRV_COMM", 0x0a},
+	{"VFT2_DRV_INPUTMETHOD", 0x0b},
+	{"VFT2_DRV_VERSIONED_PRINTER", 0x0c},
+}
+
+// fontSubTypeSymbols are the values of FileSubType for VFT_FONT.
+var fontSubTypeSymbols = []symbol{
+	{"VFT2_UNKNOWN", 0x00},
+	{"VFT2_FONT_RASTER", 0x01},
+	{"VFT2_FONT_VECTOR", 0x02},
+	{"VFT2_FONT_TRUETYPE", 0x03},
+}
+
+// symbolName returns the first name with the value.
+func symbolName(symbols []symbol, v uint32) (string, bool) {
+	for _, s := range symbols {
+		if s.value == v {
+			return s.name, true
+		}
+	}
+	return "", false
+}
+
+// formatFileFlags returns the flags joined with |, like
+// VS_FF_DEBUG | VS_FF_PRERELEASE. Unknown bits are kept as a hex number.
+func formatFileFlags(v uint32) string {
+	if v == 0 {
+		return "0"
+	}
+	var parts []string
+	for _, s := range fileFlagSymbols {
+		if v&s.value != 0 {
+			parts = append(parts, s.name)
+			v &^= s.value
+		}
+	}
+	if v != 0 {
+		parts = append(parts, fmt.Sprintf("0x%X", v))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// formatFileFlagsMask returns VS_FFI_FILEFLAGSMASK for the usual mask.
+func formatFileFlagsMask(v uint32) string {
+	if v == fileFlagsMaskAll {
+		return "VS_FFI_FILEFLAGSMASK"
+	}
+	return formatFileFlags(v)
+}
+
+// formatFileOS returns the name of the operating system, or its base and
+// windowing system joined with |.
+func formatFileOS(v uint32) string {
+	if name, ok := symbolName(fileOSSymbols, v); ok {
+		return name
+	}
+	base, ok1 := symbolName(fileOSSymbols, v&0xffff0000)
+	win, ok2 := symbolName(fileOSSymbols, v&0xffff)
+	if ok1 && ok2 {
+		return base + " | " + win
+	}
+	return fmt.Sprintf("0x%X", v)
+}
+
+// formatFileType returns the VFT_ name of the file type.
+func formatFileType(v uint32) string {
+	if name, ok := symbolName(fileTypeSymbols, v); ok {
+		return name
+	}
+	return fmt.Sprintf("0x%X", v)
+}
+
+// formatFileSubType returns the VFT2_ name of the subtype, which depends on
+// the file type.
+func formatFileSubType(fileType, v uint32) string {
+	var symbols []symbol
+	switch fileType {
+	case vftDrv:
+		symbols = driverSubTypeSymbols
+	case vftFont:
+		symbols = fontSubTypeSymbols
+	default:
+		symbols = driverSubTypeSymbols[:1]
+	}
+	if name, ok := symbolName(symbols, v); ok {
+		return name
+	}
+	return fmt.Sprintf("0x%X", v)
+}
+
+// fileFlagsMaskSymbols are the names FileFlagsMask accepts.
+var fileFlagsMaskSymbols = append([]symbol{{"VS_FFI_FILEFLAGSMASK", fileFlagsMaskAll}}, fileFlagSymbols...)
+
+// fixedFileInfoValues are the numbers the flag and type fields of a
+// FixedFileInfo stand for.
+type fixedFileInfoValues struct {
+	FileFlagsMask uint32
+	FileFlags     uint32
+	FileOS        uint32
+	FileType      uint32
+	FileSubType   uint32
+}
+
+// fieldError is a problem with one FixedFileInfo field. Values that can not
+// be parsed are errors, values that parse but do not fit together warnings.
+type fieldError struct {
+	Field    string
+	Severity Severity
+	Msg      string
+}
+
+func (e fieldError) Error() string {
+	return fmt.Sprintf("FixedFileInfo.%s: %s", e.Field, e.Msg)
+}
+
+// values parses the flag and type fields, which are hex numbers like "3f" or
+// winver.h names joined with |, like "VS_FF_DEBUG|VS_FF_PRERELEASE". It also
+// checks that the values fit together. Fields that can not be parsed are
+// zero, and every problem is returned.
+func (ff FixedFileInfo) values() (fixedFileInfoValues, []fieldError) {
+	var v fixedFileInfoValues
+	var errs []fieldError
+	failed := map[string]bool{}
+	parse := func(field, s string, symbols []symbol) uint32 {
+		n, err := parseSymbols(s, symbols)
+		if err != nil {
+			errs = append(errs, fieldError{field, SeverityError, err.Error()})
+			failed[field] = true
+		}
+		return n
+	}
+
+	v.FileFlagsMask = parse("FileFlagsMask", ff.FileFlagsMask, fileFlagsMaskSymbols)
+	v.FileFlags = parse("FileFlags", ff.FileFlags, fileFlagSymbols)
+	v.FileOS = parse("FileOS", ff.FileOS, fileOSSymbols)
+	v.FileType = parse("FileType", ff.FileType, fileTypeSymbols)
+
+	// The names of the subtypes depend on the file type
+	subTypes := driverSubTypeSymbols[:1]
+	switch v.FileType {
+	case vftDrv:
+		subTypes = driverSubTypeSymbols
+	case vftFont:
+		subTypes = fontSubTypeSymbols
+	}
+	n, err := parseSymbols(ff.FileSubType, subTypes)
+	if err != nil {
+		if _, ok := symbolValue(driverSubTypeSymbols, ff.FileSubType); ok {
+			err = fmt.Errorf("%s is only valid with FileType VFT_DRV", strings.TrimSpace(ff.FileSubType))
+		} else if _, ok := symbolValue(fontSubTypeSymbols, ff.FileSubType); ok {
+			err = fmt.Errorf("%s is only valid with FileType VFT_FONT", strings.TrimSpace(ff.FileSubType))
+		}
+		errs = append(errs, fieldError{"FileSubType", SeverityError, err.Error()})
+		failed["FileSubType"] = true
+	}
+	v.FileSubType = n
+
+	if v.FileFlagsMask&^fileFlagsMaskAll != 0 {
+		errs = append(errs, fieldError{"FileFlagsMask", SeverityWarning, fmt.Sprintf("%#x has bits that are not VS_FF_ flags", v.FileFlagsMask)})
+	}
+	if !failed["FileFlagsMask"] && !failed["FileFlags"] && v.FileFlags&^v.FileFlagsMask != 0 {
+		errs = append(errs, fieldError{"FileFlags", SeverityWarning, fmt.Sprintf("%s is not covered by FileFlagsMask %s",
+			formatFileFlags(v.FileFlags&^v.FileFlagsMask), formatFileFlagsMask(v.FileFlagsMask))})
+	}
+	if formatFileOS(v.FileOS) == fmt.Sprintf("0x%X", v.FileOS) {
+		errs = append(errs, fieldError{"FileOS", SeverityWarning, fmt.Sprintf("%#x is not a known VOS_ value", v.FileOS)})
+	}
+	if _, ok := symbolName(fileTypeSymbols, v.FileType); !ok {
+		errs = append(errs, fieldError{"FileType", SeverityWarning, fmt.Sprintf("%#x is not a known VFT_ value", v.FileType)})
+	}
+	if failed["FileType"] || failed["FileSubType"] {
+		return v, errs
+	}
+	switch v.FileType {
+	case vftDrv, vftFont:
+		if _, ok := symbolName(subTypes, v.FileSubType); !ok {
+			errs = append(errs, fieldError{"FileSubType", SeverityWarning, fmt.Sprintf("%#x is not a known subtype of %s", v.FileSubType, formatFileType(v.FileType))})
+		}
+	case vftVxd:
+		// The subtype is the virtual device identifier
+	default:
+		if v.FileSubType != 0 {
+			errs = append(errs, fieldError{"FileSubType", SeverityWarning, fmt.Sprintf("must be VFT2_UNKNOWN for FileType %s", formatFileType(v.FileType))})
+		}
+	}
+
+	return v, errs
+}
+
+// parseSymbols parses a hex number, or names from symbols and hex numbers
+// joined with |. An empty string is zero.
+func parseSymbols(s string, symbols []symbol) (uint32, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	var v uint32
+	for _, part := range strings.Split(s, "|") {
+		part = strings.TrimSpace(part)
+		if n, ok := symbolValue(symbols, part); ok {
+			v |= n
+			continue
+		}
+		digits := part
+		if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+			digits = digits[2:]
+		}
+		n, err := strconv.ParseUint(digits, 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("%q is neither a hex number nor a known name", part)
+		}
+		v |= uint32(n)
+	}
+	return v, nil
+}
+
+// symbolValue returns the value of the named symbol.
+func symbolValue(symbols []symbol, name string) (uint32, bool) {
+	name = strings.TrimSpace(name)
+	for _, s := range symbols {
+		if s.name == name {
+			return s.value, true
+		}
+	}
+	return 0, false
+}
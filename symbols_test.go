@@ -0,0 +1,115 @@
+package goversioninfo
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSymbols(t *testing.T) {
+	assert.Equal(t, "0", formatFileFlags(0))
+	assert.Equal(t, "VS_FF_PATCHED | VS_FF_PRIVATEBUILD", formatFileFlags(0x0c))
+	assert.Equal(t, "VS_FF_DEBUG | 0x40", formatFileFlags(0x41))
+	assert.Equal(t, "VS_FFI_FILEFLAGSMASK", formatFileFlagsMask(0x3f))
+	assert.Equal(t, "VS_FF_DEBUG", formatFileFlagsMask(0x01))
+
+	assert.Equal(t, "VOS_NT_WINDOWS32", formatFileOS(0x40004))
+	assert.Equal(t, "VOS_UNKNOWN", formatFileOS(0))
+	assert.Equal(t, "VOS_WINCE | VOS__WINDOWS32", formatFileOS(0x50004))
+	assert.Equal(t, "0x90004", formatFileOS(0x90004))
+
+	assert.Equal(t, "VFT_DLL", formatFileType(2))
+	assert.Equal(t, "0x6", formatFileType(6))
+
+	assert.Equal(t, "VFT2_UNKNOWN", formatFileSubType(1, 0))
+	assert.Equal(t, "0x3", formatFileSubType(1, 3))
+	assert.Equal(t, "VFT2_DRV_SOUND", formatFileSubType(vftDrv, 9))
+	assert.Equal(t, "VFT2_FONT_TRUETYPE", formatFileSubType(vftFont, 3))
+	assert.Equal(t, "0x1234", formatFileSubType(5, 0x1234))
+}
+
+func TestFixedFileInfoSymbols(t *testing.T) {
+	jsonBytes, err := os.ReadFile("testdata/json/cmd.json")
+	assert.NoError(t, err)
+
+	want := &VersionInfo{}
+	assert.NoError(t, want.ParseJSON(jsonBytes))
+	want.Build()
+	want.Walk()
+
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON(jsonBytes))
+	vi.FixedFileInfo.FileFlagsMask = "VS_FFI_FILEFLAGSMASK"
+	vi.FixedFileInfo.FileFlags = "0"
+	vi.FixedFileInfo.FileOS = "VOS_NT | VOS__WINDOWS32"
+	vi.FixedFileInfo.FileType = "VFT_APP"
+	vi.FixedFileInfo.FileSubType = "VFT2_UNKNOWN"
+	vi.Build()
+	vi.Walk()
+
+	assert.Equal(t, want.Buffer.Bytes(), vi.Buffer.Bytes())
+}
+
+func TestFixedFileInfoValues(t *testing.T) {
+	values, errs := FixedFileInfo{
+		FileFlagsMask: "0x3F",
+		FileFlags:     "VS_FF_DEBUG|VS_FF_PRERELEASE | 20",
+		FileOS:        "VOS_NT_WINDOWS32",
+		FileType:      "VFT_FONT",
+		FileSubType:   "VFT2_FONT_TRUETYPE",
+	}.values()
+	assert.Empty(t, errs)
+	assert.Equal(t, fixedFileInfoValues{
+		FileFlagsMask: 0x3f,
+		FileFlags:     0x23,
+		FileOS:        0x40004,
+		FileType:      vftFont,
+		FileSubType:   3,
+	}, values)
+
+	// Legacy hex strings keep working.
+	values, errs = FixedFileInfo{FileFlagsMask: "3f", FileOS: "040004", FileType: "03", FileSubType: "0c"}.values()
+	assert.Empty(t, errs)
+	assert.Equal(t, fixedFileInfoValues{FileFlagsMask: 0x3f, FileOS: 0x40004, FileType: vftDrv, FileSubType: 0x0c}, values)
+}
+
+func TestFixedFileInfoValuesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		ff   FixedFileInfo
+		errs []string
+	}{
+		{"unknown name", FixedFileInfo{FileFlags: "VS_FF_FAST"},
+			[]string{`FixedFileInfo.FileFlags: "VS_FF_FAST" is neither a hex number nor a known name`}},
+		{"name of another field", FixedFileInfo{FileType: "VOS_NT"},
+			[]string{`FixedFileInfo.FileType: "VOS_NT" is neither a hex number nor a known name`}},
+		{"flags outside mask", FixedFileInfo{FileFlagsMask: "VS_FF_DEBUG", FileFlags: "VS_FF_DEBUG|VS_FF_PATCHED"},
+			[]string{"FixedFileInfo.FileFlags: VS_FF_PATCHED is not covered by FileFlagsMask VS_FF_DEBUG"}},
+		{"mask bits", FixedFileInfo{FileFlagsMask: "7f"},
+			[]string{"FixedFileInfo.FileFlagsMask: 0x7f has bits that are not VS_FF_ flags"}},
+		{"unknown OS", FixedFileInfo{FileOS: "90004"},
+			[]string{"FixedFileInfo.FileOS: 0x90004 is not a known VOS_ value"}},
+		{"unknown type", FixedFileInfo{FileType: "6"},
+			[]string{"FixedFileInfo.FileType: 0x6 is not a known VFT_ value"}},
+		{"subtype of an application", FixedFileInfo{FileType: "VFT_APP", FileSubType: "1"},
+			[]string{"FixedFileInfo.FileSubType: must be VFT2_UNKNOWN for FileType VFT_APP"}},
+		{"driver subtype of a font", FixedFileInfo{FileType: "VFT_FONT", FileSubType: "VFT2_DRV_PRINTER"},
+			[]string{"FixedFileInfo.FileSubType: VFT2_DRV_PRINTER is only valid with FileType VFT_DRV"}},
+		{"font subtype of an application", FixedFileInfo{FileType: "VFT_APP", FileSubType: "VFT2_FONT_RASTER"},
+			[]string{"FixedFileInfo.FileSubType: VFT2_FONT_RASTER is only valid with FileType VFT_FONT"}},
+		{"unknown driver subtype", FixedFileInfo{FileType: "VFT_DRV", FileSubType: "0x20"},
+			[]string{"FixedFileInfo.FileSubType: 0x20 is not a known subtype of VFT_DRV"}},
+		{"virtual device", FixedFileInfo{FileType: "VFT_VXD", FileSubType: "1234"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := tt.ff.values()
+			var got []string
+			for _, err := range errs {
+				got = append(got, err.Error())
+			}
+			assert.Equal(t, tt.errs, got)
+		})
+	}
+}
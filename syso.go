@@ -0,0 +1,213 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/akavel/rsrc/coff"
+)
+
+// *****************************************************************************
+// COFF Objects
+// *****************************************************************************
+
+// Relocation types for a 32-bit address relative to the image base, one per
+// machine that coff.Arch accepts.
+const (
+	imageRelI386Dir32NB   = 0x07
+	imageRelAMD64Addr32NB = 0x03
+	imageRelARMAddr32NB   = 0x02
+	imageRelARM64Addr32NB = 0x02
+)
+
+// Sizes of the COFF headers and records written by writeCoffTo.
+const (
+	coffFileHeaderSize    = 20
+	coffSectionHeaderSize = 40
+	coffRelocationSize    = 10
+)
+
+// ReadResources reads the resources of a COFF object file like a .syso, of a
+// Windows executable or DLL, or of a .res file.
+func ReadResources(filename string) (Resources, error) {
+	if isRes(filename) {
+		return ReadRes(filename)
+	}
+
+	f, err := pe.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var res Resources
+	if f.OptionalHeader == nil {
+		res, err = objectResources(f)
+	} else {
+		res, err = executableResources(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return res, nil
+}
+
+// ReadSyso reads the resources of a COFF object file, like the ones written
+// by WriteSyso, windres or cvtres.
+func ReadSyso(filename string) (Resources, error) {
+	f, err := pe.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if f.OptionalHeader != nil {
+		return nil, fmt.Errorf("%s: not an object file, it has an optional header", filename)
+	}
+	res, err := objectResources(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return res, nil
+}
+
+// objectResources reads the resource directory of an object file. The data
+// entries point at the resources through relocations, which may target a
+// different section, like the .rsrc$02 section that cvtres writes.
+func objectResources(f *pe.File) (Resources, error) {
+	var dirSection *pe.Section
+	for _, s := range f.Sections {
+		if s.Name == ".rsrc" || s.Name == ".rsrc$01" {
+			dirSection = s
+			break
+		}
+	}
+	if dirSection == nil {
+		return nil, errors.New("no .rsrc section")
+	}
+
+	dir, err := dirSection.Data()
+	if err != nil {
+		return nil, fmt.Errorf("section %s: %w", dirSection.Name, err)
+	}
+
+	relocs := map[uint32]pe.Reloc{}
+	for _, r := range dirSection.Relocs {
+		relocs[r.VirtualAddress] = r
+	}
+
+	sections := map[*pe.Section][]byte{dirSection: dir}
+	read := func(entry, addr, size uint32) ([]byte, error) {
+		s, start := dirSection, addr
+		if r, ok := relocs[entry]; ok {
+			if int(r.SymbolTableIndex) >= len(f.COFFSymbols) {
+				return nil, fmt.Errorf("relocation at %#x refers to missing symbol %d", entry, r.SymbolTableIndex)
+			}
+			sym := f.COFFSymbols[r.SymbolTableIndex]
+			if sym.SectionNumber <= 0 || int(sym.SectionNumber) > len(f.Sections) {
+				return nil, fmt.Errorf("relocation at %#x refers to symbol %d outside of any section", entry, r.SymbolTableIndex)
+			}
+			s = f.Sections[sym.SectionNumber-1]
+			start = sym.Value + addr
+		}
+
+		data, ok := sections[s]
+		if !ok {
+			if data, err = s.Data(); err != nil {
+				return nil, fmt.Errorf("section %s: %w", s.Name, err)
+			}
+			sections[s] = data
+		}
+		if uint64(start)+uint64(size) > uint64(len(data)) {
+			return nil, fmt.Errorf("%d bytes at %#x run past the end of section %s", size, start, s.Name)
+		}
+		return data[start : start+size], nil
+	}
+
+	return parseResourceDirectory(dir, read)
+}
+
+// WriteSyso writes the resources as a COFF object file that the Go linker
+// embeds into the executable. arch must be an architecture string accepted
+// by coff.Arch, like "386" or "amd64".
+func (rs Resources) WriteSyso(filename string, arch string) error {
+	c, err := newResourceCoff(rs, arch)
+	if err != nil {
+		return err
+	}
+	return writeCoff(c, filename)
+}
+
+// newResourceCoff creates a COFF object whose .rsrc section holds the
+// resources. Numbered resources in U.S. English are added with
+// coff.AddResource, so those files stay the same as before. Others are laid
+// out in one piece by Resources.section, which also supports named resources
+// and languages.
+func newResourceCoff(rs Resources, arch string) (*coff.Coff, error) {
+	c := coff.NewRSRC()
+	if err := c.Arch(arch); err != nil {
+		return nil, err
+	}
+
+	if rs.numbered() {
+		// AddResource expects the IDs of a type in ascending order
+		sorted := append(Resources(nil), rs...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if sorted[i].Type.ID != sorted[j].Type.ID {
+				return sorted[i].Type.ID < sorted[j].Type.ID
+			}
+			return sorted[i].Name.ID < sorted[j].Name.ID
+		})
+		for i, r := range sorted {
+			if i > 0 && r.Type == sorted[i-1].Type && r.Name == sorted[i-1].Name {
+				return nil, fmt.Errorf("duplicate resource %s/%s language %04x", r.Type.typeString(), r.Name, uint16(r.LangID))
+			}
+			c.AddResource(uint32(r.Type.ID), r.Name.ID, bytes.NewReader(r.Data))
+		}
+		c.Freeze()
+		return c, nil
+	}
+
+	section, sites, err := rs.section(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(sites) > 0xffff {
+		return nil, fmt.Errorf("%d resources do not fit in one COFF section", len(sites))
+	}
+
+	var relocType uint16
+	switch arch {
+	case "386":
+		relocType = imageRelI386Dir32NB
+	case "amd64":
+		relocType = imageRelAMD64Addr32NB
+	case "arm":
+		relocType = imageRelARMAddr32NB
+	case "arm64":
+		relocType = imageRelARM64Addr32NB
+	}
+	for _, site := range sites {
+		c.Relocations = append(c.Relocations, coff.RelocationEntry{
+			RVA:         site,
+			SymbolIndex: 0,
+			Type:        relocType,
+		})
+	}
+
+	// The section data replaces the directory tree that Freeze would lay out
+	c.Dir = nil
+	c.Data = []coff.PaddedData{{Data: bytes.NewReader(section)}}
+
+	c.SectionHeader32.PointerToRawData = coffFileHeaderSize + coffSectionHeaderSize
+	c.SectionHeader32.SizeOfRawData = uint32(len(section))
+	c.SectionHeader32.PointerToRelocations = c.SectionHeader32.PointerToRawData + uint32(len(section))
+	c.SectionHeader32.NumberOfRelocations = uint16(len(sites))
+	c.FileHeader.PointerToSymbolTable = c.SectionHeader32.PointerToRelocations + uint32(len(sites))*coffRelocationSize
+	c.FileHeader.NumberOfSymbols = uint32(len(c.Symbols))
+
+	return c, nil
+}
@@ -0,0 +1,131 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSyso(t *testing.T) {
+	jsonBytes, err := os.ReadFile("testdata/json/cmd.json")
+	assert.NoError(t, err)
+
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON(jsonBytes))
+	vi.IconPath = "testdata/resource/icon.ico"
+	vi.ManifestPath = "testdata/resource/goversioninfo.exe.manifest"
+	vi.Build()
+	vi.Walk()
+
+	want, err := vi.Resources()
+	assert.NoError(t, err)
+
+	tmpdir, err := os.MkdirTemp("", "syso")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	for _, arch := range []string{"386", "amd64", "arm", "arm64"} {
+		t.Run(arch, func(t *testing.T) {
+			file := filepath.Join(tmpdir, "resource_windows_"+arch+".syso")
+			assert.NoError(t, vi.WriteSyso(file, arch))
+
+			got, err := ReadSyso(file)
+			if err != nil {
+				t.Fatal("Could not read the .syso", err)
+			}
+			assert.ElementsMatch(t, want, got)
+
+			// Writing what was read must give back the same file.
+			file2 := filepath.Join(tmpdir, "copy.syso")
+			assert.NoError(t, got.WriteSyso(file2, arch))
+			b1, err := os.ReadFile(file)
+			assert.NoError(t, err)
+			b2, err := os.ReadFile(file2)
+			assert.NoError(t, err)
+			assert.Equal(t, b1, b2)
+		})
+	}
+}
+
+func TestWriteSysoAddResource(t *testing.T) {
+	jsonBytes, err := os.ReadFile("testdata/json/cmd.json")
+	assert.NoError(t, err)
+
+	tmpdir, err := os.MkdirTemp("", "syso")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	// The manifest of testdata is checked out with CRLF line endings.
+	manifest := filepath.Join(tmpdir, "app.manifest")
+	assert.NoError(t, os.WriteFile(manifest, []byte(`<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0"/>`+"\n"), 0644))
+
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON(jsonBytes))
+	vi.IconPath = "testdata/res/legacy.ico"
+	vi.ManifestPath = manifest
+	vi.Build()
+	vi.Walk()
+
+	// Numbered resources in U.S. English are written by coff.AddResource,
+	// byte for byte like the files of earlier versions.
+	for _, arch := range []string{"386", "amd64"} {
+		file := filepath.Join(tmpdir, "resource_"+arch+".syso")
+		assert.NoError(t, vi.WriteSyso(file, arch))
+		got, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		want, err := os.ReadFile("testdata/syso/cmd_" + arch + ".syso")
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, arch)
+	}
+
+	dup := Resources{
+		{Type: ResourceID{ID: rtRCData}, Name: ResourceID{ID: 1}, LangID: LngUSEnglish},
+		{Type: ResourceID{ID: rtRCData}, Name: ResourceID{ID: 1}, LangID: LngUSEnglish},
+	}
+	assert.EqualError(t, dup.WriteSyso(filepath.Join(tmpdir, "dup.syso"), "amd64"), "duplicate resource RT_RCDATA/1 language 0409")
+}
+
+func TestReadSysoNamesAndLanguages(t *testing.T) {
+	res := Resources{
+		{Type: ResourceID{ID: 10}, Name: ResourceID{ID: 7}, LangID: LngGerman, Data: []byte("de")},
+		{Type: ResourceID{Name: "Config"}, Name: ResourceID{Name: "defaults"}, LangID: LngUSEnglish, Data: []byte("{}")},
+		{Type: ResourceID{ID: 10}, Name: ResourceID{ID: 7}, LangID: LngUSEnglish, Data: []byte("en")},
+		{Type: ResourceID{ID: 10}, Name: ResourceID{Name: "License"}, LangID: LngUSEnglish, Data: []byte("MIT")},
+	}
+
+	tmpdir, err := os.MkdirTemp("", "syso")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+	file := filepath.Join(tmpdir, "resource.syso")
+	assert.NoError(t, res.WriteSyso(file, "amd64"))
+
+	got, err := ReadSyso(file)
+	assert.NoError(t, err)
+
+	// Names are stored upper case and sorted before IDs, languages ascend.
+	assert.Equal(t, Resources{
+		{Type: ResourceID{Name: "CONFIG"}, Name: ResourceID{Name: "DEFAULTS"}, LangID: LngUSEnglish, Data: []byte("{}")},
+		{Type: ResourceID{ID: 10}, Name: ResourceID{Name: "LICENSE"}, LangID: LngUSEnglish, Data: []byte("MIT")},
+		{Type: ResourceID{ID: 10}, Name: ResourceID{ID: 7}, LangID: LngGerman, Data: []byte("de")},
+		{Type: ResourceID{ID: 10}, Name: ResourceID{ID: 7}, LangID: LngUSEnglish, Data: []byte("en")},
+	}, got)
+
+	var out bytes.Buffer
+	assert.NoError(t, got.Print(&out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if assert.Len(t, lines, 5) {
+		assert.Equal(t, []string{"RT_RCDATA", "7", "0407", "2"}, strings.Fields(lines[3]))
+	}
+
+	dup := append(res, Resource{Type: ResourceID{Name: "config"}, Name: ResourceID{Name: "Defaults"}, LangID: LngUSEnglish})
+	assert.EqualError(t, dup.WriteSyso(file, "amd64"), "duplicate resource config/Defaults language 0409")
+}
+
+func TestReadSysoNotObject(t *testing.T) {
+	_, err := ReadSyso("testdata/hex/cmd.hex")
+	assert.Error(t, err)
+}
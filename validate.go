@@ -0,0 +1,438 @@
+package goversioninfo
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// *****************************************************************************
+// Validation
+// *****************************************************************************
+
+// Severity tells how serious an Issue is.
+type Severity int
+
+const (
+	// SeverityWarning is for values that build, but are most likely not
+	// what was meant, like a StringFileInfo.FileVersion that does not match
+	// FixedFileInfo.FileVersion.
+	SeverityWarning Severity = iota
+
+	// SeverityError is for values that can not be built as given, like a
+	// version part above 65535. Build replaces them with zero.
+	SeverityError
+)
+
+// String returns Warning or Error.
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "Error"
+	}
+	return "Warning"
+}
+
+// Issue is a problem Validate found in a VersionInfo.
+type Issue struct {
+	Severity Severity
+
+	// Path is the JSON path of the value, like FixedFileInfo.FileFlags or
+	// StringTables[1].Translation.LangID.
+	Path string
+
+	Message string
+}
+
+// String returns the issue in the form "Warning: Path: Message".
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Path, i.Message)
+}
+
+// ValidationError is returned by Build in strict mode and lists the issues
+// that failed the build.
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		msgs[i] = issue.String()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks the version info for values Build can not use as given and
+// for ones that are most likely mistakes. It does not change vi, and the
+// issues are in the order of the JSON fields.
+func (vi *VersionInfo) Validate() []Issue {
+	var issues []Issue
+	add := func(severity Severity, path, format string, a ...interface{}) {
+		issues = append(issues, Issue{severity, path, fmt.Sprintf(format, a...)})
+	}
+
+	// Each version part is packed into 16 bits.
+	fileVersion := validateVersion(&issues, "FileVersion",
+		vi.FixedFileInfo.FileVersion, vi.StringFileInfo.FileVersion)
+	productVersion := validateVersion(&issues, "ProductVersion",
+		vi.FixedFileInfo.ProductVersion, vi.StringFileInfo.ProductVersion)
+
+	_, errs := vi.FixedFileInfo.values()
+	for _, err := range errs {
+		add(err.Severity, "FixedFileInfo."+err.Field, "%s", err.Msg)
+	}
+
+	if err := checkFileDate(vi.FixedFileInfo.FileDate); err != nil {
+		add(SeverityError, "FixedFileInfo.FileDate", "%v", err)
+	}
+
+	for i, t := range vi.VarFileInfo.Translation {
+		if !isKnownLangID(t.LangID) {
+			add(SeverityWarning, fmt.Sprintf("VarFileInfo.Translation[%d].LangID", i),
+				"%04X is not a known language", uint16(t.LangID))
+		}
+	}
+
+	for i, st := range vi.StringTables {
+		path := fmt.Sprintf("StringTables[%d]", i)
+		if !isKnownLangID(st.LangID) {
+			add(SeverityWarning, path+".Translation.LangID",
+				"%04X is not a known language", uint16(st.LangID))
+		}
+		validateVersionString(&issues, path+".StringFileInfo.FileVersion", st.StringFileInfo.FileVersion, fileVersion)
+		validateVersionString(&issues, path+".StringFileInfo.ProductVersion", st.StringFileInfo.ProductVersion, productVersion)
+	}
+
+	if vi.IconPath != "" {
+		for _, icon := range strings.Split(vi.IconPath, ",") {
+			validateIcon(&issues, "IconPath", icon)
+		}
+	}
+	validateManifest(&issues, vi.ManifestPath, vi.Manifest != nil)
+	if vi.Manifest != nil {
+		vi.Manifest.validate(&issues)
+	}
+	validateIcon(&issues, "ApplicationIconPath", vi.ApplicationIconPath)
+	vi.validateIcons(&issues)
+	// The message table is parsed once, for its languages and its errors
+	var mt *MessageTable
+	var mtErr error
+	if vi.MessageTablePath != "" {
+		mt, mtErr = ReadMC(vi.MessageTablePath)
+	}
+	vi.validateFileResources(&issues, mt)
+	vi.validateStringResources(&issues)
+	validateMessageTable(&issues, vi.MessageTablePath, mtErr)
+	validateFile(&issues, "ResPath", ".res", vi.ResPath)
+
+	switch vi.ResConflict {
+	case "", ConflictReplace, ConflictError:
+	default:
+		add(SeverityError, "ResConflict", "unknown conflict policy %q, expected replace or error", vi.ResConflict)
+	}
+
+	return issues
+}
+
+// validateVersion checks the parts of a FixedFileInfo version and compares it
+// with the StringFileInfo one. It returns the version Build will use, which
+// comes from the string when the fixed one is zero.
+func validateVersion(issues *[]Issue, name string, fixed FileVersion, str string) FileVersion {
+	for _, part := range fixed.parts() {
+		if part.value < 0 || part.value > 0xffff {
+			*issues = append(*issues, Issue{SeverityError, "FixedFileInfo." + name + "." + part.name,
+				fmt.Sprintf("%d is outside of 0-65535", part.value)})
+		}
+	}
+
+	if fixed.IsZero() && str != "" {
+		v, _, err := parseVersionString(str)
+		if err != nil {
+			*issues = append(*issues, Issue{SeverityWarning, "StringFileInfo." + name,
+				fmt.Sprintf("%q could not be parsed: %v", str, err)})
+		}
+		for _, part := range v.parts() {
+			if part.value > 0xffff {
+				*issues = append(*issues, Issue{SeverityError, "StringFileInfo." + name,
+					fmt.Sprintf("%s %d of %q is outside of 0-65535", part.name, part.value, str)})
+			}
+		}
+		return v
+	}
+	validateVersionString(issues, "StringFileInfo."+name, str, fixed)
+	return fixed
+}
+
+// versionPart is one named part of a FileVersion.
+type versionPart struct {
+	name  string
+	value int
+}
+
+func (f FileVersion) parts() []versionPart {
+	return []versionPart{{"Major", f.Major}, {"Minor", f.Minor}, {"Patch", f.Patch}, {"Build", f.Build}}
+}
+
+// validateVersionString warns when a version string can not be parsed or
+// does not match the fixed version. Semantic versions have no Build to match.
+func validateVersionString(issues *[]Issue, path, str string, fixed FileVersion) {
+	if str == "" || fixed.IsZero() {
+		return
+	}
+	v, semver, err := parseVersionString(str)
+	if semver {
+		v.Build = fixed.Build
+	}
+	if err != nil {
+		*issues = append(*issues, Issue{SeverityWarning, path, fmt.Sprintf("%q could not be parsed: %v", str, err)})
+	} else if v != fixed {
+		*issues = append(*issues, Issue{SeverityWarning, path,
+			fmt.Sprintf("%s and the FixedFileInfo version %s do not match", str, fixed.GetVersionString())})
+	}
+}
+
+// validateFile reports a file that can not be read.
+func validateFile(issues *[]Issue, path, kind, filename string) {
+	if filename == "" {
+		return
+	}
+	f, err := os.Open(filename)
+	if err == nil {
+		f.Close()
+		return
+	}
+	msg := err.Error()
+	if errors.Is(err, fs.ErrNotExist) {
+		msg = fmt.Sprintf("%s file %s does not exist", kind, filename)
+	}
+	*issues = append(*issues, Issue{SeverityError, path, msg})
+}
+
+// validateIcon reports an icon file that can not be read and what LintIcon
+// finds in .ico files.
+func validateIcon(issues *[]Issue, path, filename string) {
+	n := len(*issues)
+	validateFile(issues, path, "icon", filename)
+	if filename == "" || len(*issues) > n || isPNGIcon(filename) {
+		return
+	}
+	report, err := LintIcon(filename)
+	if err != nil {
+		*issues = append(*issues, Issue{SeverityError, path, err.Error()})
+		return
+	}
+	for _, issue := range report.Issues {
+		*issues = append(*issues, Issue{issue.Severity, path, issue.Path + ": " + issue.Message})
+	}
+}
+
+// validateIcons checks the Icons entries and reports the ones that take the ID
+// of another icon group in the same language.
+func (vi *VersionInfo) validateIcons(issues *[]Issue) {
+	type group struct {
+		id   ResourceID
+		lang LangID
+	}
+	used := map[group]string{}
+	if vi.ApplicationIconPath != "" || vi.IconPath != "" {
+		used[group{ResourceID{ID: 32512}, LngUSEnglish}] = "the application icon"
+	}
+
+	for i, icon := range vi.Icons {
+		path := fmt.Sprintf("Icons[%d]", i)
+		if icon.Path == "" {
+			*issues = append(*issues, Issue{SeverityError, path + ".Path", "is empty"})
+		}
+		validateIcon(issues, path+".Path", icon.Path)
+
+		if icon.ID == (ResourceID{}) {
+			*issues = append(*issues, Issue{SeverityError, path + ".ID", "needs a number above 0 or a name"})
+		} else {
+			g := group{icon.ID.key(), icon.lang()}
+			if other, ok := used[g]; ok {
+				*issues = append(*issues, Issue{SeverityError, path + ".ID",
+					fmt.Sprintf("%s is already used by %s", icon.ID, other)})
+			} else {
+				used[g] = path
+			}
+		}
+
+		if icon.LangID != nil && !isKnownLangID(*icon.LangID) {
+			*issues = append(*issues, Issue{SeverityWarning, path + ".LangID",
+				fmt.Sprintf("%04X is not a known language", uint16(*icon.LangID))})
+		}
+	}
+}
+
+// validateFileResources checks the Resources entries and reports the ones
+// that take the type, ID and language of another resource. mt is the parsed
+// message table, nil without one.
+func (vi *VersionInfo) validateFileResources(issues *[]Issue, mt *MessageTable) {
+	type leaf struct {
+		typ, id ResourceID
+		lang    LangID
+	}
+	used := map[leaf]string{
+		{ResourceID{ID: rtVersion}, ResourceID{ID: 1}, LngUSEnglish}: "the version info",
+	}
+	if vi.ManifestPath != "" || vi.Manifest != nil {
+		used[leaf{ResourceID{ID: rtManifest}, ResourceID{ID: 1}, LngUSEnglish}] = "the manifest"
+	}
+	if vi.ApplicationIconPath != "" || vi.IconPath != "" {
+		used[leaf{ResourceID{ID: rtGroupIcon}, ResourceID{ID: 32512}, LngUSEnglish}] = "the application icon"
+	}
+	for i, icon := range vi.Icons {
+		used[leaf{ResourceID{ID: rtGroupIcon}, icon.ID.key(), icon.lang()}] = fmt.Sprintf("Icons[%d]", i)
+	}
+	for i, st := range vi.StringResources {
+		for id := range st.Strings {
+			used[leaf{ResourceID{ID: rtString}, ResourceID{ID: id/16 + 1}, st.lang()}] = fmt.Sprintf("StringResources[%d]", i)
+		}
+	}
+	if mt != nil {
+		for _, lang := range mt.languages() {
+			used[leaf{ResourceID{ID: rtMessageTable}, ResourceID{ID: 1}, lang}] = "the message table"
+		}
+	}
+
+	for i, fr := range vi.FileResources {
+		path := fmt.Sprintf("Resources[%d]", i)
+		if fr.Path == "" {
+			*issues = append(*issues, Issue{SeverityError, path + ".Path", "is empty"})
+		}
+		validateFile(issues, path+".Path", "resource", fr.Path)
+
+		if fr.ID == (ResourceID{}) {
+			*issues = append(*issues, Issue{SeverityError, path + ".ID", "needs a number above 0 or a name"})
+		} else {
+			l := leaf{fr.resourceType().key(), fr.ID.key(), fr.lang()}
+			if other, ok := used[l]; ok {
+				*issues = append(*issues, Issue{SeverityError, path + ".ID",
+					fmt.Sprintf("%s %s is already used by %s", l.typ.typeString(), fr.ID, other)})
+			} else {
+				used[l] = path
+			}
+		}
+
+		if fr.LangID != nil && !isKnownLangID(*fr.LangID) {
+			*issues = append(*issues, Issue{SeverityWarning, path + ".LangID",
+				fmt.Sprintf("%04X is not a known language", uint16(*fr.LangID))})
+		}
+	}
+}
+
+// validateStringResources checks the StringResources entries and reports
+// languages that are listed twice.
+func (vi *VersionInfo) validateStringResources(issues *[]Issue) {
+	used := map[LangID]string{}
+	for i, st := range vi.StringResources {
+		path := fmt.Sprintf("StringResources[%d]", i)
+		if other, ok := used[st.lang()]; ok {
+			*issues = append(*issues, Issue{SeverityError, path + ".LangID",
+				fmt.Sprintf("%04X is already used by %s", uint16(st.lang()), other)})
+		} else {
+			used[st.lang()] = path
+		}
+
+		if st.LangID != nil && !isKnownLangID(*st.LangID) {
+			*issues = append(*issues, Issue{SeverityWarning, path + ".LangID",
+				fmt.Sprintf("%04X is not a known language", uint16(*st.LangID))})
+		}
+
+		ids := make([]int, 0, len(st.Strings))
+		for id := range st.Strings {
+			ids = append(ids, int(id))
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			s := st.Strings[uint16(id)]
+			switch {
+			case s == "":
+				*issues = append(*issues, Issue{SeverityWarning, fmt.Sprintf("%s.Strings.%d", path, id),
+					"is empty, LoadString can not tell it from a missing string"})
+			case len(utf16.Encode([]rune(s))) > 0xffff:
+				*issues = append(*issues, Issue{SeverityError, fmt.Sprintf("%s.Strings.%d", path, id),
+					"is longer than 65535 UTF-16 code units"})
+			}
+		}
+	}
+}
+
+// validateMessageTable reports a message text file that can not be read, or
+// err from parsing it.
+func validateMessageTable(issues *[]Issue, filename string, err error) {
+	n := len(*issues)
+	validateFile(issues, "MessageTablePath", "message", filename)
+	if filename == "" || len(*issues) > n {
+		return
+	}
+	if err != nil {
+		*issues = append(*issues, Issue{SeverityError, "MessageTablePath", filename + ": " + err.Error()})
+	}
+}
+
+// validateManifest reports a manifest file that can not be read and what
+// lintManifest finds in it.
+func validateManifest(issues *[]Issue, filename string, fragment bool) {
+	n := len(*issues)
+	validateFile(issues, "ManifestPath", "manifest", filename)
+	if filename == "" || len(*issues) > n {
+		return
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		*issues = append(*issues, Issue{SeverityError, "ManifestPath", err.Error()})
+		return
+	}
+	for _, issue := range lintManifest(filename, b, fragment) {
+		*issues = append(*issues, Issue{issue.Severity, "ManifestPath", issue.Path + ": " + issue.Message})
+	}
+}
+
+// isKnownLangID tells if id is language neutral or one of the Lng constants.
+func isKnownLangID(id LangID) bool {
+	switch id {
+	case 0, LngArabic, LngBulgarian, LngCatalan, LngTraditionalChinese, LngCzech,
+		LngDanish, LngGerman, LngGreek, LngUSEnglish, LngCastilianSpanish,
+		LngFinnish, LngFrench, LngHebrew, LngHungarian, LngIcelandic,
+		LngItalian, LngJapanese, LngKorean, LngDutch, LngNorwegianBokmal,
+		LngPolish, LngPortugueseBrazil, LngRhaetoRomanic, LngRomanian,
+		LngRussian, LngCroatoSerbianLatin, LngSlovak, LngAlbanian, LngSwedish,
+		LngThai, LngTurkish, LngUrdu, LngBahasa, LngSimplifiedChinese,
+		LngSwissGerman, LngUKEnglish, LngSpanishMexico, LngBelgianFrench,
+		LngSwissItalian, LngBelgianDutch, LngNorwegianNynorsk,
+		LngPortuguesePortugal, LngSerboCroatianCyrillic, LngCanadianFrench,
+		LngSwissFrench:
+		return true
+	}
+	return false
+}
+
+// check validates the version info for Build in strict mode and returns the
+// issues that fail the build. Other builds skip validation, so Build neither
+// reads the icon, manifest and message files twice nor logs anything.
+func (vi *VersionInfo) check() error {
+	if !vi.Strict && !vi.WarningsAsErrors {
+		return nil
+	}
+	if failed, _ := splitIssues(vi.Validate(), vi.Strict, vi.WarningsAsErrors); len(failed) > 0 {
+		return &ValidationError{failed}
+	}
+	return nil
+}
+
+// splitIssues separates the issues that fail a strict build from the others.
+func splitIssues(issues []Issue, strict, warningsAsErrors bool) (failed, others []Issue) {
+	for _, issue := range issues {
+		if warningsAsErrors || (strict && issue.Severity == SeverityError) {
+			failed = append(failed, issue)
+		} else {
+			others = append(others, issue)
+		}
+	}
+	return failed, others
+}
@@ -0,0 +1,125 @@
+package goversioninfo
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	jsonBytes, err := os.ReadFile("testdata/json/cmd.json")
+	assert.NoError(t, err)
+
+	vi := &VersionInfo{}
+	assert.NoError(t, vi.ParseJSON(jsonBytes))
+	vi.IconPath = "testdata/resource/icon.ico"
+	vi.ManifestPath = "testdata/resource/goversioninfo.exe.manifest"
+	assert.Empty(t, vi.Validate())
+
+	vi.FixedFileInfo.FileVersion.Major = 70000
+	vi.FixedFileInfo.ProductVersion.Build = -1
+	vi.StringFileInfo.FileVersion = "6.3.9600.16384"
+	vi.FixedFileInfo.FileFlags = "VS_FF_FAST"
+	vi.FixedFileInfo.FileSubType = "1"
+	vi.VarFileInfo.Translation = Translations{{LangID: LngUSEnglish}, {LangID: 0x0c07}}
+	vi.StringTables = []StringTable{{
+		Translation:    Translation{LangID: LngGerman},
+		StringFileInfo: StringFileInfo{ProductVersion: "1.2"},
+	}}
+	vi.IconPath = "testdata/resource/icon.ico,testdata/resource/missing.ico"
+	vi.ResConflict = "keep"
+
+	var got []string
+	for _, issue := range vi.Validate() {
+		got = append(got, issue.String())
+	}
+	assert.Equal(t, []string{
+		"Error: FixedFileInfo.FileVersion.Major: 70000 is outside of 0-65535",
+		"Warning: StringFileInfo.FileVersion: 6.3.9600.16384 and the FixedFileInfo version 70000.3.9600.16384 do not match",
+		"Error: FixedFileInfo.ProductVersion.Build: -1 is outside of 0-65535",
+		"Warning: StringFileInfo.ProductVersion: 6.3.9600.16384 and the FixedFileInfo version 6.3.9600.-1 do not match",
+		`Error: FixedFileInfo.FileFlags: "VS_FF_FAST" is neither a hex number nor a known name`,
+		"Warning: FixedFileInfo.FileSubType: must be VFT2_UNKNOWN for FileType VFT_APP",
+		"Warning: VarFileInfo.Translation[1].LangID: 0C07 is not a known language",
+		`Warning: StringTables[0].StringFileInfo.ProductVersion: "1.2" could not be parsed: version expected to start from x.y.z`,
+		"Error: IconPath: icon file testdata/resource/missing.ico does not exist",
+		`Error: ResConflict: unknown conflict policy "keep", expected replace or error`,
+	}, got)
+}
+
+func TestValidateVersionString(t *testing.T) {
+	vi := &VersionInfo{}
+	vi.StringFileInfo.FileVersion = "1.70000.0"
+	vi.StringFileInfo.ProductVersion = "latest"
+	assert.Equal(t, []Issue{
+		{SeverityError, "StringFileInfo.FileVersion", `Minor 70000 of "1.70000.0" is outside of 0-65535`},
+		{SeverityWarning, "StringFileInfo.ProductVersion", `"latest" could not be parsed: version expected to start from x.y.z`},
+	}, vi.Validate())
+}
+
+func TestBuildStrict(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	vi := &VersionInfo{}
+	vi.FixedFileInfo.FileVersion = FileVersion{1, 0, 0, 0}
+	vi.StringFileInfo.FileVersion = "2.0.0.0"
+	vi.FixedFileInfo.FileType = "VFT_EXE"
+
+	// Outside of strict mode Build does not validate or log.
+	assert.NoError(t, vi.Build())
+	assert.Empty(t, buf.String())
+
+	// Strict mode fails on the error only.
+	vi.Strict = true
+	err := vi.Build()
+	var verr *ValidationError
+	if assert.True(t, errors.As(err, &verr)) {
+		assert.Equal(t, []Issue{{SeverityError, "FixedFileInfo.FileType", `"VFT_EXE" is neither a hex number nor a known name`}}, verr.Issues)
+	}
+	assert.Empty(t, buf.String())
+
+	// Warnings fail as well.
+	buf.Reset()
+	vi.WarningsAsErrors = true
+	assert.EqualError(t, vi.Build(), "Warning: StringFileInfo.FileVersion: 2.0.0.0 and the FixedFileInfo version 1.0.0.0 do not match; "+
+		`Error: FixedFileInfo.FileType: "VFT_EXE" is neither a hex number nor a known name`)
+	assert.Empty(t, buf.String())
+
+	vi.FixedFileInfo.FileType = "VFT_APP"
+	vi.StringFileInfo.FileVersion = ""
+	assert.NoError(t, vi.Build())
+}
+
+func TestRunCLIStrict(t *testing.T) {
+	tmpdir, err := os.MkdirTemp("", "strict")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+
+	cfg := NewCLIConfig()
+	cfg.ConfigFile = "testdata/json/cmd.json"
+	cfg.OutputFile = filepath.Join(tmpdir, "resource.syso")
+	cfg.VerMajor = 65536
+	cfg.Strict = true
+	assert.EqualError(t, RunCLI(cfg), "invalid version info: Error: FixedFileInfo.FileVersion.Major: 65536 is outside of 0-65535")
+	_, err = os.Stat(cfg.OutputFile)
+	assert.True(t, os.IsNotExist(err))
+
+	cfg.VerMajor = 6
+	assert.NoError(t, RunCLI(cfg))
+
+	// Without -strict the CLI logs the issues and carries on.
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	cfg.VerMajor = 65536
+	cfg.Strict = false
+	assert.NoError(t, RunCLI(cfg))
+	assert.Contains(t, buf.String(), "Error: FixedFileInfo.FileVersion.Major: 65536 is outside of 0-65535")
+}